@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConsultationIPLimit    = "5/10m"
+	defaultConsultationPhoneLimit = "3/1h"
+	defaultConsultationMinFill    = 3 * time.Second
+	defaultConsultationDuplicate  = 30 * time.Minute
+	rateLimitSweepInterval        = 5 * time.Minute
+	rateLimitPruneAge             = 24 * time.Hour
+)
+
+// rateLimit describes a token bucket: Capacity tokens refilled evenly over Interval.
+type rateLimit struct {
+	Capacity int
+	Interval time.Duration
+}
+
+func (l rateLimit) enabled() bool {
+	return l.Capacity > 0 && l.Interval > 0
+}
+
+func (l rateLimit) refillPerSecond() float64 {
+	return float64(l.Capacity) / l.Interval.Seconds()
+}
+
+// parseRateLimit parses values like "5/10m" (5 requests per 10 minutes).
+// "0", "off" and an empty string disable the limit.
+func parseRateLimit(raw string) (rateLimit, error) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	if value == "" || value == "0" || value == "off" {
+		return rateLimit{}, nil
+	}
+
+	countPart, intervalPart, ok := strings.Cut(value, "/")
+	if !ok {
+		return rateLimit{}, fmt.Errorf("invalid rate limit %q (expected N/duration)", raw)
+	}
+	capacity, err := strconv.Atoi(strings.TrimSpace(countPart))
+	if err != nil || capacity < 0 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit count %q", countPart)
+	}
+	interval, err := time.ParseDuration(strings.TrimSpace(intervalPart))
+	if err != nil || interval <= 0 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit interval %q", intervalPart)
+	}
+	return rateLimit{Capacity: capacity, Interval: interval}, nil
+}
+
+// rateLimitStore keeps token buckets. Take consumes one token for key and
+// reports how long the caller has to wait when the bucket is empty.
+type rateLimitStore interface {
+	Take(ctx context.Context, key string, limit rateLimit) (bool, time.Duration, error)
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	refill  time.Duration
+}
+
+// memoryRateLimitStore is the default single-instance store.
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit rateLimit) (bool, time.Duration, error) {
+	if !limit.enabled() {
+		return true, 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweepLocked(now)
+
+	capacity := float64(limit.Capacity)
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = math.Min(capacity, bucket.tokens+elapsed*limit.refillPerSecond())
+	bucket.updated = now
+	bucket.refill = limit.Interval
+
+	if bucket.tokens < 1 {
+		return false, retryAfterForTokens(bucket.tokens, limit), nil
+	}
+	bucket.tokens--
+	return true, 0, nil
+}
+
+// sweepLocked drops buckets that have been idle long enough to be full again.
+func (s *memoryRateLimitStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > bucket.refill {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// postgresRateLimitStore shares buckets between instances through
+// public.rate_limit_buckets. Refill is computed with the database clock.
+type postgresRateLimitStore struct {
+	db        *sql.DB
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func newPostgresRateLimitStore(db *sql.DB) *postgresRateLimitStore {
+	return &postgresRateLimitStore{db: db}
+}
+
+func (s *postgresRateLimitStore) Take(ctx context.Context, key string, limit rateLimit) (bool, time.Duration, error) {
+	if !limit.enabled() {
+		return true, 0, nil
+	}
+	s.pruneIfDue(ctx)
+
+	var allowed bool
+	var tokens float64
+	err := s.db.QueryRowContext(
+		ctx,
+		`WITH refill AS (
+			INSERT INTO public.rate_limit_buckets AS b (bucket_key, tokens, updated_at)
+			VALUES ($1, $2::double precision - 1, NOW())
+			ON CONFLICT (bucket_key) DO UPDATE
+			SET tokens = LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * $3::double precision) - 1,
+				updated_at = NOW()
+			WHERE LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * $3::double precision) >= 1
+			RETURNING tokens
+		)
+		SELECT true, tokens FROM refill
+		UNION ALL
+		SELECT false, LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at)) * $3::double precision)
+		FROM public.rate_limit_buckets b
+		WHERE b.bucket_key = $1 AND NOT EXISTS (SELECT 1 FROM refill)`,
+		key,
+		float64(limit.Capacity),
+		limit.refillPerSecond(),
+	).Scan(&allowed, &tokens)
+	if err != nil {
+		return false, 0, fmt.Errorf("rate limit bucket %s: %w", key, err)
+	}
+	if !allowed {
+		return false, retryAfterForTokens(tokens, limit), nil
+	}
+	return true, 0, nil
+}
+
+func (s *postgresRateLimitStore) pruneIfDue(ctx context.Context) {
+	s.mu.Lock()
+	due := time.Since(s.lastPrune) >= rateLimitSweepInterval
+	if due {
+		s.lastPrune = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM public.rate_limit_buckets WHERE updated_at < NOW() - make_interval(secs => $1)`,
+		rateLimitPruneAge.Seconds(),
+	)
+	if err != nil {
+		logFromContext(ctx).Warn("rate limit prune failed", "error", err)
+	}
+}
+
+func retryAfterForTokens(tokens float64, limit rateLimit) time.Duration {
+	missing := 1 - tokens
+	if missing <= 0 {
+		return 0
+	}
+	wait := time.Duration(missing / limit.refillPerSecond() * float64(time.Second))
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait.Round(time.Second)
+}
+
+type consultationGuardConfig struct {
+	IPLimit           rateLimit
+	PhoneLimit        rateLimit
+	MinFillTime       time.Duration
+	RequireFillTime   bool
+	DuplicateWindow   time.Duration
+	TrustProxyHeaders bool
+}
+
+func loadConsultationGuardConfig() (consultationGuardConfig, error) {
+	ipLimit, err := parseRateLimit(envOrDefault("CONSULTATION_RATE_LIMIT_IP", defaultConsultationIPLimit))
+	if err != nil {
+		return consultationGuardConfig{}, fmt.Errorf("CONSULTATION_RATE_LIMIT_IP: %w", err)
+	}
+	phoneLimit, err := parseRateLimit(envOrDefault("CONSULTATION_RATE_LIMIT_PHONE", defaultConsultationPhoneLimit))
+	if err != nil {
+		return consultationGuardConfig{}, fmt.Errorf("CONSULTATION_RATE_LIMIT_PHONE: %w", err)
+	}
+
+	return consultationGuardConfig{
+		IPLimit:           ipLimit,
+		PhoneLimit:        phoneLimit,
+		MinFillTime:       parseDurationOrDefault(os.Getenv("CONSULTATION_MIN_FILL_TIME"), defaultConsultationMinFill),
+		RequireFillTime:   isTruthy(os.Getenv("CONSULTATION_REQUIRE_FORM_TIMESTAMP")),
+		DuplicateWindow:   parseDurationOrDefault(os.Getenv("CONSULTATION_DUPLICATE_WINDOW"), defaultConsultationDuplicate),
+		TrustProxyHeaders: isTruthy(os.Getenv("TRUST_PROXY_HEADERS")),
+	}, nil
+}
+
+// consultationGuard holds the anti-spam state for the public consultation form.
+type consultationGuard struct {
+	cfg   consultationGuardConfig
+	store rateLimitStore
+}
+
+func newConsultationGuard(db *sql.DB) (*consultationGuard, error) {
+	cfg, err := loadConsultationGuardConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var store rateLimitStore
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("RATE_LIMIT_STORE"))) {
+	case "", "memory":
+		store = newMemoryRateLimitStore()
+	case "postgres", "postgresql", "db":
+		store = newPostgresRateLimitStore(db)
+	default:
+		return nil, errors.New("RATE_LIMIT_STORE must be memory or postgres")
+	}
+
+	return &consultationGuard{cfg: cfg, store: store}, nil
+}
+
+// allow consumes a token from the bucket for scope/value. Store failures are
+// logged and let the request through so a database hiccup does not block leads.
+func (g *consultationGuard) allow(ctx context.Context, scope, value string, limit rateLimit) (bool, time.Duration) {
+	if g == nil || !limit.enabled() || strings.TrimSpace(value) == "" {
+		return true, 0
+	}
+	allowed, retryAfter, err := g.store.Take(ctx, "consultation:"+scope+":"+value, limit)
+	if err != nil {
+		logFromContext(ctx).Warn("rate limit check failed", "scope", scope, "error", err)
+		return true, 0
+	}
+	return allowed, retryAfter
+}
+
+// spamReason returns a non-empty reason when the submission looks automated.
+func (g *consultationGuard) spamReason(honeypot string, formStartedAt int64, now time.Time) string {
+	if g == nil {
+		return ""
+	}
+	if strings.TrimSpace(honeypot) != "" {
+		return "honeypot"
+	}
+	if g.cfg.MinFillTime <= 0 {
+		return ""
+	}
+	if formStartedAt <= 0 {
+		if g.cfg.RequireFillTime {
+			return "missing_form_timestamp"
+		}
+		return ""
+	}
+
+	startedAt := unixTimestampToTime(formStartedAt)
+	if startedAt.After(now.Add(time.Minute)) {
+		return "form_timestamp_in_future"
+	}
+	if now.Sub(startedAt) < g.cfg.MinFillTime {
+		return "filled_too_fast"
+	}
+	return ""
+}
+
+// findDuplicateConsultation looks for a non-spam request with the same phone
+// and service type inside the configured window.
+func (g *consultationGuard) findDuplicateConsultation(ctx context.Context, db *sql.DB, phone, serviceType string) (int64, time.Time, bool, error) {
+	if g == nil || g.cfg.DuplicateWindow <= 0 {
+		return 0, time.Time{}, false, nil
+	}
+
+	var id int64
+	var createdAt time.Time
+	err := db.QueryRowContext(
+		ctx,
+		`SELECT id, created_at
+		FROM public.consultations
+		WHERE phone = $1
+		  AND lower(service_type) = lower($2)
+		  AND status <> 'spam'
+		  AND created_at >= NOW() - make_interval(secs => $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`,
+		phone,
+		serviceType,
+		g.cfg.DuplicateWindow.Seconds(),
+	).Scan(&id, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, false, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+	return id, createdAt, true, nil
+}
+
+// unixTimestampToTime accepts both seconds and milliseconds since epoch.
+func unixTimestampToTime(value int64) time.Time {
+	if value > 1e12 {
+		return time.UnixMilli(value)
+	}
+	return time.Unix(value, 0)
+}
+
+// clientIP returns the caller address. Proxy headers are honoured only when
+// TRUST_PROXY_HEADERS is enabled, otherwise they could be spoofed.
+func clientIP(r *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		if forwarded := strings.TrimSpace(r.Header.Get("X-Forwarded-For")); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		return strings.TrimSpace(r.RemoteAddr)
+	}
+	return host
+}
+
+func writeRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{
+		"status":      "error",
+		"message":     "Слишком много заявок, попробуйте позже",
+		"retry_after": seconds,
+	})
+}
+
+func envOrDefault(key, fallback string) string {
+	return firstNonEmpty(os.Getenv(key), fallback)
+}
+
+func parseDurationOrDefault(raw string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return fallback
+	}
+	if value == "0" {
+		return 0
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return fallback
+	}
+	return parsed
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const apiV1Prefix = "/api/v1"
+
+// apiV1Route maps a /api/v1 endpoint onto the legacy handler that serves it.
+// The legacy routes keep their response shapes for existing clients; /api/v1
+// rewrites whatever they answer into the v1 envelope.
+type apiV1Route struct {
+	Path   string
+	Legacy string
+	// Item also serves Path/{id-or-slug} (Legacy/{id-or-slug}).
+	Item    bool
+	Methods []string
+}
+
+var apiV1Routes = []apiV1Route{
+	{Path: "/contact", Legacy: "/contact"},
+	{Path: "/about", Legacy: "/about"},
+	{Path: "/banners", Legacy: "/banners"},
+	{Path: "/partners", Legacy: "/partners"},
+	{Path: "/tuning", Legacy: "/tuning", Item: true},
+	{Path: "/service_offerings", Legacy: "/service_offerings", Item: true},
+	{Path: "/privacy_sections", Legacy: "/privacy_sections"},
+	{Path: "/portfolio_items", Legacy: "/portfolio_items", Item: true},
+	{Path: "/work_post", Legacy: "/work_post", Item: true},
+	{Path: "/search", Legacy: "/search"},
+	{Path: "/home", Legacy: homePageRoute},
+	{Path: "/pages", Legacy: pagesRoute, Item: true},
+	// Listing consultations stays on the legacy route only.
+	{Path: "/consultations", Legacy: "/api/consultations", Methods: []string{http.MethodPost}},
+}
+
+func (r apiV1Route) allows(method string) bool {
+	if len(r.Methods) == 0 {
+		return method == http.MethodGet
+	}
+	return slices.Contains(r.Methods, method)
+}
+
+// apiV1LegacyPath translates a /api/v1 path to the legacy one, or "" when no v1
+// route serves it.
+func apiV1LegacyPath(path string) (apiV1Route, string) {
+	rest := strings.TrimPrefix(path, apiV1Prefix)
+	for _, route := range apiV1Routes {
+		if rest == route.Path {
+			return route, route.Legacy
+		}
+		if item, ok := strings.CutPrefix(rest, route.Path+"/"); ok && route.Item && item != "" {
+			return route, route.Legacy + "/" + item
+		}
+	}
+	return apiV1Route{}, ""
+}
+
+// apiV1Success is the v1 envelope of every successful response. Meta carries
+// what the legacy responses put next to their payload: pagination, the
+// search query and total, confirmation messages.
+type apiV1Success struct {
+	Data any            `json:"data"`
+	Meta map[string]any `json:"meta,omitempty"`
+}
+
+type apiV1Error struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// apiV1ErrorCodes are the machine-readable codes by HTTP status; see
+// apiV1ErrorCode for the ones derived from the error details.
+var apiV1ErrorCodes = map[int]string{
+	http.StatusBadRequest:            "invalid_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "payload_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusUnprocessableEntity:   "validation_failed",
+	http.StatusTooManyRequests:       "rate_limited",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+// apiV1Handler serves /api/v1/* by running the legacy handler registered on
+// legacy for the translated path and converting its answer: success bodies
+// go into {"data","meta"} with snake_case keys, errors (plain text or JSON)
+// become {"error":{"code","message","details"}}.
+func apiV1Handler(legacy http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route, path := apiV1LegacyPath(r.URL.Path)
+		if path == "" {
+			writeAPIV1Error(w, http.StatusNotFound, apiV1Error{Code: "not_found", Message: "unknown endpoint"})
+			return
+		}
+		if !route.allows(r.Method) {
+			methods := route.Methods
+			if len(methods) == 0 {
+				methods = []string{http.MethodGet}
+			}
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			writeAPIV1Error(w, http.StatusMethodNotAllowed, apiV1Error{Code: "method_not_allowed", Message: "method not allowed"})
+			return
+		}
+
+		sub := r.Clone(context.WithValue(r.Context(), requestPathKey{}, r.URL.Path))
+		sub.URL.Path = path
+		sub.URL.RawPath = ""
+		sub.RequestURI = sub.URL.RequestURI()
+
+		rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		legacy.ServeHTTP(rec, sub)
+		// metricsMiddleware labels by r.Pattern, which the mux only set on
+		// sub; report the v1 route rather than the /api/v1/ catch-all.
+		r.Pattern = apiV1Prefix + route.Path
+		if path != route.Legacy {
+			r.Pattern += "/"
+		}
+		writeAPIV1Response(w, rec)
+	}
+}
+
+type requestPathKey struct{}
+
+// requestPath is the path the client called: r.URL.Path, or the /api/v1 one
+// when apiV1Handler passed the request on to a legacy route. Signatures
+// cover this path.
+func requestPath(r *http.Request) string {
+	if path, ok := r.Context().Value(requestPathKey{}).(string); ok {
+		return path
+	}
+	return r.URL.Path
+}
+
+func writeAPIV1Response(w http.ResponseWriter, rec *bufferedResponse) {
+	for name, values := range rec.header {
+		if name == "Content-Type" || name == "Content-Length" {
+			continue
+		}
+		w.Header()[name] = values
+	}
+
+	switch {
+	case rec.status == http.StatusNotModified:
+		w.WriteHeader(rec.status)
+		return
+	case rec.status >= 300 && rec.status < 400:
+		if location := w.Header().Get("Location"); strings.HasPrefix(location, "/") {
+			w.Header().Set("Location", apiV1Location(location))
+		}
+		w.WriteHeader(rec.status)
+		return
+	case rec.status >= 400:
+		writeAPIV1Error(w, rec.status, legacyAPIError(rec))
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(rec.body.Bytes()))
+	decoder.UseNumber()
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		writeAPIV1Error(w, http.StatusInternalServerError, apiV1Error{Code: "internal_error", Message: "invalid response"})
+		return
+	}
+
+	envelope := apiV1Success{Data: body}
+	if object, ok := body.(map[string]any); ok {
+		if data, wrapped := object["data"]; wrapped {
+			envelope.Data = data
+			for key, value := range object {
+				if key == "data" || key == "status" {
+					continue
+				}
+				if envelope.Meta == nil {
+					envelope.Meta = map[string]any{}
+				}
+				envelope.Meta[key] = value
+			}
+		}
+	}
+	envelope.Data = snakeCaseKeys(envelope.Data)
+	writeJSON(w, rec.status, envelope)
+}
+
+// legacyAPIError reads a legacy error: http.Error text, or JSON with
+// message, errors (field -> problem) and retry_after.
+func legacyAPIError(rec *bufferedResponse) apiV1Error {
+	apiErr := apiV1Error{Message: strings.TrimSpace(rec.body.String())}
+	var body map[string]any
+	if json.Unmarshal(rec.body.Bytes(), &body) == nil {
+		apiErr.Message, _ = body["message"].(string)
+		if fields, ok := body["errors"].(map[string]any); ok && len(fields) > 0 {
+			apiErr.Details = map[string]any{"fields": fields}
+		}
+		if retryAfter, ok := body["retry_after"]; ok {
+			if apiErr.Details == nil {
+				apiErr.Details = map[string]any{}
+			}
+			apiErr.Details["retry_after"] = retryAfter
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.ToLower(http.StatusText(rec.status))
+	}
+	apiErr.Code = apiV1ErrorCode(rec.status, apiErr.Details)
+	return apiErr
+}
+
+func apiV1ErrorCode(status int, details map[string]any) string {
+	fields, _ := details["fields"].(map[string]any)
+	if problem, ok := fields["captcha_token"].(string); ok {
+		return "captcha_" + problem
+	}
+	if status == http.StatusBadRequest && len(fields) > 0 {
+		return "validation_failed"
+	}
+	if code, ok := apiV1ErrorCodes[status]; ok {
+		return code
+	}
+	return "internal_error"
+}
+
+func writeAPIV1Error(w http.ResponseWriter, status int, apiErr apiV1Error) {
+	writeJSON(w, status, map[string]any{"error": apiErr})
+}
+
+// apiV1Location maps a legacy redirect target (/tuning/new-slug) to its v1 URL.
+func apiV1Location(location string) string {
+	path, query, _ := strings.Cut(location, "?")
+	for _, route := range apiV1Routes {
+		if path == route.Legacy || (route.Item && strings.HasPrefix(path, route.Legacy+"/")) {
+			location = apiV1Prefix + route.Path + strings.TrimPrefix(path, route.Legacy)
+			if query != "" {
+				location += "?" + query
+			}
+			break
+		}
+	}
+	return location
+}
+
+// snakeCaseKeys renames the object keys in value to snake_case, which is
+// what every legacy endpoint but /work_post already uses.
+func snakeCaseKeys(value any) any {
+	switch typed := value.(type) {
+	case []any:
+		for i, item := range typed {
+			typed[i] = snakeCaseKeys(item)
+		}
+		return typed
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, item := range typed {
+			out[snakeCase(key)] = snakeCaseKeys(item)
+		}
+		return out
+	}
+	return value
+}
+
+func snakeCase(key string) string {
+	var b strings.Builder
+	prevLower := false
+	for _, r := range key {
+		if unicode.IsUpper(r) {
+			if prevLower {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			prevLower = false
+			continue
+		}
+		b.WriteRune(r)
+		prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"titleModel":     "title_model",
+		"cardImageURL":   "card_image_url",
+		"fullImageUrl":   "full_image_url",
+		"ID":             "id",
+		"id":             "id",
+		"already_snake":  "already_snake",
+		"video2Link":     "video2_link",
+		"createdAt":      "created_at",
+		"":               "",
+		"ЗаголовокПоста": "заголовок_поста",
+	}
+	for key, want := range tests {
+		if got := snakeCase(key); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSnakeCaseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "scalar", value: `"titleModel"`, want: `"titleModel"`},
+		{name: "object", value: `{"titleModel":"BMW X5","cardImageURL":"https://cdn/x.jpg"}`, want: `{"title_model":"BMW X5","card_image_url":"https://cdn/x.jpg"}`},
+		{name: "values untouched", value: `{"slug":"camelCase","n":1.5,"ok":true,"none":null}`, want: `{"slug":"camelCase","n":1.5,"ok":true,"none":null}`},
+		{name: "list of objects", value: `[{"createdAt":"2024-01-01"},{"updatedAt":"2024-01-02"}]`, want: `[{"created_at":"2024-01-01"},{"updated_at":"2024-01-02"}]`},
+		{name: "nested", value: `{"workList":[{"itemTitle":"a","galleryImages":["xY"]}],"seoMeta":{"ogImage":"z"}}`, want: `{"work_list":[{"item_title":"a","gallery_images":["xY"]}],"seo_meta":{"og_image":"z"}}`},
+		{name: "snake stays", value: `{"card_description":"d","id":3}`, want: `{"card_description":"d","id":3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value, want any
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got := snakeCaseKeys(value); !reflect.DeepEqual(got, want) {
+				t.Errorf("snakeCaseKeys(%s) = %#v, want %#v", tt.value, got, want)
+			}
+		})
+	}
+}
+
+func TestAPIV1HandlerPattern(t *testing.T) {
+	mux := http.NewServeMux()
+	legacy := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"path": r.URL.Path})
+	}
+	mux.HandleFunc("/tuning", legacy)
+	mux.HandleFunc("/tuning/", legacy)
+	mux.HandleFunc(apiV1Prefix+"/", apiV1Handler(mux))
+
+	tests := map[string]string{
+		"/api/v1/tuning":          "/api/v1/tuning",
+		"/api/v1/tuning/bmw-m5":   "/api/v1/tuning/",
+		"/api/v1/tuning/camry-70": "/api/v1/tuning/",
+	}
+	for target, want := range tests {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s answered %d: %s", target, w.Code, w.Body)
+		}
+		if r.Pattern != want {
+			t.Errorf("%s: pattern %q, want %q", target, r.Pattern, want)
+		}
+	}
+}
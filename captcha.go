@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	turnstileVerifyURL   = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	hcaptchaVerifyURL    = "https://api.hcaptcha.com/siteverify"
+	recaptchaVerifyURL   = "https://www.google.com/recaptcha/api/siteverify"
+	defaultRecaptchaMin  = 0.5
+	captchaVerifyTimeout = 5 * time.Second
+	appSignatureMaxSkew  = 5 * time.Minute
+	minAppNonceLength    = 16
+	maxAppNonceLength    = 128
+)
+
+var (
+	errCaptchaRequired    = errors.New("captcha token is required")
+	errCaptchaRejected    = errors.New("captcha verification failed")
+	errCaptchaUnavailable = errors.New("captcha verification unavailable")
+)
+
+// captchaResult is the provider-neutral outcome of a siteverify call.
+type captchaResult struct {
+	Success    bool
+	Score      float64
+	Action     string
+	Hostname   string
+	ErrorCodes []string
+}
+
+// captchaVerifier checks a client token with a CAPTCHA provider.
+type captchaVerifier interface {
+	Name() string
+	Verify(ctx context.Context, token, remoteIP string) (captchaResult, error)
+}
+
+// siteverifyClient implements the form-encoded siteverify protocol shared by
+// Turnstile, hCaptcha and reCAPTCHA.
+type siteverifyClient struct {
+	VerifyURL string
+	Secret    string
+	SiteKey   string
+	Client    *http.Client
+}
+
+func (c siteverifyClient) verify(ctx context.Context, token, remoteIP string) (captchaResult, error) {
+	form := url.Values{}
+	form.Set("secret", c.Secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	if c.SiteKey != "" {
+		form.Set("sitekey", c.SiteKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.VerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return captchaResult{}, fmt.Errorf("build siteverify request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: captchaVerifyTimeout}
+	}
+	resp, err := tracedHTTPDo(client, req, "captcha siteverify")
+	if err != nil {
+		return captchaResult{}, fmt.Errorf("siteverify request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return captchaResult{}, fmt.Errorf("siteverify status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+
+	var payload struct {
+		Success    bool     `json:"success"`
+		Score      float64  `json:"score"`
+		Action     string   `json:"action"`
+		Hostname   string   `json:"hostname"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return captchaResult{}, fmt.Errorf("decode siteverify response: %w", err)
+	}
+
+	return captchaResult{
+		Success:    payload.Success,
+		Score:      payload.Score,
+		Action:     payload.Action,
+		Hostname:   payload.Hostname,
+		ErrorCodes: payload.ErrorCodes,
+	}, nil
+}
+
+type turnstileVerifier struct{ siteverifyClient }
+
+func (v turnstileVerifier) Name() string { return "turnstile" }
+
+func (v turnstileVerifier) Verify(ctx context.Context, token, remoteIP string) (captchaResult, error) {
+	return v.verify(ctx, token, remoteIP)
+}
+
+type hcaptchaVerifier struct{ siteverifyClient }
+
+func (v hcaptchaVerifier) Name() string { return "hcaptcha" }
+
+func (v hcaptchaVerifier) Verify(ctx context.Context, token, remoteIP string) (captchaResult, error) {
+	return v.verify(ctx, token, remoteIP)
+}
+
+// recaptchaV3Verifier additionally enforces a minimum score and, when set,
+// the expected action name.
+type recaptchaV3Verifier struct {
+	siteverifyClient
+	MinScore       float64
+	ExpectedAction string
+}
+
+func (v recaptchaV3Verifier) Name() string { return "recaptcha" }
+
+func (v recaptchaV3Verifier) Verify(ctx context.Context, token, remoteIP string) (captchaResult, error) {
+	result, err := v.verify(ctx, token, remoteIP)
+	if err != nil || !result.Success {
+		return result, err
+	}
+	if result.Score < v.MinScore {
+		result.Success = false
+		result.ErrorCodes = append(result.ErrorCodes, "score-too-low")
+	}
+	if v.ExpectedAction != "" && result.Action != v.ExpectedAction {
+		result.Success = false
+		result.ErrorCodes = append(result.ErrorCodes, "action-mismatch")
+	}
+	return result, nil
+}
+
+// captchaGate decides whether a public write request has to pass a CAPTCHA.
+type captchaGate struct {
+	db       *sql.DB
+	verifier captchaVerifier
+	failOpen bool
+	appKeys  map[string]string
+}
+
+func newCaptchaGate(db *sql.DB) (*captchaGate, error) {
+	verifier, err := loadCaptchaVerifier()
+	if err != nil {
+		return nil, err
+	}
+	appKeys, err := parseAppClientKeys(os.Getenv("APP_CLIENT_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	return &captchaGate{
+		db:       db,
+		verifier: verifier,
+		failOpen: isTruthy(os.Getenv("CAPTCHA_FAIL_OPEN")),
+		appKeys:  appKeys,
+	}, nil
+}
+
+func loadCaptchaVerifier() (captchaVerifier, error) {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("CAPTCHA_PROVIDER")))
+	if provider == "" || provider == "none" || provider == "off" {
+		return nil, nil
+	}
+
+	secret := strings.TrimSpace(os.Getenv("CAPTCHA_SECRET"))
+	if secret == "" {
+		return nil, errors.New("CAPTCHA_SECRET is required when CAPTCHA_PROVIDER is set")
+	}
+	client := siteverifyClient{
+		VerifyURL: strings.TrimSpace(os.Getenv("CAPTCHA_VERIFY_URL")),
+		Secret:    secret,
+		SiteKey:   strings.TrimSpace(os.Getenv("CAPTCHA_SITE_KEY")),
+		Client:    &http.Client{Timeout: captchaVerifyTimeout},
+	}
+
+	switch provider {
+	case "turnstile", "cloudflare":
+		client.VerifyURL = firstNonEmpty(client.VerifyURL, turnstileVerifyURL)
+		return turnstileVerifier{client}, nil
+	case "hcaptcha":
+		client.VerifyURL = firstNonEmpty(client.VerifyURL, hcaptchaVerifyURL)
+		return hcaptchaVerifier{client}, nil
+	case "recaptcha", "recaptcha_v3":
+		client.VerifyURL = firstNonEmpty(client.VerifyURL, recaptchaVerifyURL)
+		minScore := defaultRecaptchaMin
+		if raw := strings.TrimSpace(os.Getenv("CAPTCHA_MIN_SCORE")); raw != "" {
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				return nil, errors.New("CAPTCHA_MIN_SCORE must be a number between 0 and 1")
+			}
+			minScore = parsed
+		}
+		return recaptchaV3Verifier{
+			siteverifyClient: client,
+			MinScore:         minScore,
+			ExpectedAction:   strings.TrimSpace(os.Getenv("CAPTCHA_EXPECTED_ACTION")),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown CAPTCHA_PROVIDER %q (use turnstile, hcaptcha or recaptcha)", provider)
+	}
+}
+
+// parseAppClientKeys parses "ios:secret1,android:secret2".
+func parseAppClientKeys(raw string) (map[string]string, error) {
+	keys := map[string]string{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		appID, secret, ok := strings.Cut(entry, ":")
+		appID = strings.TrimSpace(appID)
+		secret = strings.TrimSpace(secret)
+		if !ok || appID == "" || secret == "" {
+			return nil, fmt.Errorf("APP_CLIENT_KEYS entry %q must look like app_id:secret", entry)
+		}
+		keys[appID] = secret
+	}
+	return keys, nil
+}
+
+// check returns nil when the request may proceed. Trusted app clients that
+// sign the request with their app key skip the CAPTCHA; body must be the
+// hashingBody the handler parsed the request from.
+func (g *captchaGate) check(ctx context.Context, r *http.Request, body *hashingBody, token, remoteIP string) error {
+	if g == nil || g.verifier == nil {
+		return nil
+	}
+	if g.trustedAppRequest(ctx, r, body.sum(), time.Now()) {
+		return nil
+	}
+
+	token = strings.TrimSpace(firstNonEmpty(token, r.Header.Get("X-Captcha-Token")))
+	if token == "" {
+		return errCaptchaRequired
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, captchaVerifyTimeout)
+	defer cancel()
+
+	result, err := g.verifier.Verify(ctx, token, remoteIP)
+	if err != nil {
+		logFromContext(ctx).Error("captcha verify error", "provider", g.verifier.Name(), "error", err)
+		if g.failOpen {
+			return nil
+		}
+		return errCaptchaUnavailable
+	}
+	if !result.Success {
+		logFromContext(ctx).Warn("captcha rejected", "provider", g.verifier.Name(), "error_codes", result.ErrorCodes, "score", result.Score)
+		return errCaptchaRejected
+	}
+	return nil
+}
+
+// trustedAppRequest validates X-App-Id, X-App-Timestamp, X-App-Nonce and
+// X-App-Signature, where the signature is
+//
+//	hex(HMAC-SHA256(secret, "<app_id>:<timestamp>:<nonce>:<METHOD>:<path>:<hex sha256(body)>"))
+//
+// and path is the one the client called (/api/v1/consultations or
+// /api/consultations), without the query string. Each nonce is accepted
+// once per app, so a captured signature cannot be replayed, and the body
+// hash ties it to the submitted form.
+func (g *captchaGate) trustedAppRequest(ctx context.Context, r *http.Request, bodySHA256 string, now time.Time) bool {
+	if len(g.appKeys) == 0 {
+		return false
+	}
+
+	appID := strings.TrimSpace(r.Header.Get("X-App-Id"))
+	timestamp := strings.TrimSpace(r.Header.Get("X-App-Timestamp"))
+	nonce := strings.TrimSpace(r.Header.Get("X-App-Nonce"))
+	signature := strings.TrimSpace(r.Header.Get("X-App-Signature"))
+	if appID == "" || timestamp == "" || signature == "" {
+		return false
+	}
+	if len(nonce) < minAppNonceLength || len(nonce) > maxAppNonceLength {
+		return false
+	}
+
+	secret, ok := g.appKeys[appID]
+	if !ok {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > appSignatureMaxSkew || skew < -appSignatureMaxSkew {
+		return false
+	}
+
+	provided, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	if !hmac.Equal(provided, signAppRequest(secret, appID, timestamp, nonce, r.Method, requestPath(r), bodySHA256)) {
+		return false
+	}
+	return g.claimAppNonce(ctx, appID, nonce, now)
+}
+
+func signAppRequest(secret, appID, timestamp, nonce, method, path, bodySHA256 string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(appID + ":" + timestamp + ":" + nonce + ":" + strings.ToUpper(method) + ":" + path + ":" + bodySHA256))
+	return mac.Sum(nil)
+}
+
+// claimAppNonce records nonce as used; false means it was seen before (or
+// the store is unreachable, which sends the client to the CAPTCHA). Rows
+// outlive the timestamp skew window on both sides, then get pruned.
+func (g *captchaGate) claimAppNonce(ctx context.Context, appID, nonce string, now time.Time) bool {
+	if g.db == nil {
+		return false
+	}
+	if _, err := g.db.ExecContext(
+		withQueryName(ctx, "captcha.nonce_prune"),
+		`DELETE FROM public.app_request_nonces WHERE expires_at < NOW()`,
+	); err != nil {
+		logFromContext(ctx).Warn("app nonce prune failed", "error", err)
+	}
+
+	result, err := g.db.ExecContext(
+		withQueryName(ctx, "captcha.nonce_claim"),
+		`INSERT INTO public.app_request_nonces (app_id, nonce, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (app_id, nonce) DO NOTHING`,
+		appID, nonce, now.Add(2*appSignatureMaxSkew),
+	)
+	if err != nil {
+		logFromContext(ctx).Error("app nonce claim failed", "app_id", appID, "error", err)
+		return false
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil || claimed == 0 {
+		logFromContext(ctx).Warn("app signature replayed", "app_id", appID)
+		return false
+	}
+	return true
+}
+
+// hashingBody hashes a request body as the handler reads it, so the app
+// signature covers exactly the bytes that were parsed.
+type hashingBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func hashRequestBody(r *http.Request) *hashingBody {
+	body := &hashingBody{ReadCloser: r.Body, hash: sha256.New()}
+	r.Body = body
+	return body
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	return n, err
+}
+
+// sum drains what the parser left unread and returns the hex SHA-256.
+func (b *hashingBody) sum() string {
+	_, _ = io.Copy(io.Discard, b)
+	return hex.EncodeToString(b.hash.Sum(nil))
+}
+
+func writeCaptchaError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errCaptchaRequired):
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "Подтвердите, что вы не робот",
+			"errors": map[string]string{
+				"captcha_token": "required",
+			},
+		})
+	case errors.Is(err, errCaptchaUnavailable):
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status":  "error",
+			"message": "Проверка капчи временно недоступна, попробуйте позже",
+		})
+	default:
+		writeJSON(w, http.StatusForbidden, map[string]any{
+			"status":  "error",
+			"message": "Проверка капчи не пройдена",
+			"errors": map[string]string{
+				"captcha_token": "invalid",
+			},
+		})
+	}
+}
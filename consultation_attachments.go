@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	consultationAttachmentsRoute           = "/admin/consultation_attachments"
+	defaultConsultationAttachmentsBucket   = "consultations"
+	defaultConsultationAttachmentMaxFiles  = 5
+	defaultConsultationAttachmentMaxSizeMB = 10
+	consultationAttachmentsStoreTimeout    = time.Minute
+	// consultationAttachmentsField is the multipart key; it may repeat.
+	consultationAttachmentsField = "attachments"
+)
+
+// consultationAttachmentTypes are the photo formats customers may send.
+var consultationAttachmentTypes = []string{"image/jpeg", "image/png", "image/webp", "image/heic", "image/avif"}
+
+type consultationAttachmentConfig struct {
+	Bucket    string
+	MaxFiles  int
+	MaxSizeMB int
+	// LinkTTL is how long links in manager notifications stay valid.
+	LinkTTL time.Duration
+}
+
+// consultationAttachmentsBucket reads CONSULTATION_ATTACHMENTS_BUCKET. The
+// bucket is always treated as private (see loadStorageAccessConfig).
+func consultationAttachmentsBucket() string {
+	bucket, err := cleanStorageBucket(os.Getenv("CONSULTATION_ATTACHMENTS_BUCKET"))
+	if err != nil {
+		return defaultConsultationAttachmentsBucket
+	}
+	return bucket
+}
+
+// loadConsultationAttachmentConfig reads CONSULTATION_ATTACHMENTS_BUCKET,
+// CONSULTATION_ATTACHMENTS_MAX_FILES (0 disables attachments),
+// CONSULTATION_ATTACHMENT_MAX_MB and CONSULTATION_ATTACHMENT_LINK_TTL.
+func loadConsultationAttachmentConfig() (consultationAttachmentConfig, error) {
+	maxFiles, err := parseIntOrDefault(os.Getenv("CONSULTATION_ATTACHMENTS_MAX_FILES"), defaultConsultationAttachmentMaxFiles)
+	if err != nil || maxFiles < 0 {
+		return consultationAttachmentConfig{}, errors.New("CONSULTATION_ATTACHMENTS_MAX_FILES must be a non-negative integer")
+	}
+	maxSizeMB, err := parseIntOrDefault(os.Getenv("CONSULTATION_ATTACHMENT_MAX_MB"), defaultConsultationAttachmentMaxSizeMB)
+	if err != nil || maxSizeMB <= 0 {
+		return consultationAttachmentConfig{}, errors.New("CONSULTATION_ATTACHMENT_MAX_MB must be a positive integer")
+	}
+	return consultationAttachmentConfig{
+		Bucket:    consultationAttachmentsBucket(),
+		MaxFiles:  maxFiles,
+		MaxSizeMB: maxSizeMB,
+		LinkTTL:   parseDurationOrDefault(os.Getenv("CONSULTATION_ATTACHMENT_LINK_TTL"), maxSignedURLTTL),
+	}, nil
+}
+
+func (c consultationAttachmentConfig) policy() uploadPolicy {
+	return uploadPolicy{AllowedTypes: consultationAttachmentTypes, MaxSizeMB: c.MaxSizeMB}
+}
+
+// maxBodyBytes bounds a multipart consultation: every file at its limit
+// plus room for the text fields.
+func (c consultationAttachmentConfig) maxBodyBytes() int64 {
+	return int64(c.MaxFiles*c.MaxSizeMB)<<20 + 1<<20
+}
+
+type consultationAttachment struct {
+	ID             int64     `json:"id"`
+	ConsultationID int64     `json:"consultation_id"`
+	Bucket         string    `json:"bucket"`
+	Path           string    `json:"path"`
+	Filename       string    `json:"filename"`
+	MimeType       string    `json:"mime_type"`
+	SizeBytes      int64     `json:"size_bytes"`
+	Checksum       string    `json:"checksum_sha256,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	URL            string    `json:"url,omitempty"`
+	URLExpiresAt   string    `json:"url_expires_at,omitempty"`
+}
+
+// consultationAttachmentLink is what managers get in the notification.
+type consultationAttachmentLink struct {
+	Filename  string `json:"filename"`
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// pendingConsultationAttachment is a file that passed the checks but is
+// only stored once the consultation row exists.
+type pendingConsultationAttachment struct {
+	header *multipart.FileHeader
+	check  uploadCheck
+}
+
+// consultationRequestFromForm fills the JSON request fields from a
+// multipart form, so both encodings share validation.
+func consultationRequestFromForm(form *multipart.Form) consultationCreateRequest {
+	value := func(key string) string {
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	formStartedAt, _ := strconv.ParseInt(strings.TrimSpace(value("form_started_at")), 10, 64)
+	return consultationCreateRequest{
+		FirstName:         value("first_name"),
+		LastName:          value("last_name"),
+		Phone:             value("phone"),
+		ServiceType:       value("service_type"),
+		CarModel:          value("car_model"),
+		PreferredCallTime: value("preferred_call_time"),
+		Comments:          value("comments"),
+		Website:           value("website"),
+		FormStartedAt:     formStartedAt,
+		CaptchaToken:      value("captcha_token"),
+	}
+}
+
+// checkConsultationAttachments sniffs every file before anything is saved.
+// The returned message is meant for the customer.
+func (a *App) checkConsultationAttachments(ctx context.Context, files []*multipart.FileHeader) ([]pendingConsultationAttachment, string) {
+	cfg := a.ConsultationAttachments
+	if len(files) == 0 {
+		return nil, ""
+	}
+	if cfg.MaxFiles == 0 || a.Storage == nil {
+		return nil, "Прикрепление файлов недоступно"
+	}
+	if len(files) > cfg.MaxFiles {
+		return nil, fmt.Sprintf("Можно прикрепить не больше %d файлов", cfg.MaxFiles)
+	}
+
+	policy := cfg.policy()
+	pending := make([]pendingConsultationAttachment, 0, len(files))
+	for _, header := range files {
+		// Phones often save HEIC or WebP under .jpg; customers should not be
+		// refused for that, so the extension is derived from the content.
+		filename := uploadFilename(header.Filename)
+		filename = firstNonEmpty(strings.TrimSuffix(filename, path.Ext(filename)), "photo")
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Sprintf("Не удалось прочитать файл «%s»", header.Filename)
+		}
+		check, err := policy.check(file, header.Size, cfg.Bucket, filename, filename)
+		file.Close()
+		if err != nil {
+			logFromContext(ctx).Warn("consultation attachment rejected", "filename", header.Filename, "size", header.Size, "reason", err.Error())
+			return nil, fmt.Sprintf("Файл «%s»: принимаются фото JPEG, PNG, WebP или HEIC до %d МБ", header.Filename, cfg.MaxSizeMB)
+		}
+		pending = append(pending, pendingConsultationAttachment{header: header, check: check})
+	}
+	return pending, ""
+}
+
+// storeConsultationAttachments writes the checked files under
+// <consultation id>/ and records them. The consultation is already saved,
+// so failures are logged and the file skipped rather than failing the request.
+func (a *App) storeConsultationAttachments(ctx context.Context, consultationID int64, pending []pendingConsultationAttachment) []consultationAttachment {
+	ctx, cancel := context.WithTimeout(ctx, consultationAttachmentsStoreTimeout)
+	defer cancel()
+
+	bucket := a.ConsultationAttachments.Bucket
+	stored := make([]consultationAttachment, 0, len(pending))
+	for i, item := range pending {
+		objectPath := fmt.Sprintf("%d/%d_%s", consultationID, i+1, item.check.Filename)
+		attachment, err := a.storeConsultationAttachment(ctx, consultationID, bucket, objectPath, item)
+		if err != nil {
+			logFromContext(ctx).Error("consultation attachment store failed", "consultation_id", consultationID, "bucket", bucket, "path", objectPath, "error", err)
+			continue
+		}
+		stored = append(stored, attachment)
+	}
+	return stored
+}
+
+func (a *App) storeConsultationAttachment(ctx context.Context, consultationID int64, bucket, objectPath string, item pendingConsultationAttachment) (consultationAttachment, error) {
+	file, err := item.header.Open()
+	if err != nil {
+		return consultationAttachment{}, err
+	}
+	defer file.Close()
+
+	checksum, _, _, err := inspectUpload(file)
+	if err != nil {
+		return consultationAttachment{}, err
+	}
+	if _, err := a.Storage.Put(ctx, bucket, objectPath, file, storagePutOptions{
+		ContentType: item.check.ContentType,
+		Size:        item.header.Size,
+	}); err != nil {
+		return consultationAttachment{}, err
+	}
+
+	attachment := consultationAttachment{
+		ConsultationID: consultationID,
+		Bucket:         bucket,
+		Path:           objectPath,
+		Filename:       item.check.Filename,
+		MimeType:       item.check.ContentType,
+		SizeBytes:      item.header.Size,
+		Checksum:       checksum,
+	}
+	err = a.DB.QueryRowContext(
+		withQueryName(ctx, "consultation_attachments.insert"),
+		`INSERT INTO public.consultation_attachments
+			(consultation_id, bucket, path, filename, mime_type, size_bytes, checksum_sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		consultationID,
+		bucket,
+		objectPath,
+		attachment.Filename,
+		attachment.MimeType,
+		attachment.SizeBytes,
+		optionalStringDBValue(checksum),
+	).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		// Without a row nobody can find the file; the media audit would
+		// report it as an orphan, so remove it right away.
+		if deleteErr := a.Storage.Delete(ctx, bucket, objectPath); deleteErr != nil {
+			logFromContext(ctx).Warn("consultation attachment rollback failed", "bucket", bucket, "path", objectPath, "error", deleteErr)
+		}
+		return consultationAttachment{}, err
+	}
+	return attachment, nil
+}
+
+// consultationAttachmentLinks signs the stored files for the notification.
+func (a *App) consultationAttachmentLinks(ctx context.Context, attachments []consultationAttachment) []consultationAttachmentLink {
+	ttl := min(a.ConsultationAttachments.LinkTTL, a.StorageAccess.MaxTTL)
+	links := make([]consultationAttachmentLink, 0, len(attachments))
+	for _, attachment := range attachments {
+		signedURL, expiresAt, err := a.Storage.SignedURL(ctx, attachment.Bucket, attachment.Path, ttl)
+		if err != nil {
+			logFromContext(ctx).Warn("consultation attachment sign failed", "consultation_id", attachment.ConsultationID, "path", attachment.Path, "error", err)
+			continue
+		}
+		links = append(links, consultationAttachmentLink{
+			Filename:  attachment.Filename,
+			URL:       signedURL,
+			ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return links
+}
+
+func (a *App) listConsultationAttachments(ctx context.Context, consultationID int64) ([]consultationAttachment, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "consultation_attachments.list"),
+		`SELECT id, consultation_id, bucket, path, filename, mime_type, size_bytes, COALESCE(checksum_sha256, ''), created_at
+		FROM public.consultation_attachments
+		WHERE consultation_id = $1
+		ORDER BY id`,
+		consultationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]consultationAttachment, 0, 4)
+	for rows.Next() {
+		var item consultationAttachment
+		if err := rows.Scan(&item.ID, &item.ConsultationID, &item.Bucket, &item.Path, &item.Filename, &item.MimeType, &item.SizeBytes, &item.Checksum, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// consultationAttachmentKeys returns bucket/path keys of every attachment so
+// the media audit does not report them as orphans.
+func (a *App) consultationAttachmentKeys(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "consultation_attachments.keys"),
+		`SELECT bucket, path FROM public.consultation_attachments`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[string]struct{}{}
+	for rows.Next() {
+		var bucket, objectPath string
+		if err := rows.Scan(&bucket, &objectPath); err != nil {
+			return nil, err
+		}
+		keys[mediaKey(bucket, objectPath)] = struct{}{}
+	}
+	return keys, rows.Err()
+}
+
+// adminConsultationAttachmentsHandler serves
+// GET /admin/consultation_attachments?consultation_id= (with signed URLs) and
+// DELETE /admin/consultation_attachments/{id}.
+func (a *App) adminConsultationAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdminToken(w, r) {
+		return
+	}
+	storage, ok := a.storageOrError(w)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.adminConsultationAttachmentsList(w, r, storage)
+	case http.MethodDelete:
+		id, hasID, err := parseResourceID(r, consultationAttachmentsRoute)
+		if err != nil || !hasID {
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"status":  "error",
+				"message": "id is required",
+			})
+			return
+		}
+		a.adminConsultationAttachmentDelete(w, r, storage, id)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+	}
+}
+
+func (a *App) adminConsultationAttachmentsList(w http.ResponseWriter, r *http.Request, storage Storage) {
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	consultationID, err := parseIntOrDefault(strings.TrimSpace(r.URL.Query().Get("consultation_id")), 0)
+	if err != nil || consultationID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "consultation_id is required",
+		})
+		return
+	}
+
+	items, err := a.listConsultationAttachments(ctx, int64(consultationID))
+	if err != nil {
+		logFromContext(ctx).Error("consultation attachments query failed", "consultation_id", consultationID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to fetch attachments",
+		})
+		return
+	}
+	for i := range items {
+		signedURL, expiresAt, err := storage.SignedURL(ctx, items[i].Bucket, items[i].Path, a.StorageAccess.DefaultTTL)
+		if err != nil {
+			logFromContext(ctx).Warn("consultation attachment sign failed", "path", items[i].Path, "error", err)
+			continue
+		}
+		items[i].URL = signedURL
+		items[i].URLExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   items,
+	})
+}
+
+func (a *App) adminConsultationAttachmentDelete(w http.ResponseWriter, r *http.Request, storage Storage, id int64) {
+	ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
+	defer cancel()
+
+	var bucket, objectPath string
+	err := a.DB.QueryRowContext(
+		withQueryName(ctx, "consultation_attachments.delete"),
+		`DELETE FROM public.consultation_attachments WHERE id = $1 RETURNING bucket, path`,
+		id,
+	).Scan(&bucket, &objectPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"status":  "error",
+			"message": "attachment not found",
+		})
+		return
+	}
+	if err != nil {
+		logFromContext(ctx).Error("consultation attachment delete failed", "id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to delete attachment",
+		})
+		return
+	}
+
+	// A leftover object is picked up by the media audit as an orphan.
+	if err := storage.Delete(ctx, bucket, objectPath); err != nil && !errors.Is(err, errStorageNotFound) {
+		logFromContext(ctx).Warn("consultation attachment object delete failed", "bucket", bucket, "path", objectPath, "error", err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  "success",
+		"message": "attachment deleted",
+		"data": map[string]any{
+			"id":     id,
+			"bucket": bucket,
+			"path":   objectPath,
+		},
+	})
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const defaultShutdownDrainDelay = 5 * time.Second
+
+// Set at build time:
+//
+//	go build -ldflags "-X main.buildVersion=1.4.0 -X main.buildCommit=$(git rev-parse HEAD)"
+var (
+	buildVersion = "dev"
+	buildCommit  = ""
+)
+
+var processStartedAt = time.Now()
+
+// pendingNotifications counts manager notifications that are still being delivered.
+var pendingNotifications atomic.Int64
+
+func (a *App) livezHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// readyzHandler reports whether the instance should receive traffic. It turns
+// false as soon as shutdown starts so load balancers can drain connections.
+func (a *App) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if a.shuttingDown.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status": "shutting_down",
+			"ready":  false,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	if err := a.DB.PingContext(ctx); err != nil {
+		logFromContext(ctx).Warn("readiness database ping failed", "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status": "database_unavailable",
+			"ready":  false,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "ready": true})
+}
+
+// healthReport builds the admin-only /healthz?verbose=1 payload.
+func (a *App) healthReport(ctx context.Context) (map[string]any, bool) {
+	healthy := true
+
+	dbStart := time.Now()
+	dbErr := a.DB.PingContext(ctx)
+	dbReport := map[string]any{
+		"ok":         dbErr == nil,
+		"latency_ms": millisSince(dbStart),
+	}
+	if dbErr != nil {
+		healthy = false
+		dbReport["error"] = dbErr.Error()
+	}
+
+	stats := a.DB.Stats()
+	saturation := 0.0
+	if stats.MaxOpenConnections > 0 {
+		saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+	dbReport["pool"] = map[string]any{
+		"max_open":         stats.MaxOpenConnections,
+		"open":             stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"wait_count":       stats.WaitCount,
+		"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+		"saturation":       saturation,
+	}
+
+	migration := map[string]any{"version": nil}
+	if dbErr == nil {
+		version, err := schemaVersion(ctx, a.DB)
+		if err != nil {
+			migration["error"] = err.Error()
+		} else if version > 0 {
+			migration["version"] = version
+		}
+	}
+
+	status := "ok"
+	if !healthy {
+		status = "degraded"
+	}
+
+	return map[string]any{
+		"status":        status,
+		"ready":         !a.shuttingDown.Load() && dbErr == nil,
+		"db":            dbReport,
+		"migration":     migration,
+		"storage":       a.storageHealth(ctx),
+		"notifications": notificationHealth(),
+		"build":         buildInfo(),
+	}, healthy
+}
+
+func (a *App) storageHealth(ctx context.Context) map[string]any {
+	if a.Storage == nil {
+		return map[string]any{"configured": false}
+	}
+	report := map[string]any{
+		"configured": true,
+		"backend":    a.Storage.Backend(),
+	}
+
+	start := time.Now()
+	_, err := a.Storage.ListBuckets(ctx)
+	report["latency_ms"] = millisSince(start)
+	report["reachable"] = err == nil
+	if err != nil {
+		report["error"] = err.Error()
+	}
+	return report
+}
+
+func notificationHealth() map[string]any {
+	return map[string]any{
+		"pending":            pendingNotifications.Load(),
+		"webhook_configured": strings.TrimSpace(os.Getenv("ADMIN_NOTIFY_WEBHOOK_URL")) != "",
+	}
+}
+
+// schemaVersion returns the highest applied version from public.schema_migrations
+// (0 when the table is missing).
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	exists, err := hasTable(ctx, db, "schema_migrations")
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM public.schema_migrations`).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+func buildInfo() map[string]any {
+	commit := buildCommit
+	if commit == "" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" {
+					commit = setting.Value
+				}
+			}
+		}
+	}
+
+	return map[string]any{
+		"version":        buildVersion,
+		"commit":         commit,
+		"go_version":     runtime.Version(),
+		"start_time":     processStartedAt.UTC().Format(time.RFC3339),
+		"uptime_seconds": int64(time.Since(processStartedAt).Seconds()),
+	}
+}
+
+func millisSince(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+// shutdownDrainDelay is how long readiness reports false before the server
+// stops accepting connections (SHUTDOWN_DRAIN_DELAY, default 5s).
+func shutdownDrainDelay() time.Duration {
+	return parseDurationOrDefault(os.Getenv("SHUTDOWN_DRAIN_DELAY"), defaultShutdownDrainDelay)
+}
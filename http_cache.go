@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultHTTPCacheControl = "public, max-age=60, stale-while-revalidate=300"
+
+// cachedRouteTables lists, per public route, the tables its responses are
+// built from. Their change stamps (public.content_changes, kept by triggers)
+// validate a response without running the handler's queries.
+var cachedRouteTables = map[string][]string{
+	"/contact":           {"contact", "contact_page"},
+	"/about":             {"about_page", "about_metrics", "about_sections"},
+	"/banners":           {"banners"},
+	"/partners":          {"partners"},
+	"/tuning":            {"tuning", "media"},
+	"/service_offerings": {"service_offerings"},
+	"/privacy_sections":  {"privacy_sections"},
+	"/portfolio_items":   {"portfolio_items", "media"},
+	"/work_post":         {"work_post", "blog_posts"},
+	"/search":            {"tuning", "work_post", "blog_posts", "portfolio_items", "service_offerings"},
+	homePageRoute:        publicContentTables,
+	pagesRoute:           publicContentTables,
+}
+
+// publicContentTables are all tables behind public routes; composite pages
+// can draw on any of them.
+var publicContentTables = []string{
+	"banners", "contact", "contact_page", "about_page", "about_metrics", "about_sections",
+	"partners", "tuning", "service_offerings", "privacy_sections", "portfolio_items",
+	"work_post", "blog_posts", "media",
+}
+
+// httpCacheEpoch changes on every start, so a deploy that changes response
+// shapes never answers 304 to a validator issued by the previous build.
+var httpCacheEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+type httpCacheConfig struct {
+	Enabled bool
+	// Default is the Cache-Control for routes without an entry in Routes.
+	Default string
+	// Routes maps a route ("/about") to its Cache-Control; "off" disables
+	// validators and caching headers for that route.
+	Routes map[string]string
+}
+
+// loadHTTPCacheConfig reads HTTP_CACHE_* from env. HTTP_CACHE_ROUTES is a
+// ";"-separated list of route=Cache-Control pairs, for example
+// "/about=public, max-age=600, stale-while-revalidate=86400;/search=off".
+func loadHTTPCacheConfig() (httpCacheConfig, error) {
+	cfg := httpCacheConfig{
+		Enabled: true,
+		Default: firstNonEmpty(strings.TrimSpace(os.Getenv("HTTP_CACHE_CONTROL")), defaultHTTPCacheControl),
+		Routes:  map[string]string{},
+	}
+	if raw := strings.TrimSpace(os.Getenv("HTTP_CACHE_ENABLED")); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return httpCacheConfig{}, errors.New("HTTP_CACHE_ENABLED must be true or false")
+		}
+		cfg.Enabled = enabled
+	}
+	for _, entry := range strings.Split(os.Getenv("HTTP_CACHE_ROUTES"), ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		route, value, found := strings.Cut(entry, "=")
+		route, value = strings.TrimSpace(route), strings.TrimSpace(value)
+		if !found || value == "" {
+			return httpCacheConfig{}, errors.New("HTTP_CACHE_ROUTES entries must look like /route=Cache-Control")
+		}
+		if _, ok := cachedRouteTables[route]; !ok {
+			return httpCacheConfig{}, errors.New("HTTP_CACHE_ROUTES: " + route + " is not a cacheable route")
+		}
+		cfg.Routes[route] = value
+	}
+	return cfg, nil
+}
+
+func (c httpCacheConfig) cacheControl(route string) string {
+	if value, ok := c.Routes[route]; ok {
+		return value
+	}
+	return c.Default
+}
+
+// httpCached serves a public GET route through the response cache and adds
+// ETag, Last-Modified and Cache-Control to its successful responses, answering
+// If-None-Match / If-Modified-Since with 304. When every table of the route
+// has a change stamp, the ETag derives from the stamps and a matching request
+// is answered before the handler runs; otherwise the ETag is a hash of the
+// response body.
+func (a *App) httpCached(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next(w, r)
+			return
+		}
+		cacheControl := a.HTTPCache.cacheControl(route)
+		validators := a.HTTPCache.Enabled && cacheControl != "off"
+
+		key := responseCacheKey(route, r)
+		res, hit := a.Responses.get(route, key)
+		if !hit {
+			generation := a.Responses.currentGeneration()
+			var stamp time.Time
+			stamped := false
+			if validators {
+				stamp, stamped = a.contentStamp(r.Context(), cachedRouteTables[route])
+			}
+			if stamped {
+				etag := stampETag(key, stamp)
+				if notModified(r, etag, stamp) {
+					writeNotModified(w, etag, stamp, cacheControl)
+					return
+				}
+			}
+
+			rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+			next(rec, r)
+			res = cachedResponse{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+			if res.Status == http.StatusOK {
+				if stamped {
+					res.ETag, res.LastModified = stampETag(key, stamp), stamp
+				} else {
+					sum := sha256.Sum256(res.Body)
+					res.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				}
+				a.Responses.put(route, key, res, generation)
+			}
+		}
+
+		for name, values := range res.Header {
+			w.Header()[name] = slices.Clone(values)
+		}
+		if res.Status == http.StatusOK && validators {
+			if notModified(r, res.ETag, res.LastModified) {
+				writeNotModified(w, res.ETag, res.LastModified, cacheControl)
+				return
+			}
+			setCacheHeaders(w.Header(), res.ETag, res.LastModified, cacheControl)
+		}
+		w.WriteHeader(res.Status)
+		_, _ = w.Write(res.Body)
+	}
+}
+
+// contentStamp returns the latest change stamp of tables. ok is false when
+// the lookup fails or a table has no stamp yet (schema.sql not applied).
+func (a *App) contentStamp(ctx context.Context, tables []string) (time.Time, bool) {
+	if len(tables) == 0 {
+		return time.Time{}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var changedAt sql.NullTime
+	var count int
+	err := a.DB.QueryRowContext(
+		withQueryName(ctx, "http_cache.stamp"),
+		`SELECT MAX(changed_at), COUNT(*)
+		FROM public.content_changes
+		WHERE table_name = ANY($1)`,
+		tables,
+	).Scan(&changedAt, &count)
+	if err != nil {
+		logFromContext(ctx).Debug("content stamp lookup failed", "error", err)
+		return time.Time{}, false
+	}
+	if count != len(tables) || !changedAt.Valid {
+		return time.Time{}, false
+	}
+	return changedAt.Time, true
+}
+
+// stampETag identifies the representation at stamp of the request with the
+// given responseCacheKey, which covers everything the handler's output
+// depends on besides the data.
+func stampETag(key string, stamp time.Time) string {
+	sum := sha256.Sum256([]byte(httpCacheEpoch + "\n" + strconv.FormatInt(stamp.UnixNano(), 10) + "\n" + key))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates the conditional headers; If-None-Match wins over
+// If-Modified-Since as RFC 9110 requires.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+func setCacheHeaders(header http.Header, etag string, lastModified time.Time, cacheControl string) {
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", cacheControl)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+func writeNotModified(w http.ResponseWriter, etag string, lastModified time.Time, cacheControl string) {
+	setCacheHeaders(w.Header(), etag, lastModified, cacheControl)
+	if w.Header().Get("Vary") == "" {
+		w.Header().Set("Vary", "Accept-Language")
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// bufferedResponse holds a handler's response until its validators are known.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.wrote {
+		return
+	}
+	b.wrote = true
+	b.status = code
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wrote = true
+	return b.body.Write(p)
+}
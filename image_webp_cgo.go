@@ -0,0 +1,18 @@
+//go:build cgo
+
+package main
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// webpSupported reports whether WebP variants can be produced. The encoder
+// wraps libwebp, so CGO_ENABLED=0 builds fall back to JPEG/PNG only.
+const webpSupported = true
+
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+}
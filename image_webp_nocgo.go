@@ -0,0 +1,15 @@
+//go:build !cgo
+
+package main
+
+import (
+	"errors"
+	"image"
+	"io"
+)
+
+const webpSupported = false
+
+func encodeWebP(io.Writer, image.Image, int) error {
+	return errors.New("webp encoding requires a cgo build")
+}
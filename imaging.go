@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	defaultImageVariants       = "thumb:320x320:fill,card:800x600:fit,full:1920x1920:fit"
+	defaultImageVariantFormats = "auto,webp"
+	defaultImageQuality        = 82
+	defaultOriginalQuality     = 90
+	defaultMaxImagePixels      = 50_000_000
+)
+
+var errImageTooLarge = errors.New("image dimensions exceed the processing limit")
+
+type imageVariantSpec struct {
+	Name   string
+	Width  int
+	Height int
+	Fit    string
+}
+
+type imageProcessingConfig struct {
+	Enabled         bool
+	Variants        []imageVariantSpec
+	Formats         []string
+	Quality         int
+	OriginalQuality int
+	MaxPixels       int
+}
+
+// loadImageProcessingConfig reads IMAGE_PROCESSING (default on),
+// IMAGE_VARIANTS ("name:WxH:fit|fill,..."), IMAGE_VARIANT_FORMATS
+// ("auto" keeps JPEG/PNG by source, plus "webp"), IMAGE_QUALITY,
+// IMAGE_ORIGINAL_QUALITY and IMAGE_MAX_PIXELS.
+func loadImageProcessingConfig() (imageProcessingConfig, error) {
+	cfg := imageProcessingConfig{
+		Enabled:         true,
+		Quality:         defaultImageQuality,
+		OriginalQuality: defaultOriginalQuality,
+		MaxPixels:       defaultMaxImagePixels,
+	}
+	if raw := strings.TrimSpace(os.Getenv("IMAGE_PROCESSING")); raw != "" {
+		cfg.Enabled = isTruthy(raw)
+	}
+
+	variants, err := parseImageVariants(envOrDefault("IMAGE_VARIANTS", defaultImageVariants))
+	if err != nil {
+		return imageProcessingConfig{}, err
+	}
+	cfg.Variants = variants
+
+	for _, format := range uniqueNonEmpty(strings.Split(strings.ToLower(envOrDefault("IMAGE_VARIANT_FORMATS", defaultImageVariantFormats)), ",")...) {
+		switch format {
+		case "auto", "jpeg", "png":
+		case "webp":
+			if !webpSupported {
+				continue
+			}
+		default:
+			return imageProcessingConfig{}, fmt.Errorf("IMAGE_VARIANT_FORMATS: unknown format %q", format)
+		}
+		cfg.Formats = append(cfg.Formats, format)
+	}
+
+	for key, target := range map[string]*int{
+		"IMAGE_QUALITY":          &cfg.Quality,
+		"IMAGE_ORIGINAL_QUALITY": &cfg.OriginalQuality,
+		"IMAGE_MAX_PIXELS":       &cfg.MaxPixels,
+	} {
+		value, err := parseIntOrDefault(os.Getenv(key), *target)
+		if err != nil || value <= 0 {
+			return imageProcessingConfig{}, fmt.Errorf("%s must be a positive integer", key)
+		}
+		*target = value
+	}
+	if cfg.Quality > 100 || cfg.OriginalQuality > 100 {
+		return imageProcessingConfig{}, errors.New("IMAGE_QUALITY and IMAGE_ORIGINAL_QUALITY must be between 1 and 100")
+	}
+	return cfg, nil
+}
+
+func parseImageVariants(raw string) ([]imageVariantSpec, error) {
+	var specs []imageVariantSpec
+	for _, item := range uniqueNonEmpty(strings.Split(raw, ",")...) {
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("IMAGE_VARIANTS: %q must look like name:WxH[:fit|fill]", item)
+		}
+		spec := imageVariantSpec{Name: strings.TrimSpace(parts[0]), Fit: "fit"}
+		if len(parts) == 3 {
+			spec.Fit = normalizeImageFit(parts[2])
+		}
+		if spec.Name == "" || strings.ContainsAny(spec.Name, "/@. ") {
+			return nil, fmt.Errorf("IMAGE_VARIANTS: invalid variant name %q", parts[0])
+		}
+		widthRaw, heightRaw, ok := strings.Cut(strings.ToLower(parts[1]), "x")
+		width, widthErr := strconv.Atoi(strings.TrimSpace(widthRaw))
+		height, heightErr := strconv.Atoi(strings.TrimSpace(heightRaw))
+		if !ok || widthErr != nil || heightErr != nil || width <= 0 || height <= 0 || spec.Fit == "" {
+			return nil, fmt.Errorf("IMAGE_VARIANTS: %q must look like name:WxH[:fit|fill]", item)
+		}
+		spec.Width, spec.Height = width, height
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// normalizeImageFit maps accepted aliases to "fit" (contain) or "fill"
+// (cover + center crop). Unknown values yield "".
+func normalizeImageFit(raw string) string {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "fit", "contain", "inside":
+		return "fit"
+	case "fill", "cover", "crop":
+		return "fill"
+	}
+	return ""
+}
+
+// processedImage is the result of normalising an uploaded image.
+type processedImage struct {
+	// Original is the re-encoded upload (rotated, metadata stripped); nil
+	// when the source bytes are kept as they are.
+	Original     []byte
+	OriginalType string
+	Width        int
+	Height       int
+	Variants     []imageVariantOutput
+}
+
+type imageVariantOutput struct {
+	Name     string
+	Format   string
+	MimeType string
+	Width    int
+	Height   int
+	Data     []byte
+}
+
+// processUploadedImage decodes JPEG/PNG/GIF/WebP uploads, applies the EXIF
+// orientation and renders the configured variants. JPEGs are re-encoded so
+// EXIF (GPS, camera serials) never reaches the bucket. Non-images return nil.
+func processUploadedImage(file io.ReadSeeker, cfg imageProcessingConfig) (*processedImage, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	header, format, err := image.DecodeConfig(file)
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil {
+		return nil, nil
+	}
+	if header.Width*header.Height > cfg.MaxPixels {
+		return nil, fmt.Errorf("%w (%dx%d)", errImageTooLarge, header.Width, header.Height)
+	}
+
+	raw, err := io.ReadAll(file)
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", format, err)
+	}
+
+	result := &processedImage{}
+	if format == "jpeg" {
+		src = applyExifOrientation(src, jpegExifOrientation(raw))
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: cfg.OriginalQuality}); err != nil {
+			return nil, fmt.Errorf("re-encode original: %w", err)
+		}
+		result.Original = buf.Bytes()
+		result.OriginalType = "image/jpeg"
+	}
+	bounds := src.Bounds()
+	result.Width, result.Height = bounds.Dx(), bounds.Dy()
+
+	autoFormat := "jpeg"
+	if format == "png" || format == "gif" || (format == "webp" && !isOpaque(src)) {
+		autoFormat = "png"
+	}
+
+	for _, spec := range cfg.Variants {
+		resized := resizeImage(src, spec.Width, spec.Height, spec.Fit)
+		size := resized.Bounds()
+		for _, variantFormat := range cfg.Formats {
+			if variantFormat == "auto" {
+				variantFormat = autoFormat
+			}
+			var buf bytes.Buffer
+			if err := encodeImage(&buf, resized, variantFormat, cfg.Quality); err != nil {
+				return nil, fmt.Errorf("encode %s/%s: %w", spec.Name, variantFormat, err)
+			}
+			result.Variants = append(result.Variants, imageVariantOutput{
+				Name:     spec.Name,
+				Format:   variantFormat,
+				MimeType: imageMimeType(variantFormat),
+				Width:    size.Dx(),
+				Height:   size.Dy(),
+				Data:     buf.Bytes(),
+			})
+		}
+	}
+	return result, nil
+}
+
+// imageVariantPath places a variant next to its original:
+// "cars/bmw.jpg" + thumb/webp -> "cars/bmw@thumb.webp".
+func imageVariantPath(objectPath, name, format string) string {
+	ext := path.Ext(objectPath)
+	return strings.TrimSuffix(objectPath, ext) + "@" + name + "." + imageExtension(format)
+}
+
+func imageExtension(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+func imageMimeType(format string) string {
+	return "image/" + format
+}
+
+// resizeImage scales src down to the box. "fit" keeps the whole image inside
+// it; "fill" covers it and crops the center. Images are never upscaled.
+func resizeImage(src image.Image, width, height int, fit string) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if width <= 0 {
+		width = srcW
+	}
+	if height <= 0 {
+		height = srcH
+	}
+
+	crop := bounds
+	var dstW, dstH int
+	if fit == "fill" {
+		// Crop the source to the target aspect ratio first.
+		if srcW*height > srcH*width {
+			cropW := srcH * width / height
+			offset := (srcW - cropW) / 2
+			crop = image.Rect(bounds.Min.X+offset, bounds.Min.Y, bounds.Min.X+offset+cropW, bounds.Max.Y)
+		} else {
+			cropH := srcW * height / width
+			offset := (srcH - cropH) / 2
+			crop = image.Rect(bounds.Min.X, bounds.Min.Y+offset, bounds.Max.X, bounds.Min.Y+offset+cropH)
+		}
+		dstW, dstH = min(width, crop.Dx()), min(height, crop.Dy())
+		if crop.Dx() < width || crop.Dy() < height {
+			// Too small to fill: keep the aspect ratio at source resolution.
+			scale := min(float64(crop.Dx())/float64(width), float64(crop.Dy())/float64(height))
+			dstW, dstH = int(float64(width)*scale), int(float64(height)*scale)
+		}
+	} else {
+		scale := min(1, float64(width)/float64(srcW), float64(height)/float64(srcH))
+		dstW, dstH = int(float64(srcW)*scale+0.5), int(float64(srcH)*scale+0.5)
+	}
+	dstW, dstH = max(dstW, 1), max(dstH, 1)
+
+	if dstW == srcW && dstH == srcH && crop == bounds {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	return dst
+}
+
+func encodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "jpeg":
+		return jpeg.Encode(w, flattenAlpha(img), &jpeg.Options{Quality: quality})
+	case "png":
+		return (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	case "webp":
+		return encodeWebP(w, img, quality)
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// flattenAlpha puts transparent images on white so JPEG output does not turn
+// transparent areas black.
+func flattenAlpha(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+	return false
+}
+
+// jpegExifOrientation returns the EXIF orientation tag (1-8) of a JPEG, or 1.
+func jpegExifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		segmentLen := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if marker == 0xDA || segmentLen < 2 || offset+2+segmentLen > len(data) {
+			return 1 // start of scan: no more metadata segments
+		}
+		segment := data[offset+4 : offset+2+segmentLen]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		offset += 2 + segmentLen
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyExifOrientation rotates/flips img so it displays upright without
+// relying on the (now stripped) EXIF tag.
+func applyExifOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirror horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 CW
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 CCW
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskLRUCache stores transformed images as files and evicts the least
+// recently used ones once the total size exceeds maxBytes. The index lives in
+// memory and is rebuilt from file mtimes on startup.
+type diskLRUCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // front = most recently used
+	entries map[string]*list.Element
+}
+
+type diskCacheEntry struct {
+	key  string
+	size int64
+}
+
+func newDiskLRUCache(dir string, maxBytes int64) (*diskLRUCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create image cache dir: %w", err)
+	}
+	cache := &diskLRUCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			_ = os.Remove(path) // leftover temp file
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, found{key: entry.Name(), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan image cache dir: %w", err)
+	}
+
+	// Oldest first, so the newest end up at the front.
+	slices.SortFunc(files, func(a, b found) int {
+		return a.modTime.Compare(b.modTime)
+	})
+	for _, file := range files {
+		cache.entries[file.key] = cache.order.PushFront(&diskCacheEntry{key: file.key, size: file.size})
+		cache.size += file.size
+	}
+	cache.mu.Lock()
+	cache.evictLocked()
+	cache.mu.Unlock()
+	return cache, nil
+}
+
+// filePath shards files by the first two characters of the key.
+func (c *diskLRUCache) filePath(key string) string {
+	shard := key
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+	return filepath.Join(c.dir, shard, key)
+}
+
+// Get returns the cached bytes and marks the entry as recently used.
+func (c *diskLRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(element)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.filePath(key))
+	if err != nil {
+		c.remove(key)
+		return nil, false
+	}
+	// Keep mtime roughly in step with use so the order survives restarts.
+	now := time.Now()
+	_ = os.Chtimes(c.filePath(key), now, now)
+	return data, true
+}
+
+func (c *diskLRUCache) Put(key string, data []byte) error {
+	target := c.filePath(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*diskCacheEntry)
+		c.size -= entry.size
+		entry.size = int64(len(data))
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(&diskCacheEntry{key: key, size: int64(len(data))})
+	}
+	c.size += int64(len(data))
+	c.evictLocked()
+	imageCacheBytes.Set(float64(c.size))
+	return nil
+}
+
+func (c *diskLRUCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.removeElementLocked(element)
+	}
+}
+
+func (c *diskLRUCache) evictLocked() {
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		if oldest == nil {
+			return
+		}
+		c.removeElementLocked(oldest)
+	}
+}
+
+func (c *diskLRUCache) removeElementLocked(element *list.Element) {
+	entry := element.Value.(*diskCacheEntry)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	_ = os.Remove(c.filePath(entry.key))
+	imageCacheBytes.Set(float64(c.size))
+}
+
+// Size returns the number of bytes currently cached.
+func (c *diskLRUCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	imageProxyRoute             = "/img/"
+	defaultImageProxyQuality    = 80
+	defaultImageProxyMaxSize    = 2560
+	defaultImageCacheDir        = "image_cache"
+	defaultImageCacheMaxMB      = 512
+	imageProxySignatureBytes    = 16
+	imageProxySourceETagTTL     = time.Minute
+	imageProxyTransformTimeout  = 30 * time.Second
+	maxImageProxySourceETagKeys = 10_000
+
+	// Only URLs with a "v" cache-buster are immutable; unversioned ones can
+	// change in place (uploads upsert), so clients revalidate against the
+	// ETag, which follows the source object's.
+	imageProxyVersionedCacheControl   = "public, max-age=31536000, immutable"
+	imageProxyUnversionedCacheControl = "public, max-age=300, stale-while-revalidate=3600"
+)
+
+var (
+	errImageProxyBadParams   = errors.New("invalid image parameters")
+	errImageProxySourceLarge = errors.New("source image is too large")
+	errImageProxyNotAnImage  = errors.New("source is not a supported image")
+)
+
+type imageProxyConfig struct {
+	Secret         []byte
+	AllowUnsigned  bool
+	CacheDir       string
+	CacheMaxBytes  int64
+	MaxDimension   int
+	MaxSourceBytes int64
+}
+
+// loadImageProxyConfig reads IMAGE_PROXY_SECRET (HMAC key for the sig
+// parameter), IMAGE_PROXY_ALLOW_UNSIGNED, IMAGE_PROXY_MAX_DIMENSION,
+// IMAGE_PROXY_MAX_SOURCE_MB, IMAGE_CACHE_DIR and IMAGE_CACHE_MAX_MB. The
+// proxy is off unless a secret is set or unsigned URLs are explicitly allowed.
+func loadImageProxyConfig() (imageProxyConfig, bool, error) {
+	cfg := imageProxyConfig{
+		Secret:        []byte(strings.TrimSpace(os.Getenv("IMAGE_PROXY_SECRET"))),
+		AllowUnsigned: isTruthy(os.Getenv("IMAGE_PROXY_ALLOW_UNSIGNED")),
+		CacheDir:      envOrDefault("IMAGE_CACHE_DIR", defaultImageCacheDir),
+	}
+	if len(cfg.Secret) == 0 && !cfg.AllowUnsigned {
+		return imageProxyConfig{}, false, nil
+	}
+
+	maxDimension, err := parseIntOrDefault(os.Getenv("IMAGE_PROXY_MAX_DIMENSION"), defaultImageProxyMaxSize)
+	if err != nil || maxDimension <= 0 {
+		return imageProxyConfig{}, false, errors.New("IMAGE_PROXY_MAX_DIMENSION must be a positive integer")
+	}
+	sourceMB, err := parseIntOrDefault(os.Getenv("IMAGE_PROXY_MAX_SOURCE_MB"), storageUploadMaxMB)
+	if err != nil || sourceMB <= 0 {
+		return imageProxyConfig{}, false, errors.New("IMAGE_PROXY_MAX_SOURCE_MB must be a positive integer")
+	}
+	cacheMB, err := parseIntOrDefault(os.Getenv("IMAGE_CACHE_MAX_MB"), defaultImageCacheMaxMB)
+	if err != nil || cacheMB <= 0 {
+		return imageProxyConfig{}, false, errors.New("IMAGE_CACHE_MAX_MB must be a positive integer")
+	}
+	cfg.MaxDimension = maxDimension
+	cfg.MaxSourceBytes = int64(sourceMB) << 20
+	cfg.CacheMaxBytes = int64(cacheMB) << 20
+	return cfg, true, nil
+}
+
+// imageProxy renders /img/{bucket}/{path}?w=&h=&fit=&format=&q= on demand
+// and keeps the results in a size-capped disk cache.
+type imageProxy struct {
+	cfg    imageProxyConfig
+	cache  *diskLRUCache
+	flight singleflight.Group
+
+	etagMu      sync.Mutex
+	sourceETags map[string]cachedSourceETag
+}
+
+type cachedSourceETag struct {
+	etag    string
+	expires time.Time
+}
+
+func newImageProxy(cfg imageProxyConfig) (*imageProxy, error) {
+	cache, err := newDiskLRUCache(cfg.CacheDir, cfg.CacheMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	imageCacheBytes.Set(float64(cache.Size()))
+	return &imageProxy{cfg: cfg, cache: cache, sourceETags: map[string]cachedSourceETag{}}, nil
+}
+
+// imageTransform is a parsed, validated set of /img parameters. Zero width or
+// height means "unconstrained"; Format "auto" is resolved per request.
+type imageTransform struct {
+	Width   int
+	Height  int
+	Fit     string
+	Format  string
+	Quality int
+}
+
+func parseImageTransform(query url.Values, maxDimension int) (imageTransform, error) {
+	t := imageTransform{
+		Fit:     normalizeImageFit(query.Get("fit")),
+		Format:  strings.ToLower(strings.TrimSpace(query.Get("format"))),
+		Quality: defaultImageProxyQuality,
+	}
+	if t.Fit == "" {
+		return imageTransform{}, fmt.Errorf("%w: fit must be fit or fill", errImageProxyBadParams)
+	}
+
+	for key, target := range map[string]*int{"w": &t.Width, "h": &t.Height} {
+		raw := strings.TrimSpace(query.Get(key))
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 || value > maxDimension {
+			return imageTransform{}, fmt.Errorf("%w: %s must be between 1 and %d", errImageProxyBadParams, key, maxDimension)
+		}
+		*target = value
+	}
+	if raw := strings.TrimSpace(query.Get("q")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > 100 {
+			return imageTransform{}, fmt.Errorf("%w: q must be between 1 and 100", errImageProxyBadParams)
+		}
+		t.Quality = value
+	}
+
+	switch t.Format {
+	case "", "auto":
+		t.Format = "auto"
+	case "jpg", "jpeg":
+		t.Format = "jpeg"
+	case "png":
+	case "webp":
+		if !webpSupported {
+			return imageTransform{}, fmt.Errorf("%w: webp output is not available on this server", errImageProxyBadParams)
+		}
+	default:
+		return imageTransform{}, fmt.Errorf("%w: format must be auto, jpeg, png or webp", errImageProxyBadParams)
+	}
+	return t, nil
+}
+
+// canonicalImageProxyQuery returns the parameters covered by the signature,
+// sorted by key, so equivalent URLs sign identically. "v" is an opaque
+// cache-buster that makes the response immutable.
+func canonicalImageProxyQuery(query url.Values) string {
+	signed := url.Values{}
+	for _, key := range []string{"w", "h", "fit", "format", "q", "v"} {
+		if value := strings.TrimSpace(query.Get(key)); value != "" {
+			signed.Set(key, value)
+		}
+	}
+	return signed.Encode()
+}
+
+func imageProxySignature(secret []byte, bucket, objectPath string, query url.Values) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(bucket + "/" + objectPath + "?" + canonicalImageProxyQuery(query)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:imageProxySignatureBytes])
+}
+
+// signImageProxyURL builds a relative /img URL with a sig parameter.
+func signImageProxyURL(secret []byte, bucket, objectPath string, query url.Values) string {
+	signed, _ := url.ParseQuery(canonicalImageProxyQuery(query))
+	if len(secret) > 0 {
+		signed.Set("sig", imageProxySignature(secret, bucket, objectPath, query))
+	}
+	target := imageProxyRoute + url.PathEscape(bucket) + "/" + encodeStoragePath(objectPath)
+	if encoded := signed.Encode(); encoded != "" {
+		target += "?" + encoded
+	}
+	return target
+}
+
+func (p *imageProxy) verifySignature(bucket, objectPath string, query url.Values) bool {
+	if len(p.cfg.Secret) == 0 {
+		return p.cfg.AllowUnsigned
+	}
+	provided := query.Get("sig")
+	if provided == "" {
+		return p.cfg.AllowUnsigned
+	}
+	expected := imageProxySignature(p.cfg.Secret, bucket, objectPath, query)
+	return hmac.Equal([]byte(provided), []byte(expected))
+}
+
+// imageProxyHandler serves GET /img/{bucket}/{path...}.
+func (a *App) imageProxyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	proxy := a.ImageProxy
+	if proxy == nil || a.Storage == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	bucketValue, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, imageProxyRoute), "/")
+	bucket, err := cleanStorageBucket(bucketValue)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	objectPath, err := cleanStoragePath(rest)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	// Proxy links never expire and are cached publicly, so private buckets
+	// are only reachable through signed storage links.
+	if a.StorageAccess.isPrivate(bucket) {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	if !proxy.verifySignature(bucket, objectPath, query) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	transform, err := parseImageTransform(query, proxy.cfg.MaxDimension)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// "auto" picks WebP for clients that advertise it, otherwise the source
+	// family (JPEG, or PNG for images with transparency).
+	auto := transform.Format == "auto"
+	if auto {
+		w.Header().Set("Vary", "Accept")
+		if webpSupported && strings.Contains(r.Header.Get("Accept"), "image/webp") {
+			transform.Format = "webp"
+		}
+	}
+
+	ctx := r.Context()
+	sourceETag, err := proxy.sourceETag(ctx, a.Storage, bucket, objectPath)
+	if err != nil {
+		writeImageProxyError(w, r, bucket, objectPath, err)
+		return
+	}
+
+	key := imageProxyCacheKey(bucket, objectPath, sourceETag, transform)
+	data, hit := proxy.cache.Get(key)
+	if hit {
+		imageCacheRequestsTotal.WithLabelValues("hit").Inc()
+	} else {
+		imageCacheRequestsTotal.WithLabelValues("miss").Inc()
+		result, err, _ := proxy.flight.Do(key, func() (any, error) {
+			// Detached from the request so one impatient client does not
+			// fail the render for everyone waiting on the same key.
+			renderCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), imageProxyTransformTimeout)
+			defer cancel()
+			rendered, err := proxy.render(renderCtx, a.Storage, a.Images.MaxPixels, bucket, objectPath, transform)
+			if err != nil {
+				return nil, err
+			}
+			if err := proxy.cache.Put(key, rendered); err != nil {
+				logFromContext(renderCtx).Warn("image cache write failed", "key", key, "error", err)
+			}
+			return rendered, nil
+		})
+		if err != nil {
+			writeImageProxyError(w, r, bucket, objectPath, err)
+			return
+		}
+		data = result.([]byte)
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", http.DetectContentType(data))
+	if strings.TrimSpace(r.URL.Query().Get("v")) != "" {
+		header.Set("Cache-Control", imageProxyVersionedCacheControl)
+	} else {
+		header.Set("Cache-Control", imageProxyUnversionedCacheControl)
+	}
+	header.Set("ETag", `"`+key[:32]+`"`)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if hit {
+		header.Set("X-Cache", "HIT")
+	} else {
+		header.Set("X-Cache", "MISS")
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// sourceETag returns the storage ETag of the original, memoised briefly so
+// cache hits do not cost a storage round trip each.
+func (p *imageProxy) sourceETag(ctx context.Context, storage Storage, bucket, objectPath string) (string, error) {
+	memoKey := bucket + "/" + objectPath
+	now := time.Now()
+
+	p.etagMu.Lock()
+	cached, ok := p.sourceETags[memoKey]
+	p.etagMu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.etag, nil
+	}
+
+	object, err := storage.Stat(ctx, bucket, objectPath)
+	if err != nil {
+		return "", err
+	}
+	etag := firstNonEmpty(object.ETag, strconv.FormatInt(object.Size, 10)+"-"+strconv.FormatInt(object.UpdatedAt.UnixNano(), 10))
+
+	p.etagMu.Lock()
+	if len(p.sourceETags) >= maxImageProxySourceETagKeys {
+		p.sourceETags = map[string]cachedSourceETag{}
+	}
+	p.sourceETags[memoKey] = cachedSourceETag{etag: etag, expires: now.Add(imageProxySourceETagTTL)}
+	p.etagMu.Unlock()
+	return etag, nil
+}
+
+func (p *imageProxy) render(ctx context.Context, storage Storage, maxPixels int, bucket, objectPath string, t imageTransform) ([]byte, error) {
+	body, _, err := storage.Get(ctx, bucket, objectPath)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(body, p.cfg.MaxSourceBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read source: %w", err)
+	}
+	if int64(len(raw)) > p.cfg.MaxSourceBytes {
+		return nil, errImageProxySourceLarge
+	}
+
+	header, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, errImageProxyNotAnImage
+	}
+	if header.Width*header.Height > maxPixels {
+		return nil, fmt.Errorf("%w (%dx%d)", errImageTooLarge, header.Width, header.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errImageProxyNotAnImage, err)
+	}
+	if format == "jpeg" {
+		src = applyExifOrientation(src, jpegExifOrientation(raw))
+	}
+
+	if t.Format == "auto" {
+		t.Format = "jpeg"
+		if format == "png" || format == "gif" || !isOpaque(src) {
+			t.Format = "png"
+		}
+	}
+
+	resized := resizeImage(src, t.Width, t.Height, t.Fit)
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, resized, t.Format, t.Quality); err != nil {
+		return nil, fmt.Errorf("encode %s: %w", t.Format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func imageProxyCacheKey(bucket, objectPath, sourceETag string, t imageTransform) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		bucket,
+		objectPath,
+		sourceETag,
+		strconv.Itoa(t.Width),
+		strconv.Itoa(t.Height),
+		t.Fit,
+		t.Format,
+		strconv.Itoa(t.Quality),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeImageProxyError(w http.ResponseWriter, r *http.Request, bucket, objectPath string, err error) {
+	switch {
+	case errors.Is(err, errStorageNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, errImageProxyNotAnImage):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, errImageProxySourceLarge), errors.Is(err, errImageTooLarge):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		logFromContext(r.Context()).Error("image proxy failed", "bucket", bucket, "path", objectPath, "error", err)
+		http.Error(w, "image unavailable", http.StatusBadGateway)
+	}
+}
+
+// adminImageSignHandler returns signed /img URLs for the admin panel:
+// GET /admin/img/sign?bucket=&path=&w=&h=&fit=&format=&q=&v=
+func (a *App) adminImageSignHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdminToken(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+		return
+	}
+	if a.ImageProxy == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"status":  "error",
+			"message": "image proxy is disabled (set IMAGE_PROXY_SECRET)",
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	bucket, err := cleanStorageBucket(firstNonEmpty(query.Get("bucket"), defaultStorageBucket()))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	objectPath, err := cleanStoragePath(query.Get("path"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if a.StorageAccess.isPrivate(bucket) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "bucket is private; use /admin/storage/sign instead",
+		})
+		return
+	}
+	if _, err := parseImageTransform(query, a.ImageProxy.cfg.MaxDimension); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"bucket": bucket,
+			"path":   objectPath,
+			"url":    signImageProxyURL(a.ImageProxy.cfg.Secret, bucket, objectPath, query),
+		},
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type requestInfoKey struct{}
+
+// requestInfo is attached to every request context by loggingMiddleware.
+// Handlers fill in fields that are only known later (e.g. the admin user).
+type requestInfo struct {
+	ID            string
+	ClientIP      string
+	AdminUsername string
+}
+
+func requestInfoFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// logFromContext returns the default logger annotated with the request ID
+// and, when tracing is on, the trace ID.
+func logFromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if ctx == nil {
+		return logger
+	}
+	if info := requestInfoFromContext(ctx); info != nil && info.ID != "" {
+		logger = logger.With("request_id", info.ID)
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		logger = logger.With("trace_id", spanCtx.TraceID().String())
+	}
+	return logger
+}
+
+// setupLogging installs a JSON slog handler as the process default. The
+// standard log package is routed through it as well.
+func setupLogging() {
+	level := parseLogLevel(os.Getenv("LOG_LEVEL"))
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+}
+
+func parseLogLevel(raw string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// parseSampleRate reads LOG_SUCCESS_SAMPLE_RATE (0..1, default 1).
+func parseSampleRate(raw string) float64 {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return 1
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate > 1 {
+		return 1
+	}
+	if rate < 0 {
+		return 0
+	}
+	return rate
+}
+
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// loggingMiddleware assigns a request ID and writes one structured log line
+// per request. Successful requests can be sampled with LOG_SUCCESS_SAMPLE_RATE;
+// 4xx and 5xx responses are always logged.
+func loggingMiddleware(next http.Handler) http.Handler {
+	sampleRate := parseSampleRate(os.Getenv("LOG_SUCCESS_SAMPLE_RATE"))
+	trustProxy := isTruthy(os.Getenv("TRUST_PROXY_HEADERS"))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		info := &requestInfo{
+			ID:       resolveRequestID(r.Header.Get(requestIDHeader)),
+			ClientIP: clientIP(r, trustProxy),
+		}
+		w.Header().Set(requestIDHeader, info.ID)
+		r = r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.statusCode()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case sampleRate < 1 && rand.Float64() >= sampleRate:
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", info.ID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", info.ClientIP),
+		}
+		if info.AdminUsername != "" {
+			attrs = append(attrs, slog.String("admin", info.AdminUsername))
+		}
+		slog.LogAttrs(r.Context(), level, "http request", attrs...)
+	})
+}
+
+// resolveRequestID keeps a sane incoming X-Request-ID or generates a new one.
+func resolveRequestID(incoming string) string {
+	candidate := strings.TrimSpace(incoming)
+	if candidate != "" && len(candidate) <= 128 && isRequestIDSafe(candidate) {
+		return candidate
+	}
+
+	raw := make([]byte, 16)
+	if _, err := cryptorand.Read(raw); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(raw)
+}
+
+func isRequestIDSafe(value string) bool {
+	for _, ch := range value {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder captures the status code and body size written by handlers.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(p)
+	s.bytes += int64(n)
+	return n, err
+}
+
+func (s *statusRecorder) statusCode() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
@@ -13,44 +13,79 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 )
 
 // App holds shared dependencies.
 type App struct {
-	DB *sql.DB
+	DB                      *sql.DB
+	ConsultationGuard       *consultationGuard
+	Captcha                 *captchaGate
+	Storage                 Storage
+	Images                  imageProcessingConfig
+	ImageProxy              *imageProxy
+	UploadPolicies          uploadPolicySet
+	UploadSessions          uploadSessionConfig
+	StorageAccess           storageAccessConfig
+	ConsultationAttachments consultationAttachmentConfig
+	HTTPCache               httpCacheConfig
+	Responses               *responseCache
+	Pages                   map[string]pageComposite
+
+	shuttingDown atomic.Bool
+	mediaAudit   mediaAuditState
 }
 
 var phonePattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
 
 const (
-	healthTimeout       = 5 * time.Second
-	readTimeout         = 10 * time.Second
-	writeTimeout        = 12 * time.Second
-	storageUploadMaxMB  = 25
-	defaultStorageLimit = 50
-	maxStorageLimit     = 500
-	defaultAdminSession = 12 * time.Hour
-	maxAdminSession     = 7 * 24 * time.Hour
-	adminTokenPrefix    = "cgadm1"
+	healthTimeout           = 5 * time.Second
+	readTimeout             = 10 * time.Second
+	writeTimeout            = 12 * time.Second
+	storageUploadMaxMB      = 25
+	storageBatchUploadMaxMB = 200
+	maxBatchUploadFiles     = 50
+	multipartMemoryBytes    = 32 << 20
+	defaultStorageLimit     = 50
+	maxStorageLimit         = 500
+	defaultAdminSession     = 12 * time.Hour
+	maxAdminSession         = 7 * 24 * time.Hour
+	adminTokenPrefix        = "cgadm1"
+	storageUploadTimeout    = 60 * time.Second
+	serverReadTimeout       = 15 * time.Second
+	serverWriteTimeout      = 15 * time.Second
+	// minUploadBytesPerSecond is the slowest client link large uploads are
+	// given time for (about a weak mobile connection).
+	minUploadBytesPerSecond = 64 << 10
 )
 
 func main() {
+	setupLogging()
+
 	if err := loadDotEnv(); err != nil {
-		log.Printf("warning: could not load .env: %v", err)
+		slog.Warn("could not load .env", "error", err)
+	}
+
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		fatal("tracing setup failed", "error", err)
 	}
 
 	dsn := firstNonEmpty(
@@ -58,51 +93,167 @@ func main() {
 		os.Getenv("POSTGRES_DSN"),
 	)
 	if dsn == "" {
-		log.Fatal("DATABASE_URL or POSTGRES_DSN must be set")
+		fatal("DATABASE_URL or POSTGRES_DSN must be set")
 	}
 	dsn = normalizeDSN(dsn)
 
 	db, err := openDB(dsn)
 	if err != nil {
-		log.Fatalf("database connection failed: %v", err)
+		fatal("database connection failed", "error", err)
 	}
 	defer db.Close()
+	registerDBMetrics(db)
 
-	app := &App{DB: db}
+	guard, err := newConsultationGuard(db)
+	if err != nil {
+		fatal("consultation anti-spam config invalid", "error", err)
+	}
+
+	captcha, err := newCaptchaGate(db)
+	if err != nil {
+		fatal("captcha config invalid", "error", err)
+	}
+
+	storage, err := newStorageFromEnv()
+	switch {
+	case errors.Is(err, errStorageNotConfigured):
+		slog.Warn("storage endpoints disabled", "error", err)
+	case err != nil:
+		fatal("storage config invalid", "error", err)
+	default:
+		slog.Info("storage backend ready", "backend", storage.Backend())
+	}
+
+	images, err := loadImageProcessingConfig()
+	if err != nil {
+		fatal("image processing config invalid", "error", err)
+	}
+
+	uploadPolicies, err := loadUploadPolicies()
+	if err != nil {
+		fatal("upload policy config invalid", "error", err)
+	}
+
+	uploadSessions, err := loadUploadSessionConfig()
+	if err != nil {
+		fatal("upload session config invalid", "error", err)
+	}
+
+	consultationAttachments, err := loadConsultationAttachmentConfig()
+	if err != nil {
+		fatal("consultation attachment config invalid", "error", err)
+	}
+
+	httpCache, err := loadHTTPCacheConfig()
+	if err != nil {
+		fatal("http cache config invalid", "error", err)
+	}
+
+	responses, err := loadResponseCache()
+	if err != nil {
+		fatal("response cache config invalid", "error", err)
+	}
+
+	pages, err := loadPageComposites()
+	if err != nil {
+		fatal("page composite config invalid", "error", err)
+	}
+
+	var proxy *imageProxy
+	proxyConfig, proxyEnabled, err := loadImageProxyConfig()
+	switch {
+	case err != nil:
+		fatal("image proxy config invalid", "error", err)
+	case !proxyEnabled:
+		slog.Warn("image proxy disabled", "reason", "IMAGE_PROXY_SECRET is not set")
+	default:
+		proxy, err = newImageProxy(proxyConfig)
+		if err != nil {
+			fatal("image proxy cache setup failed", "error", err)
+		}
+	}
+
+	app := &App{DB: db, ConsultationGuard: guard, Captcha: captcha, Storage: storage, Images: images, ImageProxy: proxy, UploadPolicies: uploadPolicies, UploadSessions: uploadSessions, StorageAccess: loadStorageAccessConfig(), ConsultationAttachments: consultationAttachments, HTTPCache: httpCache, Responses: responses, Pages: pages}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go app.runMediaAuditLoop(jobsCtx)
+	go app.runUploadSessionCleanupLoop(jobsCtx)
+	go app.backfillSlugs(jobsCtx)
+	go app.runResponseCacheListener(jobsCtx, dsn)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", app.rootHandler)
 	mux.HandleFunc("/healthz", app.healthHandler)
-	mux.HandleFunc("/contact", app.contactHandler)
-	mux.HandleFunc("/about", app.aboutHandler)
-	mux.HandleFunc("/banners", app.bannersHandler)
-	mux.HandleFunc("/partners", app.partnersHandler)
-	mux.HandleFunc("/tuning", app.tuningHandler)
-	mux.HandleFunc("/service_offerings", app.serviceOfferingsHandler)
-	mux.HandleFunc("/privacy_sections", app.privacySectionsHandler)
+	mux.HandleFunc("/livez", app.livezHandler)
+	mux.HandleFunc("/readyz", app.readyzHandler)
+	mux.HandleFunc("/contact", app.httpCached("/contact", app.contactHandler))
+	mux.HandleFunc("/about", app.httpCached("/about", app.aboutHandler))
+	mux.HandleFunc("/banners", app.httpCached("/banners", app.bannersHandler))
+	mux.HandleFunc("/partners", app.httpCached("/partners", app.partnersHandler))
+	mux.HandleFunc("/tuning", app.httpCached("/tuning", app.tuningHandler))
+	mux.HandleFunc("/tuning/", app.httpCached("/tuning", app.tuningHandler))
+	mux.HandleFunc("/service_offerings", app.httpCached("/service_offerings", app.serviceOfferingsHandler))
+	mux.HandleFunc("/service_offerings/", app.httpCached("/service_offerings", app.serviceOfferingsHandler))
+	mux.HandleFunc("/privacy_sections", app.httpCached("/privacy_sections", app.privacySectionsHandler))
 	mux.HandleFunc("/api/consultations", app.consultationsHandler)
-	mux.HandleFunc("/portfolio_items", app.portfolioItemsHandler)
-	mux.HandleFunc("/work_post", app.workPostHandler)
+	mux.HandleFunc("/portfolio_items", app.httpCached("/portfolio_items", app.portfolioItemsHandler))
+	mux.HandleFunc("/portfolio_items/", app.httpCached("/portfolio_items", app.portfolioItemsHandler))
+	mux.HandleFunc("/work_post", app.httpCached("/work_post", app.workPostHandler))
+	mux.HandleFunc("/work_post/", app.httpCached("/work_post", app.workPostHandler))
+	mux.HandleFunc("/search", app.httpCached("/search", app.searchHandler))
+	mux.HandleFunc(homePageRoute, app.httpCached(homePageRoute, app.pageHandler))
+	mux.HandleFunc(pagesRoute+"/", app.httpCached(pagesRoute, app.pageHandler))
+	mux.HandleFunc(apiV1Prefix+"/", apiV1Handler(mux))
 	mux.HandleFunc("/admin/auth/login", app.adminAuthLoginHandler)
 	mux.HandleFunc("/admin/auth/me", app.adminAuthMeHandler)
 	app.registerAdminCRUDRoutes(mux)
 	mux.HandleFunc("/admin/storage/upload", app.adminStorageUploadHandler)
 	mux.HandleFunc("/admin/storage/files", app.adminStorageListHandler)
 	mux.HandleFunc("/admin/storage/file", app.adminStorageDeleteHandler)
+	mux.HandleFunc("/admin/storage/copy", app.adminStorageCopyHandler)
+	mux.HandleFunc("/admin/storage/move", app.adminStorageMoveHandler)
+	mux.HandleFunc("/admin/storage/rename", app.adminStorageRenameHandler)
+	mux.HandleFunc("/admin/storage/sign", app.adminStorageSignHandler)
+	mux.HandleFunc(uploadSessionsRoute, app.adminUploadSessionsHandler)
+	mux.HandleFunc(uploadSessionsRoute+"/", app.adminUploadSessionsHandler)
+	mux.HandleFunc(consultationAttachmentsRoute, app.adminConsultationAttachmentsHandler)
+	mux.HandleFunc(consultationAttachmentsRoute+"/", app.adminConsultationAttachmentsHandler)
+	mux.HandleFunc("/admin/translations/missing", app.adminMissingTranslationsHandler)
+	mux.HandleFunc("/admin/media", app.adminMediaHandler)
+	mux.HandleFunc("/admin/media/", app.adminMediaHandler)
+	mux.HandleFunc("/admin/img/sign", app.adminImageSignHandler)
+	mux.HandleFunc(imageProxyRoute, app.imageProxyHandler)
+	if local, ok := storage.(*localStorage); ok {
+		local.uploadPolicies = app.UploadPolicies
+		mux.HandleFunc(localStorageRoute, local.filesHandler)
+	}
+
+	metricsCfg := loadMetricsConfig()
+	var metricsServer *http.Server
+	switch {
+	case metricsCfg.Addr != "":
+		metricsServer = startMetricsServer(metricsCfg)
+		slog.Info("metrics listening", "addr", metricsCfg.Addr)
+	case metricsCfg.enabled():
+		mux.Handle("/metrics", metricsHandler(metricsCfg))
+	default:
+		slog.Info("metrics endpoint disabled (set METRICS_TOKEN or METRICS_ADDR)")
+	}
 
 	server := &http.Server{
 		Addr:         ":" + firstNonEmpty(os.Getenv("PORT"), "8080"),
-		Handler:      loggingMiddleware(corsMiddleware(mux)),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		Handler:      loggingMiddleware(tracingMiddleware(metricsMiddleware(corsMiddleware(mux)))),
+		ReadTimeout:  serverReadTimeout,
+		WriteTimeout: serverWriteTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
 
 	// Start the HTTP server.
 	go func() {
-		log.Printf("listening on %s", server.Addr)
+		slog.Info("listening", "addr", server.Addr)
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("server error: %v", err)
+			fatal("server error", "error", err)
 		}
 	}()
 
@@ -111,24 +262,57 @@ func main() {
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
+	// Fail readiness first so load balancers stop routing new traffic here.
+	app.shuttingDown.Store(true)
+	stopJobs()
+	if delay := shutdownDrainDelay(); delay > 0 {
+		slog.Info("draining before shutdown", "delay", delay.String())
+		time.Sleep(delay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("server shutdown error: %v", err)
+		slog.Error("server shutdown error", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			slog.Error("metrics server shutdown error", "error", err)
+		}
 	}
 	if err := db.Close(); err != nil {
-		log.Printf("database close error: %v", err)
+		slog.Error("database close error", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("tracing shutdown error", "error", err)
 	}
-	log.Println("shutdown complete")
+	slog.Info("shutdown complete")
 }
 
 func (a *App) healthHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
 	defer cancel()
 
+	if isTruthy(r.URL.Query().Get("verbose")) {
+		if !requireAdminToken(w, r) {
+			return
+		}
+		report, healthy := a.healthReport(ctx)
+		statusCode := http.StatusOK
+		if !healthy {
+			statusCode = http.StatusServiceUnavailable
+		}
+		writeJSON(w, statusCode, report)
+		return
+	}
+
 	if err := a.DB.PingContext(ctx); err != nil {
-		http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+		logFromContext(ctx).Warn("health database ping failed", "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status": "error",
+			"db":     false,
+		})
 		return
 	}
 
@@ -146,24 +330,24 @@ func (a *App) bannersHandler(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	queries := []string{
-		`SELECT id, section, title, COALESCE(to_jsonb(b)->>'image_url', to_jsonb(b)->>'image') AS image_url, priority
+		`SELECT id, section, title, COALESCE(to_jsonb(b)->>'image_url', to_jsonb(b)->>'image') AS image_url, priority, to_jsonb(b)->'translations' AS translations
 		FROM public.banners b
 		ORDER BY priority ASC, id ASC`,
-		`SELECT id, section, title, COALESCE(to_jsonb(b)->>'image_url', to_jsonb(b)->>'image') AS image_url, priority
+		`SELECT id, section, title, COALESCE(to_jsonb(b)->>'image_url', to_jsonb(b)->>'image') AS image_url, priority, to_jsonb(b)->'translations' AS translations
 		FROM banners b
 		ORDER BY priority ASC, id ASC`,
 	}
 
 	var rows *sql.Rows
 	var err error
-	for _, query := range queries {
-		rows, err = a.DB.QueryContext(ctx, query)
+	for idx, query := range queries {
+		rows, err = a.DB.QueryContext(withQueryName(ctx, fmt.Sprintf("banners.list#%d", idx+1)), query)
 		if err == nil {
 			break
 		}
 	}
 	if err != nil {
-		log.Printf("banners query failed: %v", err)
+		logFromContext(ctx).Error("banners query failed", "error", err)
 		http.Error(w, "failed to fetch banners", http.StatusInternalServerError)
 		return
 	}
@@ -177,13 +361,16 @@ func (a *App) bannersHandler(w http.ResponseWriter, r *http.Request) {
 		Priority int    `json:"priority"`
 	}
 
+	locales := requestLocales(r)
 	banners := make([]banner, 0, 8)
 	for rows.Next() {
 		var b banner
-		if err := rows.Scan(&b.ID, &b.Section, &b.Title, &b.ImageURL, &b.Priority); err != nil {
+		var translationsRaw []byte
+		if err := rows.Scan(&b.ID, &b.Section, &b.Title, &b.ImageURL, &b.Priority, &translationsRaw); err != nil {
 			http.Error(w, "failed to read banners", http.StatusInternalServerError)
 			return
 		}
+		b.Title = locales.text(parseContentTranslations(translationsRaw), "title", b.Title)
 		banners = append(banners, b)
 	}
 	if err := rows.Err(); err != nil {
@@ -191,6 +378,7 @@ func (a *App) bannersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	locales.setHeaders(w)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(banners); err != nil {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
@@ -209,19 +397,20 @@ func (a *App) contactHandler(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := a.DB.QueryContext(
 		ctx,
-		`SELECT id, phone_number, address, description, email, work_schedule
-		FROM public.contact
+		`SELECT id, phone_number, address, description, email, work_schedule, to_jsonb(c)->'translations' AS translations
+		FROM public.contact c
 		ORDER BY id ASC`,
 	)
 	if err != nil {
 		rows, err = a.DB.QueryContext(
 			ctx,
-			`SELECT id, phone_number, address, description, NULL::text AS email, NULL::text AS work_schedule
-			FROM public.contact_page
+			`SELECT id, phone_number, address, description, NULL::text AS email, NULL::text AS work_schedule, to_jsonb(c)->'translations' AS translations
+			FROM public.contact_page c
 			ORDER BY id ASC`,
 		)
 	}
 	if err != nil {
+		logFromContext(ctx).Error("failed to fetch contact", "error", err)
 		http.Error(w, "failed to fetch contact", http.StatusInternalServerError)
 		return
 	}
@@ -236,6 +425,7 @@ func (a *App) contactHandler(w http.ResponseWriter, r *http.Request) {
 		WorkSchedule *string `json:"work_schedule"`
 	}
 
+	locales := requestLocales(r)
 	contacts := make([]contact, 0, 4)
 	for rows.Next() {
 		var c contact
@@ -244,6 +434,7 @@ func (a *App) contactHandler(w http.ResponseWriter, r *http.Request) {
 		var description sql.NullString
 		var email sql.NullString
 		var workSchedule sql.NullString
+		var translationsRaw []byte
 
 		if err := rows.Scan(
 			&c.ID,
@@ -252,16 +443,18 @@ func (a *App) contactHandler(w http.ResponseWriter, r *http.Request) {
 			&description,
 			&email,
 			&workSchedule,
+			&translationsRaw,
 		); err != nil {
 			http.Error(w, "failed to read contact", http.StatusInternalServerError)
 			return
 		}
 
+		translations := parseContentTranslations(translationsRaw)
 		c.PhoneNumber = nullableString(phoneNumber)
-		c.Address = nullableString(address)
-		c.Description = nullableString(description)
+		c.Address = locales.textPtr(translations, "address", nullableString(address))
+		c.Description = locales.textPtr(translations, "description", nullableString(description))
 		c.Email = nullableString(email)
-		c.WorkSchedule = nullableString(workSchedule)
+		c.WorkSchedule = locales.textPtr(translations, "work_schedule", nullableString(workSchedule))
 
 		contacts = append(contacts, c)
 	}
@@ -270,6 +463,7 @@ func (a *App) contactHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	locales.setHeaders(w)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(contacts); err != nil {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
@@ -315,12 +509,14 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 		Sections []aboutSection `json:"sections"`
 	}
 
+	locales := requestLocales(r)
 	page := (*aboutPage)(nil)
 	metrics := make([]aboutMetric, 0, 4)
 	sections := make([]aboutSection, 0, 4)
 
 	hasAboutPage, err := hasTable(ctx, a.DB, "about_page")
 	if err != nil {
+		logFromContext(ctx).Error("failed to resolve about page table", "error", err)
 		http.Error(w, "failed to resolve about page table", http.StatusInternalServerError)
 		return
 	}
@@ -332,11 +528,12 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 		var missionDescription sql.NullString
 		var videoURL sql.NullString
 		var missionImageURL sql.NullString
+		var translationsRaw []byte
 
 		err := a.DB.QueryRowContext(
 			ctx,
-			`SELECT id, banner_title, banner_image_url, history_description, mission_description, video_url, mission_image_url
-			FROM public.about_page
+			`SELECT id, banner_title, banner_image_url, history_description, mission_description, video_url, mission_image_url, to_jsonb(p)->'translations' AS translations
+			FROM public.about_page p
 			ORDER BY id ASC
 			LIMIT 1`,
 		).Scan(
@@ -347,16 +544,18 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 			&missionDescription,
 			&videoURL,
 			&missionImageURL,
+			&translationsRaw,
 		)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "failed to fetch about page", http.StatusInternalServerError)
 			return
 		}
 		if err == nil {
-			pageItem.Title = nullableString(title)
+			translations := parseContentTranslations(translationsRaw)
+			pageItem.Title = locales.textPtr(translations, "banner_title", nullableString(title))
 			pageItem.BannerImageURL = nullableString(bannerImageURL)
-			pageItem.IntroDescription = nullableString(introDescription)
-			pageItem.MissionDescription = nullableString(missionDescription)
+			pageItem.IntroDescription = locales.textPtr(translations, "history_description", nullableString(introDescription))
+			pageItem.MissionDescription = locales.textPtr(translations, "mission_description", nullableString(missionDescription))
 			pageItem.VideoURL = nullableString(videoURL)
 			pageItem.MissionImageURL = nullableString(missionImageURL)
 			page = &pageItem
@@ -370,19 +569,21 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 
 	hasAboutMetrics, err := hasTable(ctx, a.DB, "about_metrics")
 	if err != nil {
+		logFromContext(ctx).Error("failed to resolve about metrics table", "error", err)
 		http.Error(w, "failed to resolve about metrics table", http.StatusInternalServerError)
 		return
 	}
 	if hasAboutMetrics {
 		rows, err := a.DB.QueryContext(
 			ctx,
-			`SELECT id, metric_key, metric_value, metric_label, position
-			FROM public.about_metrics
+			`SELECT id, metric_key, metric_value, metric_label, position, to_jsonb(m)->'translations' AS translations
+			FROM public.about_metrics m
 			WHERE about_id = $1
 			ORDER BY position ASC, id ASC`,
 			aboutID,
 		)
 		if err != nil {
+			logFromContext(ctx).Error("failed to fetch about metrics", "error", err)
 			http.Error(w, "failed to fetch about metrics", http.StatusInternalServerError)
 			return
 		}
@@ -390,10 +591,12 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 
 		for rows.Next() {
 			var item aboutMetric
-			if err := rows.Scan(&item.ID, &item.Key, &item.Value, &item.Label, &item.Position); err != nil {
+			var translationsRaw []byte
+			if err := rows.Scan(&item.ID, &item.Key, &item.Value, &item.Label, &item.Position, &translationsRaw); err != nil {
 				http.Error(w, "failed to read about metrics", http.StatusInternalServerError)
 				return
 			}
+			item.Label = locales.text(parseContentTranslations(translationsRaw), "metric_label", item.Label)
 			metrics = append(metrics, item)
 		}
 		if err := rows.Err(); err != nil {
@@ -404,19 +607,21 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 
 	hasAboutSections, err := hasTable(ctx, a.DB, "about_sections")
 	if err != nil {
+		logFromContext(ctx).Error("failed to resolve about sections table", "error", err)
 		http.Error(w, "failed to resolve about sections table", http.StatusInternalServerError)
 		return
 	}
 	if hasAboutSections {
 		rows, err := a.DB.QueryContext(
 			ctx,
-			`SELECT id, section_key, title, description, position
-			FROM public.about_sections
+			`SELECT id, section_key, title, description, position, to_jsonb(s)->'translations' AS translations
+			FROM public.about_sections s
 			WHERE about_id = $1
 			ORDER BY position ASC, id ASC`,
 			aboutID,
 		)
 		if err != nil {
+			logFromContext(ctx).Error("failed to fetch about sections", "error", err)
 			http.Error(w, "failed to fetch about sections", http.StatusInternalServerError)
 			return
 		}
@@ -424,10 +629,14 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 
 		for rows.Next() {
 			var item aboutSection
-			if err := rows.Scan(&item.ID, &item.Key, &item.Title, &item.Description, &item.Position); err != nil {
+			var translationsRaw []byte
+			if err := rows.Scan(&item.ID, &item.Key, &item.Title, &item.Description, &item.Position, &translationsRaw); err != nil {
 				http.Error(w, "failed to read about sections", http.StatusInternalServerError)
 				return
 			}
+			translations := parseContentTranslations(translationsRaw)
+			item.Title = locales.text(translations, "title", item.Title)
+			item.Description = locales.text(translations, "description", item.Description)
 			sections = append(sections, item)
 		}
 		if err := rows.Err(); err != nil {
@@ -436,6 +645,7 @@ func (a *App) aboutHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	locales.setHeaders(w)
 	writeJSON(w, http.StatusOK, aboutResponse{
 		Page:     page,
 		Metrics:  metrics,
@@ -457,6 +667,7 @@ func (a *App) partnersHandler(w http.ResponseWriter, r *http.Request) {
 		`SELECT id, logo_url FROM public.partners ORDER BY id ASC`,
 	)
 	if err != nil {
+		logFromContext(ctx).Error("failed to fetch partners", "error", err)
 		http.Error(w, "failed to fetch partners", http.StatusInternalServerError)
 		return
 	}
@@ -497,40 +708,60 @@ func (a *App) tuningHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
 	defer cancel()
 
+	var listQuery publicListQuery
+	var err error
+	if key, single := publicItemKey(r, "/tuning"); single {
+		tableName, err := resolveTuningTable(ctx, a.DB)
+		if err != nil || tableName == "" {
+			logFromContext(ctx).Error("failed to resolve tuning table", "error", err)
+			http.Error(w, "failed to fetch tuning", http.StatusInternalServerError)
+			return
+		}
+		id, ok := a.publicItemID(ctx, w, r, "/tuning", "public."+tableName, key, true)
+		if !ok {
+			return
+		}
+		listQuery = publicItemQuery(id)
+	} else if listQuery, err = parsePublicListQuery(r.URL.Query(), tuningListSpec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	queries := []string{
-		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, created_at, updated_at
+		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, created_at, updated_at
 		FROM public.tuning t
 		ORDER BY created_at DESC, id DESC`,
-		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, created_at, updated_at
+		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, created_at, updated_at
 		FROM public.tuning t
 		ORDER BY created_at DESC, id DESC`,
-		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, NULL::jsonb AS full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, created_at, updated_at
+		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, NULL::jsonb AS full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, created_at, updated_at
 		FROM public.tuning t
 		ORDER BY created_at DESC, id DESC`,
-		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, NULL::jsonb AS full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, created_at, updated_at
+		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, NULL::jsonb AS full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, created_at, updated_at
 		FROM public.tuning t
 		ORDER BY created_at DESC, id DESC`,
-		`SELECT row_number() OVER () AS id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, NULL::jsonb AS full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, NOW() AS created_at, NOW() AS updated_at
+		`SELECT row_number() OVER () AS id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, NULL::jsonb AS full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, NOW() AS created_at, NOW() AS updated_at
 		FROM public.tuning t`,
-		`SELECT row_number() OVER () AS id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, NULL::jsonb AS full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, NOW() AS created_at, NOW() AS updated_at
+		`SELECT row_number() OVER () AS id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, NULL::jsonb AS full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, NOW() AS created_at, NOW() AS updated_at
 		FROM public.tuning t`,
-		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, created_at, updated_at
+		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, NULL::text AS title, card_image_url, full_image_url, description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, created_at, updated_at
 		FROM public.tunning t
 		ORDER BY created_at DESC, id DESC`,
-		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, created_at, updated_at
+		`SELECT id, to_jsonb(t)->>'brand' AS brand, to_jsonb(t)->>'model' AS model, title, card_image_url, full_image_url, title AS description, card_description, full_description, video_image_url, video_link, to_jsonb(t)->>'price' AS price, to_jsonb(t)->>'slug' AS slug, to_jsonb(t)->'translations' AS translations, created_at, updated_at
 		FROM public.tunning t
 		ORDER BY created_at DESC, id DESC`,
 	}
 
 	var rows *sql.Rows
-	var err error
-	for _, query := range queries {
-		rows, err = a.DB.QueryContext(ctx, query)
+	for idx, query := range queries {
+		query, args := listQuery.wrap(query)
+		rows, err = a.DB.QueryContext(withQueryName(ctx, fmt.Sprintf("tuning.list#%d", idx+1)), query, args...)
 		if err == nil {
 			break
 		}
 	}
 	if err != nil {
+		logFromContext(ctx).Error("failed to fetch tuning", "error", err)
 		http.Error(w, "failed to fetch tuning", http.StatusInternalServerError)
 		return
 	}
@@ -538,6 +769,7 @@ func (a *App) tuningHandler(w http.ResponseWriter, r *http.Request) {
 
 	type tuningItem struct {
 		ID              int       `json:"id"`
+		Slug            *string   `json:"slug"`
 		Brand           *string   `json:"brand"`
 		Model           *string   `json:"model"`
 		Title           *string   `json:"title"`
@@ -551,17 +783,26 @@ func (a *App) tuningHandler(w http.ResponseWriter, r *http.Request) {
 		VideoLink       *string   `json:"video_link"`
 		CreatedAt       time.Time `json:"created_at"`
 		UpdatedAt       time.Time `json:"updated_at"`
+
+		CardImageVariants imageVariantURLs   `json:"card_image_variants,omitempty"`
+		FullImageVariants []imageVariantURLs `json:"full_image_variants,omitempty"`
 	}
 
+	locales := requestLocales(r)
+	page := newPublicListPage(listQuery)
 	items := make([]tuningItem, 0, 8)
 	for rows.Next() {
 		var item tuningItem
+		var listCursor string
+		var listTotal int64
 		var brand sql.NullString
 		var model sql.NullString
 		var title sql.NullString
 		var cardImageURL sql.NullString
 		var fullImageURLRaw []byte
 		var price sql.NullString
+		var slug sql.NullString
+		var translationsRaw []byte
 		var description sql.NullString
 		var cardDescription sql.NullString
 		var fullDescription sql.NullString
@@ -581,22 +822,29 @@ func (a *App) tuningHandler(w http.ResponseWriter, r *http.Request) {
 			&videoImageURL,
 			&videoLink,
 			&price,
+			&slug,
+			&translationsRaw,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&listCursor,
+			&listTotal,
 		); err != nil {
 			http.Error(w, "failed to read tuning", http.StatusInternalServerError)
 			return
 		}
+		page.add(int64(item.ID), listCursor, listTotal)
 
 		item.Brand = nullableString(brand)
 		item.Model = nullableString(model)
 		item.Title = nullableString(title)
 		item.CardImageURL = nullableString(cardImageURL)
 		item.FullImageURL = parseStringArray(fullImageURLRaw)
-		item.Price = nullableString(price)
-		item.Description = nullableString(description)
-		item.CardDescription = nullableString(cardDescription)
-		item.FullDescription = nullableString(fullDescription)
+		translations := parseContentTranslations(translationsRaw)
+		item.Price = locales.textPtr(translations, "price", nullableString(price))
+		item.Slug = nullableString(slug)
+		item.Description = locales.textPtr(translations, "description", nullableString(description))
+		item.CardDescription = locales.textPtr(translations, "card_description", nullableString(cardDescription))
+		item.FullDescription = locales.textPtr(translations, "full_description", nullableString(fullDescription))
 		item.VideoImageURL = nullableString(videoImageURL)
 		item.VideoLink = nullableString(videoLink)
 
@@ -606,12 +854,35 @@ func (a *App) tuningHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to read tuning", http.StatusInternalServerError)
 		return
 	}
+	items = items[:page.size()]
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(items); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
+	var links []string
+	for _, item := range items {
+		if item.CardImageURL != nil {
+			links = append(links, *item.CardImageURL)
+		}
+		links = append(links, item.FullImageURL...)
 	}
+	if variants := a.imageVariantsByURL(ctx, links); len(variants) > 0 {
+		for i := range items {
+			if items[i].CardImageURL != nil {
+				items[i].CardImageVariants = variants[*items[i].CardImageURL]
+			}
+			var full []imageVariantURLs
+			for idx, link := range items[i].FullImageURL {
+				if found, ok := variants[link]; ok {
+					if full == nil {
+						full = make([]imageVariantURLs, len(items[i].FullImageURL))
+					}
+					full[idx] = found
+				}
+			}
+			items[i].FullImageVariants = full
+		}
+	}
+
+	locales.setHeaders(w)
+	writePublicList(w, page, items)
 }
 
 func (a *App) portfolioItemsHandler(w http.ResponseWriter, r *http.Request) {
@@ -623,13 +894,24 @@ func (a *App) portfolioItemsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
 	defer cancel()
 
-	rows, err := a.DB.QueryContext(
-		ctx,
-		`SELECT id, brand, title, image_url, description, youtube_link, created_at
-		FROM public.portfolio_items
-		ORDER BY created_at DESC, id DESC`,
-	)
+	var listQuery publicListQuery
+	var err error
+	if key, single := publicItemKey(r, "/portfolio_items"); single {
+		id, ok := a.publicItemID(ctx, w, r, "/portfolio_items", "public.portfolio_items", key, false)
+		if !ok {
+			return
+		}
+		listQuery = publicItemQuery(id)
+	} else if listQuery, err = parsePublicListQuery(r.URL.Query(), portfolioListSpec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, args := listQuery.wrap(`SELECT id, to_jsonb(p)->>'slug' AS slug, brand, title, image_url, description, youtube_link, created_at, to_jsonb(p)->'translations' AS translations
+		FROM public.portfolio_items p`)
+	rows, err := a.DB.QueryContext(ctx, query, args...)
 	if err != nil {
+		logFromContext(ctx).Error("failed to fetch portfolio items", "error", err)
 		http.Error(w, "failed to fetch portfolio items", http.StatusInternalServerError)
 		return
 	}
@@ -637,36 +919,53 @@ func (a *App) portfolioItemsHandler(w http.ResponseWriter, r *http.Request) {
 
 	type portfolioItem struct {
 		ID          int       `json:"id"`
+		Slug        *string   `json:"slug"`
 		Brand       *string   `json:"brand"`
 		Title       string    `json:"title"`
 		ImageURL    string    `json:"image_url"`
 		Description *string   `json:"description"`
 		YoutubeLink *string   `json:"youtube_link"`
 		CreatedAt   time.Time `json:"created_at"`
+
+		ImageVariants imageVariantURLs `json:"image_variants,omitempty"`
 	}
 
+	locales := requestLocales(r)
+	page := newPublicListPage(listQuery)
 	items := make([]portfolioItem, 0, 8)
 	for rows.Next() {
 		var item portfolioItem
+		var listCursor string
+		var listTotal int64
+		var slug sql.NullString
 		var brand sql.NullString
 		var description sql.NullString
 		var youtubeLink sql.NullString
+		var translationsRaw []byte
 
 		if err := rows.Scan(
 			&item.ID,
+			&slug,
 			&brand,
 			&item.Title,
 			&item.ImageURL,
 			&description,
 			&youtubeLink,
 			&item.CreatedAt,
+			&translationsRaw,
+			&listCursor,
+			&listTotal,
 		); err != nil {
 			http.Error(w, "failed to read portfolio items", http.StatusInternalServerError)
 			return
 		}
+		page.add(int64(item.ID), listCursor, listTotal)
 
+		translations := parseContentTranslations(translationsRaw)
+		item.Slug = nullableString(slug)
 		item.Brand = nullableString(brand)
-		item.Description = nullableString(description)
+		item.Title = locales.text(translations, "title", item.Title)
+		item.Description = locales.textPtr(translations, "description", nullableString(description))
 		item.YoutubeLink = nullableString(youtubeLink)
 
 		items = append(items, item)
@@ -675,12 +974,20 @@ func (a *App) portfolioItemsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to read portfolio items", http.StatusInternalServerError)
 		return
 	}
+	items = items[:page.size()]
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(items); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
+	links := make([]string, 0, len(items))
+	for _, item := range items {
+		links = append(links, item.ImageURL)
+	}
+	if variants := a.imageVariantsByURL(ctx, links); len(variants) > 0 {
+		for i := range items {
+			items[i].ImageVariants = variants[items[i].ImageURL]
+		}
 	}
+
+	locales.setHeaders(w)
+	writePublicList(w, page, items)
 }
 
 func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
@@ -694,6 +1001,7 @@ func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	tableName, err := resolveWorkPostTable(ctx, a.DB)
 	if err != nil {
+		logFromContext(ctx).Error("failed to resolve work posts table", "error", err)
 		http.Error(w, "failed to resolve work posts table", http.StatusInternalServerError)
 		return
 	}
@@ -704,32 +1012,44 @@ func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	hasGalleryImages, err := hasColumn(ctx, a.DB, tableName, "gallery_images")
 	if err != nil {
+		logFromContext(ctx).Error("failed to resolve work posts columns", "error", err)
 		http.Error(w, "failed to resolve work posts columns", http.StatusInternalServerError)
 		return
 	}
 
-	query := `SELECT id, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, NULL::jsonb AS gallery_images, created_at, updated_at
-		FROM public.blog_posts
+	var listQuery publicListQuery
+	if key, single := publicItemKey(r, "/work_post"); single {
+		id, ok := a.publicItemID(ctx, w, r, "/work_post", "public."+tableName, key, false)
+		if !ok {
+			return
+		}
+		listQuery = publicItemQuery(id)
+	} else if listQuery, err = parsePublicListQuery(r.URL.Query(), workPostListSpec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := `SELECT id, to_jsonb(t)->>'slug' AS slug, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, NULL::jsonb AS gallery_images, created_at, updated_at, to_jsonb(t)->'translations' AS translations
+		FROM public.blog_posts t
 		ORDER BY created_at DESC, id DESC`
 	if tableName == "work_post" && hasGalleryImages {
-		query = `SELECT id, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, gallery_images, created_at, updated_at
-		FROM public.work_post
+		query = `SELECT id, to_jsonb(t)->>'slug' AS slug, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, gallery_images, created_at, updated_at, to_jsonb(t)->'translations' AS translations
+		FROM public.work_post t
 		ORDER BY created_at DESC, id DESC`
 	} else if tableName == "work_post" {
-		query = `SELECT id, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, NULL::jsonb AS gallery_images, created_at, updated_at
-		FROM public.work_post
+		query = `SELECT id, to_jsonb(t)->>'slug' AS slug, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, NULL::jsonb AS gallery_images, created_at, updated_at, to_jsonb(t)->'translations' AS translations
+		FROM public.work_post t
 		ORDER BY created_at DESC, id DESC`
 	} else if tableName == "blog_posts" && hasGalleryImages {
-		query = `SELECT id, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, gallery_images, created_at, updated_at
-		FROM public.blog_posts
+		query = `SELECT id, to_jsonb(t)->>'slug' AS slug, title_model, card_image_url, full_image_url, card_description, work_list, full_description, video_image_url, video_link, gallery_images, created_at, updated_at, to_jsonb(t)->'translations' AS translations
+		FROM public.blog_posts t
 		ORDER BY created_at DESC, id DESC`
 	}
 
-	rows, err := a.DB.QueryContext(
-		ctx,
-		query,
-	)
+	query, args := listQuery.wrap(query)
+	rows, err := a.DB.QueryContext(ctx, query, args...)
 	if err != nil {
+		logFromContext(ctx).Error("failed to fetch work posts", "error", err)
 		http.Error(w, "failed to fetch work posts", http.StatusInternalServerError)
 		return
 	}
@@ -737,6 +1057,7 @@ func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	type workPost struct {
 		ID              int      `json:"id"`
+		Slug            string   `json:"slug"`
 		Title           string   `json:"title"`
 		Description     string   `json:"description"`
 		FullDescription string   `json:"fullDescription"`
@@ -746,9 +1067,14 @@ func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
 		GalleryImages   []string `json:"galleryImages"`
 	}
 
+	locales := requestLocales(r)
+	page := newPublicListPage(listQuery)
 	posts := make([]workPost, 0, 8)
 	for rows.Next() {
 		var post workPost
+		var listCursor string
+		var listTotal int64
+		var slug sql.NullString
 		var titleModel string
 		var cardImageURL sql.NullString
 		var fullImageURL sql.NullString
@@ -760,9 +1086,11 @@ func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
 		var galleryImagesRaw []byte
 		var createdAt time.Time
 		var updatedAt time.Time
+		var translationsRaw []byte
 
 		if err := rows.Scan(
 			&post.ID,
+			&slug,
 			&titleModel,
 			&cardImageURL,
 			&fullImageURL,
@@ -774,21 +1102,27 @@ func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
 			&galleryImagesRaw,
 			&createdAt,
 			&updatedAt,
+			&translationsRaw,
+			&listCursor,
+			&listTotal,
 		); err != nil {
 			http.Error(w, "failed to read work posts", http.StatusInternalServerError)
 			return
 		}
+		page.add(int64(post.ID), listCursor, listTotal)
 
 		cardURL := nullStringValue(cardImageURL)
 		fullURL := nullStringValue(fullImageURL)
 		videoImage := nullStringValue(videoImageURL)
 
-		post.Title = titleModel
-		post.Description = nullStringValue(cardDescription)
-		post.FullDescription = nullStringValue(fullDescription)
+		post.Slug = nullStringValue(slug)
+		translations := parseContentTranslations(translationsRaw)
+		post.Title = locales.text(translations, "title_model", titleModel)
+		post.Description = locales.text(translations, "card_description", nullStringValue(cardDescription))
+		post.FullDescription = locales.text(translations, "full_description", nullStringValue(fullDescription))
 		post.ImageURL = firstNonEmpty(cardURL, fullURL, videoImage)
 		post.VideoURL = nullStringValue(videoLink)
-		post.PerformedWorks = parsePerformedWorks(workList)
+		post.PerformedWorks = locales.list(translations, "work_list", parsePerformedWorks(workList))
 		post.GalleryImages = parseStringArray(galleryImagesRaw)
 		if len(post.GalleryImages) == 0 {
 			post.GalleryImages = uniqueNonEmpty(cardURL, fullURL, videoImage)
@@ -801,11 +1135,8 @@ func (a *App) workPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
-	}
+	locales.setHeaders(w)
+	writePublicList(w, page, posts[:page.size()])
 }
 
 func (a *App) serviceOfferingsHandler(w http.ResponseWriter, r *http.Request) {
@@ -817,27 +1148,41 @@ func (a *App) serviceOfferingsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
 	defer cancel()
 
+	var listQuery publicListQuery
+	var err error
+	if key, single := publicItemKey(r, "/service_offerings"); single {
+		id, ok := a.publicItemID(ctx, w, r, "/service_offerings", "public.service_offerings", key, false)
+		if !ok {
+			return
+		}
+		listQuery = publicItemQuery(id)
+	} else if listQuery, err = parsePublicListQuery(r.URL.Query(), serviceOfferingsListSpec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	queries := []string{
-		`SELECT id, service_type, title, detailed_description, gallery_images, price_text, position, created_at, updated_at
-		FROM public.service_offerings
+		`SELECT id, to_jsonb(s)->>'slug' AS slug, service_type, title, detailed_description, gallery_images, price_text, position, created_at, updated_at, to_jsonb(s)->'translations' AS translations
+		FROM public.service_offerings s
 		ORDER BY position ASC, id ASC`,
-		`SELECT id, service_type, title, detailed_description, gallery_images, price_text, position, NOW() AS created_at, NOW() AS updated_at
-		FROM public.service_offerings
+		`SELECT id, to_jsonb(s)->>'slug' AS slug, service_type, title, detailed_description, gallery_images, price_text, position, NOW() AS created_at, NOW() AS updated_at, to_jsonb(s)->'translations' AS translations
+		FROM public.service_offerings s
 		ORDER BY position ASC, id ASC`,
-		`SELECT id, service_type, title, detailed_description, NULL::jsonb AS gallery_images, price_text, position, NOW() AS created_at, NOW() AS updated_at
-		FROM public.service_offerings
+		`SELECT id, to_jsonb(s)->>'slug' AS slug, service_type, title, detailed_description, NULL::jsonb AS gallery_images, price_text, position, NOW() AS created_at, NOW() AS updated_at, to_jsonb(s)->'translations' AS translations
+		FROM public.service_offerings s
 		ORDER BY position ASC, id ASC`,
 	}
 
 	var rows *sql.Rows
-	var err error
-	for _, query := range queries {
-		rows, err = a.DB.QueryContext(ctx, query)
+	for idx, query := range queries {
+		query, args := listQuery.wrap(query)
+		rows, err = a.DB.QueryContext(withQueryName(ctx, fmt.Sprintf("service_offerings.list#%d", idx+1)), query, args...)
 		if err == nil {
 			break
 		}
 	}
 	if err != nil {
+		logFromContext(ctx).Error("failed to fetch service offerings", "error", err)
 		http.Error(w, "failed to fetch service offerings", http.StatusInternalServerError)
 		return
 	}
@@ -845,6 +1190,7 @@ func (a *App) serviceOfferingsHandler(w http.ResponseWriter, r *http.Request) {
 
 	type serviceOffering struct {
 		ID                  int       `json:"id"`
+		Slug                *string   `json:"slug"`
 		ServiceType         *string   `json:"service_type"`
 		Title               *string   `json:"title"`
 		DetailedDescription *string   `json:"detailed_description"`
@@ -855,17 +1201,24 @@ func (a *App) serviceOfferingsHandler(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt           time.Time `json:"updated_at"`
 	}
 
+	locales := requestLocales(r)
+	page := newPublicListPage(listQuery)
 	items := make([]serviceOffering, 0, 8)
 	for rows.Next() {
 		var item serviceOffering
+		var listCursor string
+		var listTotal int64
+		var slug sql.NullString
 		var serviceType sql.NullString
 		var title sql.NullString
 		var detailedDescription sql.NullString
 		var galleryImagesRaw []byte
 		var priceText sql.NullString
+		var translationsRaw []byte
 
 		if err := rows.Scan(
 			&item.ID,
+			&slug,
 			&serviceType,
 			&title,
 			&detailedDescription,
@@ -874,16 +1227,22 @@ func (a *App) serviceOfferingsHandler(w http.ResponseWriter, r *http.Request) {
 			&item.Position,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&translationsRaw,
+			&listCursor,
+			&listTotal,
 		); err != nil {
 			http.Error(w, "failed to read service offerings", http.StatusInternalServerError)
 			return
 		}
+		page.add(int64(item.ID), listCursor, listTotal)
 
+		item.Slug = nullableString(slug)
 		item.ServiceType = nullableString(serviceType)
-		item.Title = nullableString(title)
-		item.DetailedDescription = nullableString(detailedDescription)
+		translations := parseContentTranslations(translationsRaw)
+		item.Title = locales.textPtr(translations, "title", nullableString(title))
+		item.DetailedDescription = locales.textPtr(translations, "detailed_description", nullableString(detailedDescription))
 		item.GalleryImages = parseStringArray(galleryImagesRaw)
-		item.PriceText = nullableString(priceText)
+		item.PriceText = locales.textPtr(translations, "price_text", nullableString(priceText))
 
 		items = append(items, item)
 	}
@@ -892,11 +1251,8 @@ func (a *App) serviceOfferingsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(items); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
-	}
+	locales.setHeaders(w)
+	writePublicList(w, page, items[:page.size()])
 }
 
 func (a *App) privacySectionsHandler(w http.ResponseWriter, r *http.Request) {
@@ -910,11 +1266,12 @@ func (a *App) privacySectionsHandler(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := a.DB.QueryContext(
 		ctx,
-		`SELECT id, title, description, position
-		FROM public.privacy_sections
+		`SELECT id, title, description, position, to_jsonb(p)->'translations' AS translations
+		FROM public.privacy_sections p
 		ORDER BY position ASC, id ASC`,
 	)
 	if err != nil {
+		logFromContext(ctx).Error("failed to fetch privacy sections", "error", err)
 		http.Error(w, "failed to fetch privacy sections", http.StatusInternalServerError)
 		return
 	}
@@ -927,13 +1284,18 @@ func (a *App) privacySectionsHandler(w http.ResponseWriter, r *http.Request) {
 		Position    int    `json:"position"`
 	}
 
+	locales := requestLocales(r)
 	items := make([]privacySection, 0, 8)
 	for rows.Next() {
 		var item privacySection
-		if err := rows.Scan(&item.ID, &item.Title, &item.Description, &item.Position); err != nil {
+		var translationsRaw []byte
+		if err := rows.Scan(&item.ID, &item.Title, &item.Description, &item.Position, &translationsRaw); err != nil {
 			http.Error(w, "failed to read privacy sections", http.StatusInternalServerError)
 			return
 		}
+		translations := parseContentTranslations(translationsRaw)
+		item.Title = locales.text(translations, "title", item.Title)
+		item.Description = locales.text(translations, "description", item.Description)
 		items = append(items, item)
 	}
 	if err := rows.Err(); err != nil {
@@ -941,6 +1303,7 @@ func (a *App) privacySectionsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	locales.setHeaders(w)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(items); err != nil {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
@@ -962,46 +1325,103 @@ func (a *App) consultationsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type consultationCreateRequest struct {
+	FirstName         string `json:"first_name"`
+	LastName          string `json:"last_name"`
+	Phone             string `json:"phone"`
+	ServiceType       string `json:"service_type"`
+	CarModel          string `json:"car_model"`
+	PreferredCallTime string `json:"preferred_call_time"`
+	Comments          string `json:"comments"`
+	// Website is a honeypot: the field is hidden from humans, bots fill it.
+	Website string `json:"website"`
+	// FormStartedAt is the unix time (s or ms) when the form was rendered.
+	FormStartedAt int64  `json:"form_started_at"`
+	CaptchaToken  string `json:"captcha_token"`
+}
+
+// createConsultationHandler accepts a JSON body, or multipart/form-data with
+// the same fields plus optional photos under "attachments".
 func (a *App) createConsultationHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
 	defer cancel()
 
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB safety limit for JSON payload
-	defer r.Body.Close()
-
-	type consultationCreateRequest struct {
-		FirstName         string `json:"first_name"`
-		LastName          string `json:"last_name"`
-		Phone             string `json:"phone"`
-		ServiceType       string `json:"service_type"`
-		CarModel          string `json:"car_model"`
-		PreferredCallTime string `json:"preferred_call_time"`
-		Comments          string `json:"comments"`
+	guard := a.ConsultationGuard
+	trustProxy := guard != nil && guard.cfg.TrustProxyHeaders
+	remoteIP := clientIP(r, trustProxy)
+	if guard != nil {
+		if allowed, retryAfter := guard.allow(ctx, "ip", remoteIP, guard.cfg.IPLimit); !allowed {
+			recordConsultationSubmission("", "rate_limited")
+			writeRateLimited(w, retryAfter)
+			return
+		}
 	}
 
-	var req consultationCreateRequest
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{
-			"status":  "error",
-			"message": "???????????????????????? JSON",
-			"errors": map[string]string{
-				"body": "?????????????????? ???????????? ??????????????",
-			},
-		})
-		return
-	}
+	var (
+		req   consultationCreateRequest
+		files []*multipart.FileHeader
+		body  *hashingBody
+	)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// The multipart variant carries the same fields plus photos.
+		r.Body = http.MaxBytesReader(w, r.Body, a.ConsultationAttachments.maxBodyBytes())
+		defer r.Body.Close()
+		body = hashRequestBody(r)
+		if err := r.ParseMultipartForm(multipartMemoryBytes); err != nil {
+			recordConsultationSubmission("", "invalid")
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{
+					"status":  "error",
+					"message": "Слишком большой запрос",
+					"errors": map[string]string{
+						"attachments": fmt.Sprintf("Не больше %d файлов до %d МБ каждый", a.ConsultationAttachments.MaxFiles, a.ConsultationAttachments.MaxSizeMB),
+					},
+				})
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"status":  "error",
+				"message": "Некорректная форма",
+				"errors": map[string]string{
+					"body": "Не удалось прочитать multipart/form-data",
+				},
+			})
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		req = consultationRequestFromForm(r.MultipartForm)
+		files = r.MultipartForm.File[consultationAttachmentsField]
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB safety limit for JSON payload
+		defer r.Body.Close()
+		body = hashRequestBody(r)
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			recordConsultationSubmission("", "invalid")
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"status":  "error",
+				"message": "???????????????????????? JSON",
+				"errors": map[string]string{
+					"body": "?????????????????? ???????????? ??????????????",
+				},
+			})
+			return
+		}
 
-	if err := decoder.Decode(&struct{}{}); err != io.EOF {
-		writeJSON(w, http.StatusBadRequest, map[string]any{
-			"status":  "error",
-			"message": "???????????????????????? JSON",
-			"errors": map[string]string{
-				"body": "?????????????????? ???????? JSON-????????????",
-			},
-		})
-		return
+		if err := decoder.Decode(&struct{}{}); err != io.EOF {
+			recordConsultationSubmission("", "invalid")
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"status":  "error",
+				"message": "???????????????????????? JSON",
+				"errors": map[string]string{
+					"body": "?????????????????? ???????? JSON-????????????",
+				},
+			})
+			return
+		}
 	}
 
 	errorsMap := validateConsultationRequest(
@@ -1013,7 +1433,12 @@ func (a *App) createConsultationHandler(w http.ResponseWriter, r *http.Request)
 		req.PreferredCallTime,
 		req.Comments,
 	)
+	attachments, attachmentsError := a.checkConsultationAttachments(ctx, files)
+	if attachmentsError != "" {
+		errorsMap["attachments"] = attachmentsError
+	}
 	if len(errorsMap) > 0 {
+		recordConsultationSubmission(req.ServiceType, "invalid")
 		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
 			"status":  "error",
 			"message": "???????????? ??????????????????",
@@ -1030,13 +1455,52 @@ func (a *App) createConsultationHandler(w http.ResponseWriter, r *http.Request)
 	preferredCallTime := optionalStringDBValue(req.PreferredCallTime)
 	comments := optionalStringDBValue(req.Comments)
 
+	if err := a.Captcha.check(ctx, r, body, req.CaptchaToken, remoteIP); err != nil {
+		recordConsultationSubmission(serviceType, "captcha_failed")
+		writeCaptchaError(w, err)
+		return
+	}
+
+	if guard != nil {
+		if allowed, retryAfter := guard.allow(ctx, "phone", phone, guard.cfg.PhoneLimit); !allowed {
+			recordConsultationSubmission(serviceType, "rate_limited")
+			writeRateLimited(w, retryAfter)
+			return
+		}
+	}
+
+	status := "new"
+	spamReason := guard.spamReason(req.Website, req.FormStartedAt, time.Now())
+	if spamReason != "" {
+		status = "spam"
+		logFromContext(ctx).Warn("consultation marked as spam", "reason", spamReason, "client_ip", remoteIP)
+	} else {
+		duplicateID, duplicateCreatedAt, found, err := guard.findDuplicateConsultation(ctx, a.DB, phone, serviceType)
+		if err != nil {
+			logFromContext(ctx).Error("consultation duplicate check failed", "error", err)
+		}
+		if found {
+			recordConsultationSubmission(serviceType, "duplicate")
+			writeJSON(w, http.StatusOK, map[string]any{
+				"status":  "success",
+				"message": "Заявка уже получена, мы скоро свяжемся с вами",
+				"data": map[string]any{
+					"id":         duplicateID,
+					"created_at": duplicateCreatedAt.UTC().Format(time.RFC3339),
+					"duplicate":  true,
+				},
+			})
+			return
+		}
+	}
+
 	var id int64
 	var createdAt time.Time
 	err := a.DB.QueryRowContext(
 		ctx,
 		`INSERT INTO public.consultations
 		(first_name, last_name, phone, service_type, car_model, preferred_call_time, comments, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, 'new')
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at`,
 		firstName,
 		lastName,
@@ -1045,8 +1509,11 @@ func (a *App) createConsultationHandler(w http.ResponseWriter, r *http.Request)
 		carModel,
 		preferredCallTime,
 		comments,
+		status,
 	).Scan(&id, &createdAt)
 	if err != nil {
+		logFromContext(ctx).Error("consultation insert failed", "error", err)
+		recordConsultationSubmission(serviceType, "error")
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "???? ?????????????? ?????????????????? ????????????",
@@ -1054,7 +1521,34 @@ func (a *App) createConsultationHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	go notifyAdminAboutConsultation(consultationNotification{
+	// Spam is kept for review but answered like a normal submission so bots
+	// get no signal, and managers are not notified.
+	if status == "spam" {
+		recordConsultationSubmission(serviceType, "spam")
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"status":  "success",
+			"message": "???????????? ?????????????? ??????????????",
+			"data": map[string]any{
+				"id":         id,
+				"created_at": createdAt.UTC().Format(time.RFC3339),
+			},
+		})
+		return
+	}
+
+	var (
+		stored []consultationAttachment
+		links  []consultationAttachmentLink
+	)
+	if len(attachments) > 0 {
+		// Photos get their own deadline; the form timeout is sized for text.
+		stored = a.storeConsultationAttachments(r.Context(), id, attachments)
+		links = a.consultationAttachmentLinks(ctx, stored)
+	}
+
+	recordConsultationSubmission(serviceType, "accepted")
+	pendingNotifications.Add(1)
+	go notifyAdminAboutConsultation(context.WithoutCancel(ctx), consultationNotification{
 		ID:                id,
 		FirstName:         firstName,
 		LastName:          lastName,
@@ -1065,15 +1559,20 @@ func (a *App) createConsultationHandler(w http.ResponseWriter, r *http.Request)
 		Comments:          optionalStringValue(req.Comments),
 		Status:            "new",
 		CreatedAt:         createdAt,
+		Attachments:       links,
 	})
 
+	data := map[string]any{
+		"id":         id,
+		"created_at": createdAt.UTC().Format(time.RFC3339),
+	}
+	if len(attachments) > 0 {
+		data["attachments"] = len(stored)
+	}
 	writeJSON(w, http.StatusCreated, map[string]any{
 		"status":  "success",
 		"message": "???????????? ?????????????? ??????????????",
-		"data": map[string]any{
-			"id":         id,
-			"created_at": createdAt.UTC().Format(time.RFC3339),
-		},
+		"data":    data,
 	})
 }
 
@@ -1088,11 +1587,15 @@ func (a *App) listConsultationsHandler(w http.ResponseWriter, r *http.Request) {
 	if statusFilter != "" {
 		query += ` WHERE status = $1`
 		args = append(args, statusFilter)
+	} else {
+		// Spam is only listed when requested explicitly (?status=spam).
+		query += ` WHERE status <> 'spam'`
 	}
 	query += ` ORDER BY created_at DESC, id DESC`
 
 	rows, err := a.DB.QueryContext(ctx, query, args...)
 	if err != nil {
+		logFromContext(ctx).Error("consultations query failed", "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "???? ?????????????? ???????????????? ????????????",
@@ -1166,7 +1669,7 @@ func (a *App) rootHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write([]byte(`{"service":"carbon_go","status":"running","routes":["/","/healthz","/contact","/about","/banners","/partners","/tuning","/service_offerings","/privacy_sections","/api/consultations","/portfolio_items","/work_post","/admin/auth/*","/admin/*","/admin/storage/*"]}`))
+	_, _ = w.Write([]byte(`{"service":"carbon_go","status":"running","routes":["/","/healthz","/livez","/readyz","/contact","/about","/banners","/partners","/tuning","/service_offerings","/privacy_sections","/api/consultations","/portfolio_items","/work_post","/search","/api/home","/api/pages/{name}","/api/v1/*","/admin/auth/*","/admin/*","/admin/storage/*"]}`))
 }
 
 type adminAuthLoginRequest struct {
@@ -1241,7 +1744,7 @@ func (a *App) adminAuthLoginHandler(w http.ResponseWriter, r *http.Request) {
 
 	token, expiresAt, err := issueAdminAccessToken(username, cfg.SigningSecret, cfg.SessionTTL, time.Now())
 	if err != nil {
-		log.Printf("admin auth token issue failed: %v", err)
+		logFromContext(r.Context()).Error("admin auth token issue failed", "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to issue access token",
@@ -1303,6 +1806,8 @@ type tableCRUDConfig struct {
 	RequiredOnCreate map[string]struct{}
 	JSONColumns      map[string]struct{}
 	TouchUpdatedAt   bool
+	Slug             *slugConfig
+	Translatable     map[string]struct{}
 }
 
 func columnSet(values ...string) map[string]struct{} {
@@ -1313,49 +1818,61 @@ func columnSet(values ...string) map[string]struct{} {
 	return set
 }
 
-func (a *App) registerAdminCRUDRoutes(mux *http.ServeMux) {
-	configs := []tableCRUDConfig{
+func adminCRUDConfigs() []tableCRUDConfig {
+	return []tableCRUDConfig{
 		{
 			Path:             "/admin/banners",
 			Table:            "public.banners",
 			OrderBy:          "t.priority ASC, t.id ASC",
-			MutableColumns:   columnSet("section", "title", "image_url", "priority"),
+			MutableColumns:   columnSet("section", "title", "image_url", "priority", "translations"),
 			RequiredOnCreate: columnSet("section", "title", "image_url"),
+			JSONColumns:      columnSet("translations"),
+			Translatable:     columnSet("title"),
 		},
 		{
 			Path:             "/admin/contact",
 			Table:            "public.contact",
 			OrderBy:          "t.id ASC",
-			MutableColumns:   columnSet("phone_number", "address", "description", "email", "work_schedule"),
+			MutableColumns:   columnSet("phone_number", "address", "description", "email", "work_schedule", "translations"),
 			RequiredOnCreate: columnSet(),
+			JSONColumns:      columnSet("translations"),
+			Translatable:     columnSet("address", "description", "work_schedule"),
 		},
 		{
 			Path:             "/admin/contact_page",
 			Table:            "public.contact_page",
 			OrderBy:          "t.id ASC",
-			MutableColumns:   columnSet("id", "phone_number", "address", "description", "image_url"),
+			MutableColumns:   columnSet("id", "phone_number", "address", "description", "image_url", "translations"),
 			RequiredOnCreate: columnSet(),
+			JSONColumns:      columnSet("translations"),
+			Translatable:     columnSet("address", "description"),
 		},
 		{
 			Path:             "/admin/about_page",
 			Table:            "public.about_page",
 			OrderBy:          "t.id ASC",
-			MutableColumns:   columnSet("id", "banner_image_url", "banner_title", "history_description", "video_url", "mission_description", "mission_image_url"),
+			MutableColumns:   columnSet("id", "banner_image_url", "banner_title", "history_description", "video_url", "mission_description", "mission_image_url", "translations"),
 			RequiredOnCreate: columnSet(),
+			JSONColumns:      columnSet("translations"),
+			Translatable:     columnSet("banner_title", "history_description", "mission_description"),
 		},
 		{
 			Path:             "/admin/about_metrics",
 			Table:            "public.about_metrics",
 			OrderBy:          "t.position ASC, t.id ASC",
-			MutableColumns:   columnSet("about_id", "metric_key", "metric_value", "metric_label", "position"),
+			MutableColumns:   columnSet("about_id", "metric_key", "metric_value", "metric_label", "position", "translations"),
 			RequiredOnCreate: columnSet("metric_key", "metric_value", "metric_label"),
+			JSONColumns:      columnSet("translations"),
+			Translatable:     columnSet("metric_label"),
 		},
 		{
 			Path:             "/admin/about_sections",
 			Table:            "public.about_sections",
 			OrderBy:          "t.position ASC, t.id ASC",
-			MutableColumns:   columnSet("about_id", "section_key", "title", "description", "position"),
+			MutableColumns:   columnSet("about_id", "section_key", "title", "description", "position", "translations"),
 			RequiredOnCreate: columnSet("section_key", "title", "description"),
+			JSONColumns:      columnSet("translations"),
+			Translatable:     columnSet("title", "description"),
 		},
 		{
 			Path:             "/admin/partners",
@@ -1368,51 +1885,64 @@ func (a *App) registerAdminCRUDRoutes(mux *http.ServeMux) {
 			Path:             "/admin/tuning",
 			Table:            "public.tuning",
 			OrderBy:          "t.created_at DESC, t.id DESC",
-			MutableColumns:   columnSet("brand", "model", "card_image_url", "full_image_url", "price", "description", "card_description", "full_description", "video_image_url", "video_link"),
+			MutableColumns:   columnSet("brand", "model", "card_image_url", "full_image_url", "price", "description", "card_description", "full_description", "video_image_url", "video_link", "slug", "translations"),
 			RequiredOnCreate: columnSet(),
-			JSONColumns:      columnSet("full_image_url"),
+			JSONColumns:      columnSet("full_image_url", "translations"),
 			TouchUpdatedAt:   true,
+			Slug:             &slugConfig{Source: []string{"brand", "model"}, Fallback: "tuning"},
+			Translatable:     columnSet("price", "description", "card_description", "full_description"),
 		},
 		{
 			Path:             "/admin/service_offerings",
 			Table:            "public.service_offerings",
 			OrderBy:          "t.position ASC, t.id ASC",
-			MutableColumns:   columnSet("service_type", "title", "detailed_description", "gallery_images", "price_text", "position"),
+			MutableColumns:   columnSet("service_type", "title", "detailed_description", "gallery_images", "price_text", "position", "slug", "translations"),
 			RequiredOnCreate: columnSet("service_type", "title"),
-			JSONColumns:      columnSet("gallery_images"),
+			JSONColumns:      columnSet("gallery_images", "translations"),
 			TouchUpdatedAt:   true,
+			Slug:             &slugConfig{Source: []string{"title"}, Fallback: "service"},
+			Translatable:     columnSet("title", "detailed_description", "price_text"),
 		},
 		{
 			Path:             "/admin/privacy_sections",
 			Table:            "public.privacy_sections",
 			OrderBy:          "t.position ASC, t.id ASC",
-			MutableColumns:   columnSet("title", "description", "position"),
+			MutableColumns:   columnSet("title", "description", "position", "translations"),
 			RequiredOnCreate: columnSet("title", "description"),
+			JSONColumns:      columnSet("translations"),
+			Translatable:     columnSet("title", "description"),
 		},
 		{
 			Path:             "/admin/portfolio_items",
 			Table:            "public.portfolio_items",
 			OrderBy:          "t.created_at DESC, t.id DESC",
-			MutableColumns:   columnSet("brand", "title", "image_url", "description", "youtube_link"),
+			MutableColumns:   columnSet("brand", "title", "image_url", "description", "youtube_link", "slug", "translations"),
 			RequiredOnCreate: columnSet("title", "image_url"),
+			JSONColumns:      columnSet("translations"),
+			Slug:             &slugConfig{Source: []string{"title"}, Fallback: "portfolio"},
+			Translatable:     columnSet("title", "description"),
 		},
 		{
 			Path:             "/admin/work_post",
 			Table:            "public.work_post",
 			OrderBy:          "t.created_at DESC, t.id DESC",
-			MutableColumns:   columnSet("title_model", "card_image_url", "full_image_url", "card_description", "work_list", "gallery_images", "full_description", "video_image_url", "video_link"),
+			MutableColumns:   columnSet("title_model", "card_image_url", "full_image_url", "card_description", "work_list", "gallery_images", "full_description", "video_image_url", "video_link", "slug", "translations"),
 			RequiredOnCreate: columnSet("title_model"),
-			JSONColumns:      columnSet("work_list", "gallery_images"),
+			JSONColumns:      columnSet("work_list", "gallery_images", "translations"),
 			TouchUpdatedAt:   true,
+			Slug:             &slugConfig{Source: []string{"title_model"}, Fallback: "work"},
+			Translatable:     columnSet("title_model", "card_description", "full_description", "work_list"),
 		},
 		{
 			Path:             "/admin/blog_posts",
 			Table:            "public.blog_posts",
 			OrderBy:          "t.created_at DESC, t.id DESC",
-			MutableColumns:   columnSet("title_model", "card_image_url", "full_image_url", "card_description", "work_list", "gallery_images", "full_description", "video_image_url", "video_link"),
+			MutableColumns:   columnSet("title_model", "card_image_url", "full_image_url", "card_description", "work_list", "gallery_images", "full_description", "video_image_url", "video_link", "slug", "translations"),
 			RequiredOnCreate: columnSet("title_model"),
-			JSONColumns:      columnSet("work_list", "gallery_images"),
+			JSONColumns:      columnSet("work_list", "gallery_images", "translations"),
 			TouchUpdatedAt:   true,
+			Slug:             &slugConfig{Source: []string{"title_model"}, Fallback: "work"},
+			Translatable:     columnSet("title_model", "card_description", "full_description", "work_list"),
 		},
 		{
 			Path:             "/admin/consultations",
@@ -1422,8 +1952,10 @@ func (a *App) registerAdminCRUDRoutes(mux *http.ServeMux) {
 			RequiredOnCreate: columnSet("first_name", "last_name", "phone", "service_type"),
 		},
 	}
+}
 
-	for _, cfg := range configs {
+func (a *App) registerAdminCRUDRoutes(mux *http.ServeMux) {
+	for _, cfg := range adminCRUDConfigs() {
 		config := cfg
 		handler := a.makeAdminTableCRUDHandler(config)
 		mux.HandleFunc(config.Path, handler)
@@ -1494,9 +2026,9 @@ func (a *App) adminFetchMany(w http.ResponseWriter, r *http.Request, cfg tableCR
 	var raw []byte
 	if err := a.queryAdminList(ctx, cfg.Table, orderBy, &raw); err != nil {
 		if orderBy != "t.id ASC" {
-			log.Printf("admin list %s failed with order '%s': %v; retry with id ASC", cfg.Table, orderBy, err)
+			logFromContext(ctx).Warn("admin list failed, retrying with id ASC", "table", cfg.Table, "order_by", orderBy, "error", err)
 			if retryErr := a.queryAdminList(ctx, cfg.Table, "t.id ASC", &raw); retryErr != nil {
-				log.Printf("admin list %s retry failed: %v", cfg.Table, retryErr)
+				logFromContext(ctx).Error("admin list retry failed", "table", cfg.Table, "error", retryErr)
 				writeJSON(w, http.StatusInternalServerError, map[string]any{
 					"status":  "error",
 					"message": "failed to fetch data",
@@ -1504,7 +2036,7 @@ func (a *App) adminFetchMany(w http.ResponseWriter, r *http.Request, cfg tableCR
 				return
 			}
 		} else {
-			log.Printf("admin list %s failed: %v", cfg.Table, err)
+			logFromContext(ctx).Error("admin list failed", "table", cfg.Table, "error", err)
 			writeJSON(w, http.StatusInternalServerError, map[string]any{
 				"status":  "error",
 				"message": "failed to fetch data",
@@ -1517,7 +2049,7 @@ func (a *App) adminFetchMany(w http.ResponseWriter, r *http.Request, cfg tableCR
 	if len(raw) == 0 {
 		data = []any{}
 	} else if err := json.Unmarshal(raw, &data); err != nil {
-		log.Printf("admin list %s decode failed: %v", cfg.Table, err)
+		logFromContext(ctx).Error("admin list decode failed", "table", cfg.Table, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to parse data",
@@ -1533,7 +2065,7 @@ func (a *App) adminFetchMany(w http.ResponseWriter, r *http.Request, cfg tableCR
 
 func (a *App) queryAdminList(ctx context.Context, tableName, orderBy string, out *[]byte) error {
 	query := fmt.Sprintf(
-		`SELECT COALESCE(json_agg(to_jsonb(t) ORDER BY %s), '[]'::json) FROM %s t`,
+		`SELECT COALESCE(json_agg(to_jsonb(t) - `+adminHiddenColumns+` ORDER BY %s), '[]'::json) FROM %s t`,
 		orderBy,
 		quoteTableName(tableName),
 	)
@@ -1544,7 +2076,7 @@ func (a *App) adminFetchOne(w http.ResponseWriter, r *http.Request, cfg tableCRU
 	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
 	defer cancel()
 
-	query := fmt.Sprintf(`SELECT to_jsonb(t) FROM %s t WHERE t.id = $1`, quoteTableName(cfg.Table))
+	query := fmt.Sprintf(`SELECT to_jsonb(t) - `+adminHiddenColumns+` FROM %s t WHERE t.id = $1`, quoteTableName(cfg.Table))
 
 	var raw []byte
 	if err := a.DB.QueryRowContext(ctx, query, id).Scan(&raw); err != nil {
@@ -1555,7 +2087,7 @@ func (a *App) adminFetchOne(w http.ResponseWriter, r *http.Request, cfg tableCRU
 			})
 			return
 		}
-		log.Printf("admin fetch one %s failed: %v", cfg.Table, err)
+		logFromContext(ctx).Error("admin fetch one failed", "table", cfg.Table, "id", id, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to fetch data",
@@ -1565,7 +2097,7 @@ func (a *App) adminFetchOne(w http.ResponseWriter, r *http.Request, cfg tableCRU
 
 	var data any
 	if err := json.Unmarshal(raw, &data); err != nil {
-		log.Printf("admin fetch one %s decode failed: %v", cfg.Table, err)
+		logFromContext(ctx).Error("admin fetch one decode failed", "table", cfg.Table, "id", id, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to parse data",
@@ -1599,9 +2131,17 @@ func (a *App) adminCreateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 		})
 		return
 	}
+	if validationErrors := translationErrors(payload, cfg); len(validationErrors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"message": "validation error",
+			"errors":  validationErrors,
+		})
+		return
+	}
 	if cfg.Path == "/admin/tuning" {
 		if err := a.alignTuningCreatePayloadToSchema(ctx, payload); err != nil {
-			log.Printf("admin create %s schema alignment failed: %v", cfg.Table, err)
+			logFromContext(ctx).Error("admin create schema alignment failed", "table", cfg.Table, "error", err)
 			writeJSON(w, http.StatusInternalServerError, map[string]any{
 				"status":  "error",
 				"message": "failed to prepare payload",
@@ -1609,6 +2149,9 @@ func (a *App) adminCreateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 			return
 		}
 	}
+	if cfg.Slug != nil && !a.assignSlug(ctx, w, cfg, payload, 0) {
+		return
+	}
 
 	keys := sortedMapKeys(payload)
 	quotedTable := quoteTableName(cfg.Table)
@@ -1617,7 +2160,7 @@ func (a *App) adminCreateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 	args := make([]any, 0, len(keys))
 	if len(keys) == 0 {
 		query = fmt.Sprintf(
-			`WITH ins AS (INSERT INTO %s DEFAULT VALUES RETURNING *) SELECT to_jsonb(ins) FROM ins`,
+			`WITH ins AS (INSERT INTO %s DEFAULT VALUES RETURNING *) SELECT to_jsonb(ins) - `+adminHiddenColumns+` FROM ins`,
 			quotedTable,
 		)
 	} else {
@@ -1639,7 +2182,7 @@ func (a *App) adminCreateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 		}
 
 		query = fmt.Sprintf(
-			`WITH ins AS (INSERT INTO %s (%s) VALUES (%s) RETURNING *) SELECT to_jsonb(ins) FROM ins`,
+			`WITH ins AS (INSERT INTO %s (%s) VALUES (%s) RETURNING *) SELECT to_jsonb(ins) - `+adminHiddenColumns+` FROM ins`,
 			quotedTable,
 			strings.Join(columns, ", "),
 			strings.Join(placeholders, ", "),
@@ -1648,7 +2191,11 @@ func (a *App) adminCreateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 
 	var raw []byte
 	if err := a.DB.QueryRowContext(ctx, query, args...).Scan(&raw); err != nil {
-		log.Printf("admin create %s failed: %v", cfg.Table, err)
+		if cfg.Slug != nil && isUniqueViolation(err) {
+			writeSlugConflict(w, fmt.Sprint(payload["slug"]))
+			return
+		}
+		logFromContext(ctx).Error("admin create failed", "table", cfg.Table, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to create record",
@@ -1656,9 +2203,11 @@ func (a *App) adminCreateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 		return
 	}
 
+	a.Responses.invalidate(cfg.Table)
+
 	var data any
 	if err := json.Unmarshal(raw, &data); err != nil {
-		log.Printf("admin create %s decode failed: %v", cfg.Table, err)
+		logFromContext(ctx).Error("admin create decode failed", "table", cfg.Table, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to parse created record",
@@ -1689,6 +2238,17 @@ func (a *App) adminUpdateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 		})
 		return
 	}
+	if validationErrors := translationErrors(payload, cfg); len(validationErrors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"message": "validation error",
+			"errors":  validationErrors,
+		})
+		return
+	}
+	if cfg.Slug != nil && !a.assignSlug(ctx, w, cfg, payload, id) {
+		return
+	}
 
 	keys := sortedMapKeys(payload)
 	if len(keys) == 0 && !cfg.TouchUpdatedAt {
@@ -1711,6 +2271,11 @@ func (a *App) adminUpdateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 			return
 		}
 		args = append(args, value)
+		if key == "translations" {
+			// Merged per locale: {"en": {...}} replaces only en, {"en": null} drops it.
+			setClauses = append(setClauses, fmt.Sprintf("translations = jsonb_strip_nulls(COALESCE(translations, '{}'::jsonb) || $%d::jsonb)", idx+1))
+			continue
+		}
 		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", quoteIdentifier(key), idx+1))
 	}
 	if cfg.TouchUpdatedAt {
@@ -1718,12 +2283,16 @@ func (a *App) adminUpdateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 	}
 
 	query := fmt.Sprintf(
-		`WITH upd AS (UPDATE %s SET %s WHERE id = $%d RETURNING *) SELECT to_jsonb(upd) FROM upd`,
+		`WITH upd AS (UPDATE %s SET %s WHERE id = $%d RETURNING *) SELECT to_jsonb(upd) - `+adminHiddenColumns+` FROM upd`,
 		quoteTableName(cfg.Table),
 		strings.Join(setClauses, ", "),
 		len(args)+1,
 	)
 	args = append(args, id)
+	if _, renamed := payload["slug"]; renamed && cfg.Slug != nil {
+		query = slugRenameQuery(cfg.Table, strings.Join(setClauses, ", "), len(args), len(args)+1)
+		args = append(args, cfg.Table)
+	}
 
 	var raw []byte
 	if err := a.DB.QueryRowContext(ctx, query, args...).Scan(&raw); err != nil {
@@ -1734,7 +2303,11 @@ func (a *App) adminUpdateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 			})
 			return
 		}
-		log.Printf("admin update %s failed: %v", cfg.Table, err)
+		if cfg.Slug != nil && isUniqueViolation(err) {
+			writeSlugConflict(w, fmt.Sprint(payload["slug"]))
+			return
+		}
+		logFromContext(ctx).Error("admin update failed", "table", cfg.Table, "id", id, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to update record",
@@ -1742,9 +2315,11 @@ func (a *App) adminUpdateOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 		return
 	}
 
+	a.Responses.invalidate(cfg.Table)
+
 	var data any
 	if err := json.Unmarshal(raw, &data); err != nil {
-		log.Printf("admin update %s decode failed: %v", cfg.Table, err)
+		logFromContext(ctx).Error("admin update decode failed", "table", cfg.Table, "id", id, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to parse updated record",
@@ -1763,7 +2338,7 @@ func (a *App) adminDeleteOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 	defer cancel()
 
 	query := fmt.Sprintf(
-		`WITH del AS (DELETE FROM %s WHERE id = $1 RETURNING *) SELECT to_jsonb(del) FROM del`,
+		`WITH del AS (DELETE FROM %s WHERE id = $1 RETURNING *) SELECT to_jsonb(del) - `+adminHiddenColumns+` FROM del`,
 		quoteTableName(cfg.Table),
 	)
 
@@ -1776,17 +2351,24 @@ func (a *App) adminDeleteOne(w http.ResponseWriter, r *http.Request, cfg tableCR
 			})
 			return
 		}
-		log.Printf("admin delete %s failed: %v", cfg.Table, err)
+		logFromContext(ctx).Error("admin delete failed", "table", cfg.Table, "id", id, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to delete record",
 		})
 		return
 	}
+	if cfg.Slug != nil {
+		if _, err := a.DB.ExecContext(ctx, `DELETE FROM public.slug_redirects WHERE table_name = $1 AND record_id = $2`, cfg.Table, id); err != nil {
+			logFromContext(ctx).Warn("slug redirect cleanup failed", "table", cfg.Table, "id", id, "error", err)
+		}
+	}
+
+	a.Responses.invalidate(cfg.Table)
 
 	var data any
 	if err := json.Unmarshal(raw, &data); err != nil {
-		log.Printf("admin delete %s decode failed: %v", cfg.Table, err)
+		logFromContext(ctx).Error("admin delete decode failed", "table", cfg.Table, "id", id, "error", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"status":  "error",
 			"message": "failed to parse deleted record",
@@ -1964,12 +2546,6 @@ func quoteTableName(value string) string {
 	return strings.Join(quoted, ".")
 }
 
-type supabaseStorageConfig struct {
-	BaseURL       string
-	ServiceRole   string
-	DefaultBucket string
-}
-
 func (a *App) adminStorageUploadHandler(w http.ResponseWriter, r *http.Request) {
 	if !requireAdminToken(w, r) {
 		return
@@ -1982,39 +2558,53 @@ func (a *App) adminStorageUploadHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	cfg, err := loadSupabaseStorageConfig()
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"status":  "error",
-			"message": err.Error(),
-		})
+	storage, ok := a.storageOrError(w)
+	if !ok {
 		return
 	}
 
-	maxBytes := int64(storageUploadMaxMB) << 20
+	// A batch may carry many files; each is still held to its bucket limit.
+	maxBytes := max(a.UploadPolicies.maxUploadBytes(), int64(storageBatchUploadMaxMB)<<20)
+	bodyTimeout := uploadReadTimeout(maxBytes)
+	extendRequestDeadlines(w, r, bodyTimeout, bodyTimeout+serverWriteTimeout)
 	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
-	if err := r.ParseMultipartForm(maxBytes); err != nil {
+	if err := r.ParseMultipartForm(multipartMemoryBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{
+				"status":         "error",
+				"message":        fmt.Sprintf("upload exceeds %d MB", maxBytes>>20),
+				"max_size_bytes": maxBytes,
+			})
+			return
+		}
 		writeJSON(w, http.StatusBadRequest, map[string]any{
 			"status":  "error",
 			"message": "invalid multipart form",
 		})
 		return
 	}
-	if r.MultipartForm != nil {
-		defer r.MultipartForm.RemoveAll()
-	}
+	defer r.MultipartForm.RemoveAll()
 
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
+	fileHeaders := r.MultipartForm.File["file"]
+	if len(fileHeaders) == 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
 			"status":  "error",
 			"message": "file is required (form-data key: file)",
 		})
 		return
 	}
-	defer file.Close()
+	if len(fileHeaders) > maxBatchUploadFiles {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": fmt.Sprintf("at most %d files per request", maxBatchUploadFiles),
+		})
+		return
+	}
+	// Each file gets up to storageUploadTimeout to be processed and stored.
+	extendRequestDeadlines(w, r, serverReadTimeout, time.Duration(len(fileHeaders))*storageUploadTimeout+serverWriteTimeout)
 
-	bucketValue := firstNonEmpty(r.FormValue("bucket"), cfg.DefaultBucket)
+	bucketValue := firstNonEmpty(r.FormValue("bucket"), defaultStorageBucket())
 	bucket, err := cleanStorageBucket(bucketValue)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
@@ -2024,11 +2614,6 @@ func (a *App) adminStorageUploadHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	filename := sanitizeFilename(firstNonEmpty(r.FormValue("filename"), fileHeader.Filename))
-	if filename == "" {
-		filename = fmt.Sprintf("upload_%d.bin", time.Now().UnixNano())
-	}
-
 	folder, err := cleanOptionalStoragePath(r.FormValue("folder"))
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
@@ -2038,74 +2623,305 @@ func (a *App) adminStorageUploadHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	objectPath := filename
-	if folder != "" {
-		objectPath = folder + "/" + filename
-	}
-
-	contentType := strings.TrimSpace(fileHeader.Header.Get("Content-Type"))
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
 	upsertValue := strings.TrimSpace(strings.ToLower(r.FormValue("upsert")))
 	upsert := upsertValue == "" || upsertValue == "1" || upsertValue == "true" || upsertValue == "yes"
 
-	uploadURL := buildSupabaseObjectURL(cfg.BaseURL, bucket, objectPath)
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, file)
+	tags, err := parseMediaTags(r.FormValue("tags"))
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{
+		writeJSON(w, http.StatusBadRequest, map[string]any{
 			"status":  "error",
-			"message": "failed to build upload request",
+			"message": err.Error(),
 		})
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+cfg.ServiceRole)
-	req.Header.Set("apikey", cfg.ServiceRole)
-	req.Header.Set("x-upsert", strconv.FormatBool(upsert))
-	req.Header.Set("Content-Type", contentType)
-	if fileHeader.Size > 0 {
-		req.ContentLength = fileHeader.Size
+
+	processValue := strings.TrimSpace(r.FormValue("process"))
+	opts := storeUploadOptions{
+		Policy:     a.UploadPolicies.forBucket(bucket),
+		Bucket:     bucket,
+		Folder:     folder,
+		Upsert:     upsert,
+		Process:    processValue == "" || isTruthy(processValue),
+		AltText:    strings.TrimSpace(r.FormValue("alt_text")),
+		Tags:       tags,
+		UploadedBy: adminUsernameFromContext(r.Context()),
+	}
+	if dedupValue := strings.TrimSpace(r.FormValue("dedup")); dedupValue != "" {
+		opts.SkipDedup = !isTruthy(dedupValue)
 	}
 
-	resp, err := (&http.Client{Timeout: 70 * time.Second}).Do(req)
-	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{
-			"status":  "error",
-			"message": "storage upload request failed",
+	// A single file keeps the original response shape.
+	if len(fileHeaders) == 1 {
+		opts.Filename = uploadFilename(firstNonEmpty(r.FormValue("filename"), fileHeaders[0].Filename))
+		data, err := a.storeUploadPart(r.Context(), storage, fileHeaders[0], opts)
+		if err != nil {
+			writeUploadError(w, r, err)
+			return
+		}
+		statusCode := http.StatusCreated
+		if data["deduplicated"] == true {
+			statusCode = http.StatusOK
+		}
+		writeJSON(w, statusCode, map[string]any{
+			"status": "success",
+			"data":   data,
 		})
 		return
 	}
-	defer resp.Body.Close()
 
-	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		writeJSON(w, http.StatusBadGateway, map[string]any{
-			"status":  "error",
-			"message": "storage upload failed",
-			"details": strings.TrimSpace(string(respBody)),
-		})
-		return
+	// Batches report every file; one bad file does not fail the others.
+	results := make([]map[string]any, 0, len(fileHeaders))
+	failed := 0
+	for i, fileHeader := range fileHeaders {
+		opts.Filename = uploadFilename(fileHeader.Filename)
+		result := map[string]any{"index": i, "filename": fileHeader.Filename}
+
+		data, err := a.storeUploadPart(r.Context(), storage, fileHeader, opts)
+		if err != nil {
+			failed++
+			statusCode, payload := uploadErrorResponse(r, err)
+			result["status"] = "error"
+			result["status_code"] = statusCode
+			result["error"] = payload
+		} else {
+			result["status"] = "success"
+			result["data"] = data
+		}
+		results = append(results, result)
 	}
 
-	writeJSON(w, http.StatusCreated, map[string]any{
-		"status": "success",
-		"data": map[string]any{
-			"bucket":        bucket,
-			"path":          objectPath,
-			"mime_type":     contentType,
-			"size":          fileHeader.Size,
-			"upsert":        upsert,
-			"storage_url":   buildSupabaseObjectURL(cfg.BaseURL, bucket, objectPath),
-			"public_url":    buildSupabasePublicURL(cfg.BaseURL, bucket, objectPath),
-			"storage_reply": strings.TrimSpace(string(respBody)),
+	statusCode, status := http.StatusCreated, "success"
+	switch {
+	case failed == len(results):
+		statusCode, status = http.StatusUnprocessableEntity, "error"
+	case failed > 0:
+		statusCode, status = http.StatusMultiStatus, "partial"
+	}
+	writeJSON(w, statusCode, map[string]any{
+		"status": status,
+		"data":   results,
+		"meta": map[string]any{
+			"total":    len(results),
+			"uploaded": len(results) - failed,
+			"failed":   failed,
 		},
 	})
 }
 
+func uploadFilename(raw string) string {
+	if filename := sanitizeFilename(raw); filename != "" {
+		return filename
+	}
+	return fmt.Sprintf("upload_%d", time.Now().UnixNano())
+}
+
+// storeUploadPart stores one multipart file with its own timeout.
+func (a *App) storeUploadPart(ctx context.Context, storage Storage, fileHeader *multipart.FileHeader, opts storeUploadOptions) (map[string]any, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open upload: %w", err)
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, storageUploadTimeout)
+	defer cancel()
+	return a.storeUpload(ctx, storage, file, fileHeader.Size, opts)
+}
+
+var errStorageUploadFailed = errors.New("storage upload failed")
+
+type storeUploadOptions struct {
+	Policy     uploadPolicy
+	Bucket     string
+	Folder     string
+	Filename   string
+	Upsert     bool
+	Process    bool
+	AltText    string
+	Tags       []string
+	UploadedBy string
+	// SkipDedup stores the file even when the policy would return an
+	// identical existing object.
+	SkipDedup bool
+}
+
+// storeUpload runs an upload through the bucket policy and image processing,
+// writes it (plus variants) to storage and records it in the media library.
+// Client-facing failures are *uploadRejection; storage failures wrap
+// errStorageUploadFailed.
+func (a *App) storeUpload(ctx context.Context, storage Storage, file io.ReadSeeker, size int64, opts storeUploadOptions) (map[string]any, error) {
+	objectPath := joinStoragePath(opts.Folder, opts.Filename)
+
+	// The type comes from the file bytes, never from the client header.
+	check, err := opts.Policy.check(file, size, opts.Bucket, objectPath, opts.Filename)
+	if err != nil {
+		var rejection *uploadRejection
+		if errors.As(err, &rejection) {
+			logFromContext(ctx).Warn("upload rejected by policy", "bucket", opts.Bucket, "path", objectPath, "reason", err.Error())
+		}
+		return nil, err
+	}
+	objectPath = joinStoragePath(opts.Folder, check.Filename)
+	contentType := check.ContentType
+
+	// Images are rotated upright, stripped of EXIF and get resized variants
+	// unless the caller opts out.
+	var (
+		body       io.ReadSeeker = file
+		storedSize               = size
+		processed  *processedImage
+	)
+	if opts.Process {
+		processed, err = processUploadedImage(file, a.Images)
+		if err != nil {
+			statusCode := http.StatusUnprocessableEntity
+			if errors.Is(err, errImageTooLarge) {
+				statusCode = http.StatusRequestEntityTooLarge
+			}
+			logFromContext(ctx).Warn("image processing failed", "path", objectPath, "error", err)
+			return nil, &uploadRejection{
+				StatusCode: statusCode,
+				Message:    "failed to process image",
+				Details:    map[string]any{"details": err.Error()},
+			}
+		}
+	}
+	if processed != nil && processed.Original != nil {
+		body = bytes.NewReader(processed.Original)
+		storedSize = int64(len(processed.Original))
+		contentType = processed.OriginalType
+	}
+
+	checksum, width, height, err := inspectUpload(body)
+	if err != nil {
+		return nil, fmt.Errorf("inspect upload: %w", err)
+	}
+
+	// The same bytes already in the bucket are returned instead of stored
+	// again. A failed lookup only costs the duplicate.
+	if opts.Policy.dedupEnabled() && !opts.SkipDedup {
+		existing, err := a.findMediaByChecksum(ctx, storage, opts.Bucket, checksum)
+		switch {
+		case err == nil:
+			logFromContext(ctx).Info("upload deduplicated", "bucket", opts.Bucket, "path", existing.Path, "filename", opts.Filename)
+			return a.uploadResponse(ctx, storage, existing, existing.ID, size, "", opts.Upsert, true), nil
+		case !errors.Is(err, sql.ErrNoRows):
+			logFromContext(ctx).Warn("dedup lookup failed", "bucket", opts.Bucket, "error", err)
+		}
+	}
+
+	putOpts := storagePutOptions{
+		ContentType: contentType,
+		Size:        storedSize,
+		Upsert:      opts.Upsert,
+	}
+	// Content-addressed names never change meaning, so the object can be
+	// cached forever and an existing one is already the right file.
+	var object storageObject
+	if opts.Policy.Naming == uploadNamingHash {
+		objectPath = joinStoragePath(opts.Folder, checksum+strings.ToLower(path.Ext(check.Filename)))
+		putOpts.CacheControl = "public, max-age=31536000, immutable"
+		putOpts.Upsert = false
+		object, err = storage.Stat(ctx, opts.Bucket, objectPath)
+	}
+	if opts.Policy.Naming != uploadNamingHash || err != nil {
+		object, err = storage.Put(ctx, opts.Bucket, objectPath, body, putOpts)
+	}
+	if err != nil {
+		logFromContext(ctx).Error("storage upload failed", "backend", storage.Backend(), "bucket", opts.Bucket, "path", objectPath, "error", err)
+		return nil, fmt.Errorf("%w: %w", errStorageUploadFailed, err)
+	}
+
+	item := mediaItem{
+		Backend:    storage.Backend(),
+		Bucket:     opts.Bucket,
+		Path:       objectPath,
+		PublicURL:  storage.PublicURL(opts.Bucket, objectPath),
+		SizeBytes:  storedSize,
+		MimeType:   contentType,
+		Width:      width,
+		Height:     height,
+		Checksum:   checksum,
+		AltText:    opts.AltText,
+		Tags:       opts.Tags,
+		UploadedBy: opts.UploadedBy,
+		Variants:   storeImageVariants(ctx, storage, opts.Bucket, objectPath, processed),
+	}
+
+	// The file is already stored; a failed media row is logged, not fatal.
+	var mediaID any
+	id, err := a.recordMedia(ctx, item)
+	if err != nil {
+		logFromContext(ctx).Error("media record failed", "bucket", opts.Bucket, "path", objectPath, "error", err)
+	} else {
+		mediaID = id
+	}
+	return a.uploadResponse(ctx, storage, item, mediaID, size, object.ETag, opts.Upsert, false), nil
+}
+
+// uploadResponse renders a stored (or deduplicated) upload.
+func (a *App) uploadResponse(ctx context.Context, storage Storage, item mediaItem, mediaID any, originalSize int64, etag string, upsert, deduplicated bool) map[string]any {
+	response := map[string]any{
+		"bucket":          item.Bucket,
+		"path":            item.Path,
+		"mime_type":       item.MimeType,
+		"size":            item.SizeBytes,
+		"original_size":   originalSize,
+		"width":           item.Width,
+		"height":          item.Height,
+		"checksum_sha256": item.Checksum,
+		"upsert":          upsert,
+		"etag":            etag,
+		"backend":         item.Backend,
+		"media_id":        mediaID,
+		"variants":        item.Variants,
+		"deduplicated":    deduplicated,
+	}
+	for key, value := range a.objectLinks(ctx, storage, item.Bucket, item.Path) {
+		response[key] = value
+	}
+	return response
+}
+
+// writeUploadError renders a storeUpload failure.
+func writeUploadError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode, payload := uploadErrorResponse(r, err)
+	writeJSON(w, statusCode, payload)
+}
+
+func uploadErrorResponse(r *http.Request, err error) (int, map[string]any) {
+	var rejection *uploadRejection
+	if errors.As(err, &rejection) {
+		payload := map[string]any{
+			"status":  "error",
+			"message": rejection.Message,
+		}
+		for key, value := range rejection.Details {
+			payload[key] = value
+		}
+		return rejection.StatusCode, payload
+	}
+	if errors.Is(err, errStorageUploadFailed) {
+		return storageErrorStatus(err), map[string]any{
+			"status":  "error",
+			"message": "storage upload failed",
+			"details": err.Error(),
+		}
+	}
+	logFromContext(r.Context()).Error("upload inspection failed", "error", err)
+	return http.StatusInternalServerError, map[string]any{
+		"status":  "error",
+		"message": "failed to read upload",
+	}
+}
+
+func adminUsernameFromContext(ctx context.Context) string {
+	if info := requestInfoFromContext(ctx); info != nil {
+		return info.AdminUsername
+	}
+	return ""
+}
+
 func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 	if !requireAdminToken(w, r) {
 		return
@@ -2118,12 +2934,8 @@ func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg, err := loadSupabaseStorageConfig()
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"status":  "error",
-			"message": err.Error(),
-		})
+	storage, ok := a.storageOrError(w)
+	if !ok {
 		return
 	}
 
@@ -2180,7 +2992,7 @@ func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if allBuckets {
-		buckets, err := a.supabaseListBuckets(ctx, cfg)
+		buckets, err := storage.ListBuckets(ctx)
 		if err != nil {
 			writeJSON(w, http.StatusBadGateway, map[string]any{
 				"status":  "error",
@@ -2192,7 +3004,7 @@ func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 
 		items := make(map[string]any, len(buckets))
 		for _, bucket := range buckets {
-			data, err := a.supabaseListBucketFiles(ctx, cfg, bucket, opts)
+			objects, err := storage.List(ctx, bucket, opts)
 			if err != nil {
 				items[bucket] = map[string]any{
 					"status":  "error",
@@ -2200,7 +3012,7 @@ func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 				}
 				continue
 			}
-			items[bucket] = data
+			items[bucket] = a.storageListEntries(storage, objects)
 		}
 
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -2208,6 +3020,7 @@ func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 			"data":   items,
 			"meta": map[string]any{
 				"all":            true,
+				"backend":        storage.Backend(),
 				"bucket_count":   len(buckets),
 				"per_bucket_max": opts.Limit,
 				"prefix":         opts.Prefix,
@@ -2216,7 +3029,7 @@ func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bucketValue := firstNonEmpty(r.URL.Query().Get("bucket"), cfg.DefaultBucket)
+	bucketValue := firstNonEmpty(r.URL.Query().Get("bucket"), defaultStorageBucket())
 	bucket, err := cleanStorageBucket(bucketValue)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
@@ -2226,123 +3039,31 @@ func (a *App) adminStorageListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := a.supabaseListBucketFiles(ctx, cfg, bucket, opts)
+	objects, err := storage.List(ctx, bucket, opts)
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{
-			"status":  "error",
-			"message": "storage list failed",
-			"details": err.Error(),
-		})
+		writeStorageError(w, "storage list failed", err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"status": "success",
-		"data":   data,
+		"data":   a.storageListEntries(storage, objects),
 		"meta": map[string]any{
-			"bucket": bucket,
-			"prefix": prefix,
-			"limit":  limit,
-			"offset": offset,
+			"backend": storage.Backend(),
+			"bucket":  bucket,
+			"prefix":  prefix,
+			"limit":   limit,
+			"offset":  offset,
 		},
 	})
 }
 
-type storageListOptions struct {
-	Prefix     string
-	Limit      int
-	Offset     int
-	SortColumn string
-	SortOrder  string
-	Search     string
-}
-
-func (a *App) supabaseListBuckets(ctx context.Context, cfg supabaseStorageConfig) ([]string, error) {
-	listURL := fmt.Sprintf("%s/storage/v1/bucket", strings.TrimRight(cfg.BaseURL, "/"))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("build buckets request: %w", err)
+func (a *App) storageListEntries(storage Storage, objects []storageObject) []map[string]any {
+	entries := make([]map[string]any, 0, len(objects))
+	for _, object := range objects {
+		entries = append(entries, storageListEntry(storage, object, a.StorageAccess.isPrivate(object.Bucket)))
 	}
-	req.Header.Set("Authorization", "Bearer "+cfg.ServiceRole)
-	req.Header.Set("apikey", cfg.ServiceRole)
-
-	resp, err := (&http.Client{Timeout: 20 * time.Second}).Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("buckets request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("buckets request status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
-	}
-
-	var payload []map[string]any
-	if err := json.Unmarshal(raw, &payload); err != nil {
-		return nil, fmt.Errorf("decode buckets response: %w", err)
-	}
-
-	buckets := make([]string, 0, len(payload))
-	for _, bucket := range payload {
-		name, _ := bucket["name"].(string)
-		name = strings.TrimSpace(name)
-		if name == "" {
-			continue
-		}
-		buckets = append(buckets, name)
-	}
-	sort.Strings(buckets)
-	return buckets, nil
-}
-
-func (a *App) supabaseListBucketFiles(ctx context.Context, cfg supabaseStorageConfig, bucket string, opts storageListOptions) (any, error) {
-	payload := map[string]any{
-		"prefix": opts.Prefix,
-		"limit":  opts.Limit,
-		"offset": opts.Offset,
-		"sortBy": map[string]any{
-			"column": opts.SortColumn,
-			"order":  opts.SortOrder,
-		},
-	}
-	if opts.Search != "" {
-		payload["search"] = opts.Search
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("build list payload: %w", err)
-	}
-
-	listURL := fmt.Sprintf("%s/storage/v1/object/list/%s", strings.TrimRight(cfg.BaseURL, "/"), url.PathEscape(bucket))
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, listURL, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("build list request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+cfg.ServiceRole)
-	req.Header.Set("apikey", cfg.ServiceRole)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := (&http.Client{Timeout: 20 * time.Second}).Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("list request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("list request status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
-	}
-
-	if len(raw) == 0 {
-		return []any{}, nil
-	}
-
-	var data any
-	if err := json.Unmarshal(raw, &data); err != nil {
-		return nil, fmt.Errorf("decode list response: %w", err)
-	}
-	return data, nil
+	return entries
 }
 
 func (a *App) adminStorageDeleteHandler(w http.ResponseWriter, r *http.Request) {
@@ -2357,16 +3078,12 @@ func (a *App) adminStorageDeleteHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	cfg, err := loadSupabaseStorageConfig()
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"status":  "error",
-			"message": err.Error(),
-		})
+	storage, ok := a.storageOrError(w)
+	if !ok {
 		return
 	}
 
-	bucketValue := firstNonEmpty(r.URL.Query().Get("bucket"), cfg.DefaultBucket)
+	bucketValue := firstNonEmpty(r.URL.Query().Get("bucket"), defaultStorageBucket())
 	bucket, err := cleanStorageBucket(bucketValue)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
@@ -2385,40 +3102,48 @@ func (a *App) adminStorageDeleteHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	deleteURL := buildSupabaseObjectURL(cfg.BaseURL, bucket, objectPath)
 	ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{
-			"status":  "error",
-			"message": "failed to build delete request",
-		})
-		return
+	// Refuse to break live pages unless the caller insists with ?force=1.
+	if !isTruthy(r.URL.Query().Get("force")) {
+		usages, err := a.mediaUsages(ctx, bucket, objectPath)
+		if err != nil {
+			logFromContext(ctx).Error("media usage check failed", "bucket", bucket, "path", objectPath, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to check file usage (pass force=1 to skip)",
+			})
+			return
+		}
+		if len(usages) > 0 {
+			writeJSON(w, http.StatusConflict, map[string]any{
+				"status":  "error",
+				"message": "file is still referenced (pass force=1 to delete anyway)",
+				"usages":  usages,
+			})
+			return
+		}
 	}
-	req.Header.Set("Authorization", "Bearer "+cfg.ServiceRole)
-	req.Header.Set("apikey", cfg.ServiceRole)
 
-	resp, err := (&http.Client{Timeout: 20 * time.Second}).Do(req)
+	variantPaths, err := a.mediaVariantPaths(ctx, bucket, objectPath)
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{
-			"status":  "error",
-			"message": "storage delete request failed",
-		})
-		return
+		logFromContext(ctx).Warn("media variant lookup failed", "bucket", bucket, "path", objectPath, "error", err)
 	}
-	defer resp.Body.Close()
 
-	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		writeJSON(w, http.StatusBadGateway, map[string]any{
-			"status":  "error",
-			"message": "storage delete failed",
-			"details": strings.TrimSpace(string(raw)),
-		})
+	if err := storage.Delete(ctx, bucket, objectPath); err != nil {
+		logFromContext(ctx).Error("storage delete failed", "backend", storage.Backend(), "bucket", bucket, "path", objectPath, "error", err)
+		writeStorageError(w, "storage delete failed", err)
 		return
 	}
+	for _, variantPath := range variantPaths {
+		if err := storage.Delete(ctx, bucket, variantPath); err != nil && !errors.Is(err, errStorageNotFound) {
+			logFromContext(ctx).Warn("image variant delete failed", "bucket", bucket, "path", variantPath, "error", err)
+		}
+	}
+	if err := a.forgetMedia(ctx, bucket, objectPath); err != nil {
+		logFromContext(ctx).Error("media row cleanup failed", "bucket", bucket, "path", objectPath, "error", err)
+	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"status": "success",
@@ -2465,11 +3190,13 @@ func requireAdminToken(w http.ResponseWriter, r *http.Request) bool {
 	}
 
 	if cfg.StaticToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(cfg.StaticToken)) == 1 {
+		setRequestAdmin(r, "static_token")
 		return true
 	}
 
 	if cfg.SigningSecret != "" {
-		if _, err := verifyAdminAccessToken(provided, cfg.SigningSecret, time.Now()); err == nil {
+		if claims, err := verifyAdminAccessToken(provided, cfg.SigningSecret, time.Now()); err == nil {
+			setRequestAdmin(r, claims.Username)
 			return true
 		}
 	}
@@ -2481,6 +3208,13 @@ func requireAdminToken(w http.ResponseWriter, r *http.Request) bool {
 	return false
 }
 
+// setRequestAdmin records the authenticated admin for the request log.
+func setRequestAdmin(r *http.Request, username string) {
+	if info := requestInfoFromContext(r.Context()); info != nil {
+		info.AdminUsername = username
+	}
+}
+
 func extractAdminToken(r *http.Request) string {
 	provided := strings.TrimSpace(r.Header.Get("X-Admin-Token"))
 	if provided != "" {
@@ -2609,28 +3343,6 @@ func signAdminTokenPayload(payload, signingSecret string) []byte {
 	return mac.Sum(nil)
 }
 
-func loadSupabaseStorageConfig() (supabaseStorageConfig, error) {
-	baseURL := strings.TrimRight(strings.TrimSpace(os.Getenv("SUPABASE_URL")), "/")
-	serviceRole := strings.TrimSpace(os.Getenv("SUPABASE_SERVICE_ROLE_KEY"))
-	defaultBucket := strings.TrimSpace(os.Getenv("STORAGE_BUCKET"))
-	if defaultBucket == "" {
-		defaultBucket = "cars"
-	}
-
-	if baseURL == "" {
-		return supabaseStorageConfig{}, errors.New("SUPABASE_URL is not set")
-	}
-	if serviceRole == "" {
-		return supabaseStorageConfig{}, errors.New("SUPABASE_SERVICE_ROLE_KEY is not set")
-	}
-
-	return supabaseStorageConfig{
-		BaseURL:       baseURL,
-		ServiceRole:   serviceRole,
-		DefaultBucket: defaultBucket,
-	}, nil
-}
-
 func cleanStorageBucket(value string) (string, error) {
 	bucket := strings.TrimSpace(value)
 	if bucket == "" {
@@ -2690,24 +3402,6 @@ func sanitizeFilename(value string) string {
 	return base
 }
 
-func buildSupabaseObjectURL(baseURL, bucket, objectPath string) string {
-	return fmt.Sprintf(
-		"%s/storage/v1/object/%s/%s",
-		strings.TrimRight(baseURL, "/"),
-		url.PathEscape(bucket),
-		encodeStoragePath(objectPath),
-	)
-}
-
-func buildSupabasePublicURL(baseURL, bucket, objectPath string) string {
-	return fmt.Sprintf(
-		"%s/storage/v1/object/public/%s/%s",
-		strings.TrimRight(baseURL, "/"),
-		url.PathEscape(bucket),
-		encodeStoragePath(objectPath),
-	)
-}
-
 func encodeStoragePath(path string) string {
 	parts := strings.Split(path, "/")
 	for i, part := range parts {
@@ -2738,10 +3432,12 @@ func isTruthy(value string) bool {
 }
 
 func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", dsn)
+	connConfig, err := pgx.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
+	connConfig.Tracer = pgxQueryTracer{}
+	db := stdlib.OpenDB(*connConfig)
 
 	db.SetMaxOpenConns(15)
 	db.SetMaxIdleConns(5)
@@ -2755,7 +3451,7 @@ func openDB(dsn string) (*sql.DB, error) {
 		if pingErr == nil {
 			break
 		}
-		log.Printf("database ping attempt %d/3 failed: %v", attempt, pingErr)
+		slog.Warn("database ping failed", "attempt", attempt, "max_attempts", 3, "error", pingErr)
 		time.Sleep(2 * time.Second)
 	}
 	if pingErr != nil {
@@ -2898,6 +3594,26 @@ func writeJSON(w http.ResponseWriter, statusCode int, payload any) {
 	}
 }
 
+// extendRequestDeadlines lets one request outlive the server's ReadTimeout
+// and WriteTimeout: large upload bodies and long storage writes would
+// otherwise lose their connection while the handler keeps running.
+func extendRequestDeadlines(w http.ResponseWriter, r *http.Request, read, write time.Duration) {
+	controller := http.NewResponseController(w)
+	now := time.Now()
+	if err := controller.SetReadDeadline(now.Add(read)); err != nil {
+		logFromContext(r.Context()).Warn("extend read deadline failed", "error", err)
+	}
+	if err := controller.SetWriteDeadline(now.Add(write)); err != nil {
+		logFromContext(r.Context()).Warn("extend write deadline failed", "error", err)
+	}
+}
+
+// uploadReadTimeout is how long a body of up to maxBytes may take to arrive
+// at minUploadBytesPerSecond, and never less than serverReadTimeout.
+func uploadReadTimeout(maxBytes int64) time.Duration {
+	return serverReadTimeout + time.Duration(maxBytes/minUploadBytesPerSecond)*time.Second
+}
+
 func optionalStringDBValue(input string) any {
 	trimmed := strings.TrimSpace(input)
 	if trimmed == "" {
@@ -2997,40 +3713,53 @@ type consultationNotification struct {
 	Comments          *string   `json:"comments"`
 	Status            string    `json:"status"`
 	CreatedAt         time.Time `json:"created_at"`
+	// Attachments are signed links to the customer's photos.
+	Attachments []consultationAttachmentLink `json:"attachments,omitempty"`
 }
 
-func notifyAdminAboutConsultation(payload consultationNotification) {
+// notifyAdminAboutConsultation runs detached from the request; ctx only
+// carries the request ID and trace for logs and spans.
+func notifyAdminAboutConsultation(ctx context.Context, payload consultationNotification) {
+	defer pendingNotifications.Add(-1)
+
 	webhookURL := strings.TrimSpace(os.Getenv("ADMIN_NOTIFY_WEBHOOK_URL"))
 	if webhookURL == "" {
 		return
 	}
+	logger := logFromContext(ctx)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("consultation notify marshal error: %v", err)
+		recordNotification("webhook", "error")
+		logger.Error("consultation notify marshal error", "consultation_id", payload.ID, "error", err)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
 	if err != nil {
-		log.Printf("consultation notify request error: %v", err)
+		recordNotification("webhook", "error")
+		logger.Error("consultation notify request error", "consultation_id", payload.ID, "error", err)
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := (&http.Client{Timeout: 3 * time.Second}).Do(req)
+	resp, err := tracedHTTPDo(&http.Client{Timeout: 3 * time.Second}, req, "webhook consultation")
 	if err != nil {
-		log.Printf("consultation notify send error: %v", err)
+		recordNotification("webhook", "error")
+		logger.Error("consultation notify send error", "consultation_id", payload.ID, "error", err)
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		log.Printf("consultation notify non-2xx status: %s", resp.Status)
+		recordNotification("webhook", "failed")
+		logger.Error("consultation notify non-2xx status", "consultation_id", payload.ID, "status", resp.StatusCode)
+		return
 	}
+	recordNotification("webhook", "sent")
 }
 
 func resolveWorkPostTable(ctx context.Context, db *sql.DB) (string, error) {
@@ -3113,20 +3842,12 @@ func hasTable(ctx context.Context, db *sql.DB, tableName string) (bool, error) {
 	return exists, nil
 }
 
-// loggingMiddleware adds a minimal request log.
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start).Truncate(time.Millisecond))
-	})
-}
-
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Admin-Token")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Admin-Token, X-Captcha-Token, X-App-Id, X-App-Timestamp, X-App-Nonce, X-App-Signature, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,1023 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	defaultMediaLimit = 50
+	maxMediaLimit     = 200
+	maxMediaTags      = 20
+)
+
+// mediaURLColumn is a content column that stores links to uploaded files,
+// either a single URL (TEXT) or a JSON array of URLs.
+type mediaURLColumn struct {
+	Table     string
+	Column    string
+	AdminPath string
+}
+
+// mediaURLColumns lists every place the CMS tables reference storage files.
+// Keep it in sync with registerAdminCRUDRoutes when adding image columns.
+var mediaURLColumns = []mediaURLColumn{
+	{Table: "banners", Column: "image_url", AdminPath: "/admin/banners"},
+	{Table: "contact_page", Column: "image_url", AdminPath: "/admin/contact_page"},
+	{Table: "about_page", Column: "banner_image_url", AdminPath: "/admin/about_page"},
+	{Table: "about_page", Column: "mission_image_url", AdminPath: "/admin/about_page"},
+	{Table: "about_page", Column: "video_url", AdminPath: "/admin/about_page"},
+	{Table: "partners", Column: "logo_url", AdminPath: "/admin/partners"},
+	{Table: "tuning", Column: "card_image_url", AdminPath: "/admin/tuning"},
+	{Table: "tuning", Column: "full_image_url", AdminPath: "/admin/tuning"},
+	{Table: "tuning", Column: "video_image_url", AdminPath: "/admin/tuning"},
+	{Table: "tuning_cards", Column: "image_url"},
+	{Table: "service_offerings", Column: "gallery_images", AdminPath: "/admin/service_offerings"},
+	{Table: "portfolio_items", Column: "image_url", AdminPath: "/admin/portfolio_items"},
+	{Table: "work_post", Column: "card_image_url", AdminPath: "/admin/work_post"},
+	{Table: "work_post", Column: "full_image_url", AdminPath: "/admin/work_post"},
+	{Table: "work_post", Column: "gallery_images", AdminPath: "/admin/work_post"},
+	{Table: "work_post", Column: "video_image_url", AdminPath: "/admin/work_post"},
+	{Table: "blog_posts", Column: "card_image_url", AdminPath: "/admin/blog_posts"},
+	{Table: "blog_posts", Column: "full_image_url", AdminPath: "/admin/blog_posts"},
+	{Table: "blog_posts", Column: "gallery_images", AdminPath: "/admin/blog_posts"},
+	{Table: "blog_posts", Column: "video_image_url", AdminPath: "/admin/blog_posts"},
+}
+
+type mediaItem struct {
+	ID         int64          `json:"id"`
+	Backend    string         `json:"backend"`
+	Bucket     string         `json:"bucket"`
+	Path       string         `json:"path"`
+	PublicURL  string         `json:"public_url"`
+	SizeBytes  int64          `json:"size_bytes"`
+	MimeType   string         `json:"mime_type"`
+	Width      *int           `json:"width"`
+	Height     *int           `json:"height"`
+	Checksum   string         `json:"checksum_sha256,omitempty"`
+	AltText    string         `json:"alt_text"`
+	Tags       []string       `json:"tags"`
+	UploadedBy string         `json:"uploaded_by,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Variants   []mediaVariant `json:"variants"`
+	UsageCount *int           `json:"usage_count,omitempty"`
+}
+
+// mediaVariant is a derived image stored next to the original (see imageVariantPath).
+type mediaVariant struct {
+	Name      string `json:"name"`
+	Format    string `json:"format"`
+	Path      string `json:"path"`
+	MimeType  string `json:"mime_type"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	SizeBytes int64  `json:"size_bytes"`
+	URL       string `json:"url,omitempty"`
+}
+
+// imageVariantURLs maps variant name -> format -> public URL, e.g.
+// {"thumb": {"jpeg": "...", "webp": "..."}}.
+type imageVariantURLs map[string]map[string]string
+
+// mediaReference is one content cell pointing at a storage object.
+type mediaReference struct {
+	Table     string `json:"table"`
+	Column    string `json:"column"`
+	RowID     int64  `json:"id"`
+	URL       string `json:"url"`
+	AdminPath string `json:"admin_path,omitempty"`
+	Bucket    string `json:"-"`
+	Path      string `json:"-"`
+}
+
+func mediaKey(bucket, objectPath string) string {
+	return bucket + "/" + objectPath
+}
+
+// inspectUpload hashes the file and reads image dimensions from its header,
+// leaving the reader rewound for the actual upload.
+func inspectUpload(file io.ReadSeeker) (checksum string, width, height *int, err error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", nil, nil, fmt.Errorf("hash upload: %w", err)
+	}
+	checksum = hex.EncodeToString(hasher.Sum(nil))
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", nil, nil, fmt.Errorf("rewind upload: %w", err)
+	}
+	if cfg, _, decodeErr := image.DecodeConfig(file); decodeErr == nil {
+		width, height = &cfg.Width, &cfg.Height
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", nil, nil, fmt.Errorf("rewind upload: %w", err)
+	}
+	return checksum, width, height, nil
+}
+
+// recordMedia upserts the media row for an uploaded object. Alt text and
+// tags are only overwritten when provided.
+func (a *App) recordMedia(ctx context.Context, item mediaItem) (int64, error) {
+	var tags any
+	if item.Tags != nil {
+		tags = item.Tags
+	}
+	if item.Variants == nil {
+		item.Variants = []mediaVariant{}
+	}
+	variants, err := json.Marshal(item.Variants)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = a.DB.QueryRowContext(
+		withQueryName(ctx, "media.upsert"),
+		`INSERT INTO public.media
+			(backend, bucket, path, public_url, size_bytes, mime_type, width, height, checksum_sha256, alt_text, tags, uploaded_by, variants)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11::text[], '{}'), $12, $13::jsonb)
+		ON CONFLICT (bucket, path) DO UPDATE SET
+			backend = EXCLUDED.backend,
+			public_url = EXCLUDED.public_url,
+			size_bytes = EXCLUDED.size_bytes,
+			mime_type = EXCLUDED.mime_type,
+			width = EXCLUDED.width,
+			height = EXCLUDED.height,
+			checksum_sha256 = EXCLUDED.checksum_sha256,
+			alt_text = COALESCE(EXCLUDED.alt_text, public.media.alt_text),
+			tags = CASE WHEN $11::text[] IS NULL THEN public.media.tags ELSE EXCLUDED.tags END,
+			uploaded_by = EXCLUDED.uploaded_by,
+			variants = EXCLUDED.variants,
+			updated_at = NOW()
+		RETURNING id`,
+		item.Backend,
+		item.Bucket,
+		item.Path,
+		item.PublicURL,
+		item.SizeBytes,
+		item.MimeType,
+		item.Width,
+		item.Height,
+		optionalStringDBValue(item.Checksum),
+		optionalStringDBValue(item.AltText),
+		tags,
+		optionalStringDBValue(item.UploadedBy),
+		string(variants),
+	).Scan(&id)
+	if err == nil {
+		a.Responses.invalidate("media")
+	}
+	return id, err
+}
+
+// forgetMedia drops the media row after the object itself was deleted.
+func (a *App) forgetMedia(ctx context.Context, bucket, objectPath string) error {
+	_, err := a.DB.ExecContext(
+		withQueryName(ctx, "media.delete"),
+		`DELETE FROM public.media WHERE bucket = $1 AND path = $2`,
+		bucket,
+		objectPath,
+	)
+	if err == nil {
+		a.Responses.invalidate("media")
+	}
+	return err
+}
+
+// findMediaByChecksum returns the oldest media row in the bucket with the
+// given content hash whose object still exists, or sql.ErrNoRows.
+func (a *App) findMediaByChecksum(ctx context.Context, storage Storage, bucket, checksum string) (mediaItem, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "media.find_by_checksum"),
+		`SELECT `+mediaSelectColumns+`
+		FROM public.media
+		WHERE bucket = $1 AND checksum_sha256 = $2
+		ORDER BY id
+		LIMIT 5`,
+		bucket,
+		checksum,
+	)
+	if err != nil {
+		return mediaItem{}, err
+	}
+	var items []mediaItem
+	for rows.Next() {
+		item, err := scanMediaItem(rows)
+		if err != nil {
+			rows.Close()
+			return mediaItem{}, err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return mediaItem{}, err
+	}
+
+	// Rows can outlive their objects (see the media audit); skip those.
+	for _, item := range items {
+		if _, err := storage.Stat(ctx, item.Bucket, item.Path); err == nil {
+			return item, nil
+		} else if !errors.Is(err, errStorageNotFound) {
+			return mediaItem{}, err
+		}
+	}
+	return mediaItem{}, sql.ErrNoRows
+}
+
+// parseMediaTags accepts "a, b" or a JSON array and returns lower-cased,
+// de-duplicated tags. A nil result means "not provided".
+func parseMediaTags(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var values []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &values); err != nil {
+			return nil, errors.New("tags must be a JSON array of strings or a comma-separated list")
+		}
+	} else {
+		values = strings.Split(raw, ",")
+	}
+	return normalizeMediaTags(values)
+}
+
+func normalizeMediaTags(values []string) ([]string, error) {
+	tags := make([]string, 0, len(values))
+	for _, value := range uniqueNonEmpty(values...) {
+		tag := strings.ToLower(strings.TrimSpace(value))
+		if len([]rune(tag)) > 50 {
+			return nil, fmt.Errorf("tag %q is too long (max 50 characters)", tag)
+		}
+		tags = append(tags, tag)
+	}
+	tags = uniqueNonEmpty(tags...)
+	if len(tags) > maxMediaTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxMediaTags)
+	}
+	return tags, nil
+}
+
+// collectMediaReferences scans every mediaURLColumns column and resolves the
+// stored links to bucket/path. Content tables are small, so a full scan per
+// call is fine.
+func (a *App) collectMediaReferences(ctx context.Context) ([]mediaReference, error) {
+	available, err := a.availableMediaColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []mediaReference
+	for _, col := range mediaURLColumns {
+		if _, ok := available[col.Table+"."+col.Column]; !ok {
+			continue
+		}
+
+		query := fmt.Sprintf(
+			`SELECT t.id, to_jsonb(t.%s) FROM %s t WHERE t.%s IS NOT NULL`,
+			quoteIdentifier(col.Column),
+			quoteTableName("public."+col.Table),
+			quoteIdentifier(col.Column),
+		)
+		rows, err := a.DB.QueryContext(withQueryName(ctx, "media.references."+col.Table), query)
+		if err != nil {
+			return nil, fmt.Errorf("scan %s.%s: %w", col.Table, col.Column, err)
+		}
+
+		for rows.Next() {
+			var (
+				rowID int64
+				raw   []byte
+			)
+			if err := rows.Scan(&rowID, &raw); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan %s.%s: %w", col.Table, col.Column, err)
+			}
+			for _, link := range mediaLinksFromJSON(raw) {
+				ref := mediaReference{
+					Table:  col.Table,
+					Column: col.Column,
+					RowID:  rowID,
+					URL:    link,
+				}
+				if col.AdminPath != "" {
+					ref.AdminPath = col.AdminPath + "/" + strconv.FormatInt(rowID, 10)
+				}
+				ref.Bucket, ref.Path, _ = resolveStorageURL(a.Storage, link)
+				refs = append(refs, ref)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan %s.%s: %w", col.Table, col.Column, err)
+		}
+		rows.Close()
+	}
+	return refs, nil
+}
+
+// availableMediaColumns returns the data_type of each mediaURLColumns column
+// present in this database, keyed by "table.column".
+func (a *App) availableMediaColumns(ctx context.Context) (map[string]string, error) {
+	columns := make([]string, 0, len(mediaURLColumns))
+	for _, col := range mediaURLColumns {
+		columns = append(columns, col.Column)
+	}
+
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "media.columns"),
+		`SELECT table_name, column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = 'public'
+		  AND column_name = ANY($1)`,
+		uniqueNonEmpty(columns...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("inspect media columns: %w", err)
+	}
+	defer rows.Close()
+
+	available := map[string]string{}
+	for rows.Next() {
+		var table, column, dataType string
+		if err := rows.Scan(&table, &column, &dataType); err != nil {
+			return nil, fmt.Errorf("inspect media columns: %w", err)
+		}
+		available[table+"."+column] = dataType
+	}
+	return available, rows.Err()
+}
+
+// mediaLinksFromJSON extracts links from a to_jsonb() cell: a plain string,
+// a JSON array of strings, or a string holding a JSON array (legacy TEXT rows).
+func mediaLinksFromJSON(raw []byte) []string {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil
+	}
+
+	switch typed := value.(type) {
+	case string:
+		trimmed := strings.TrimSpace(typed)
+		if strings.HasPrefix(trimmed, "[") {
+			return parseStringArray([]byte(trimmed))
+		}
+		return uniqueNonEmpty(trimmed)
+	case []any:
+		links := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if link, ok := item.(string); ok {
+				links = append(links, link)
+			}
+		}
+		return uniqueNonEmpty(links...)
+	}
+	return nil
+}
+
+// resolveStorageURL maps a stored link back to bucket/path. It understands
+// the active backend's public URLs plus Supabase and /files/ links, so rows
+// written before a backend switch still resolve.
+func resolveStorageURL(storage Storage, raw string) (string, string, bool) {
+	link := strings.TrimSpace(raw)
+	if link == "" {
+		return "", "", false
+	}
+	if idx := strings.IndexAny(link, "?#"); idx >= 0 {
+		link = link[:idx]
+	}
+
+	var rest string
+	if storage != nil {
+		// PublicURL(b, p) always ends with "b/p", so what precedes it is the base.
+		probe := storage.PublicURL("bucket", "path")
+		if base := strings.TrimSuffix(probe, "bucket/path"); base != probe && base != "" && strings.HasPrefix(link, base) {
+			rest = strings.TrimPrefix(link, base)
+		}
+	}
+	if rest == "" {
+		for _, marker := range []string{
+			"/storage/v1/object/public/",
+			"/storage/v1/render/image/public/",
+			"/storage/v1/object/sign/",
+			"/storage/v1/object/",
+			localStorageRoute,
+		} {
+			if idx := strings.Index(link, marker); idx >= 0 {
+				rest = link[idx+len(marker):]
+				break
+			}
+		}
+	}
+	if rest == "" {
+		return "", "", false
+	}
+
+	bucketPart, pathPart, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", "", false
+	}
+	bucket, err := url.PathUnescape(bucketPart)
+	if err != nil {
+		return "", "", false
+	}
+	objectPath, err := url.PathUnescape(pathPart)
+	if err != nil {
+		return "", "", false
+	}
+	if bucket, err = cleanStorageBucket(bucket); err != nil {
+		return "", "", false
+	}
+	if objectPath, err = cleanStoragePath(objectPath); err != nil {
+		return "", "", false
+	}
+	return bucket, objectPath, true
+}
+
+// mediaReferenceIndex groups references by bucket/path.
+func mediaReferenceIndex(refs []mediaReference) map[string][]mediaReference {
+	index := make(map[string][]mediaReference)
+	for _, ref := range refs {
+		if ref.Bucket == "" {
+			continue
+		}
+		key := mediaKey(ref.Bucket, ref.Path)
+		index[key] = append(index[key], ref)
+	}
+	return index
+}
+
+// storeImageVariants uploads the rendered variants next to the original.
+// Failures are logged and skipped: the original upload already succeeded.
+func storeImageVariants(ctx context.Context, storage Storage, bucket, objectPath string, processed *processedImage) []mediaVariant {
+	if processed == nil {
+		return []mediaVariant{}
+	}
+
+	stored := make([]mediaVariant, 0, len(processed.Variants))
+	for _, variant := range processed.Variants {
+		variantPath := imageVariantPath(objectPath, variant.Name, variant.Format)
+		_, err := storage.Put(ctx, bucket, variantPath, bytes.NewReader(variant.Data), storagePutOptions{
+			ContentType:  variant.MimeType,
+			CacheControl: "public, max-age=31536000",
+			Size:         int64(len(variant.Data)),
+			Upsert:       true,
+		})
+		if err != nil {
+			logFromContext(ctx).Error("image variant upload failed", "bucket", bucket, "path", variantPath, "error", err)
+			continue
+		}
+		stored = append(stored, mediaVariant{
+			Name:      variant.Name,
+			Format:    variant.Format,
+			Path:      variantPath,
+			MimeType:  variant.MimeType,
+			Width:     variant.Width,
+			Height:    variant.Height,
+			SizeBytes: int64(len(variant.Data)),
+			URL:       storage.PublicURL(bucket, variantPath),
+		})
+	}
+	return stored
+}
+
+// mediaVariantIndex maps bucket/path of every original to its variant paths.
+func (a *App) mediaVariantIndex(ctx context.Context) (map[string][]string, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "media.variants"),
+		`SELECT bucket, path, variants FROM public.media WHERE jsonb_array_length(variants) > 0`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := map[string][]string{}
+	for rows.Next() {
+		var (
+			bucket, objectPath string
+			raw                []byte
+			variants           []mediaVariant
+		)
+		if err := rows.Scan(&bucket, &objectPath, &raw); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &variants); err != nil {
+			continue
+		}
+		key := mediaKey(bucket, objectPath)
+		for _, variant := range variants {
+			index[key] = append(index[key], variant.Path)
+		}
+	}
+	return index, rows.Err()
+}
+
+// mediaVariantPaths returns the stored variant paths of one original.
+func (a *App) mediaVariantPaths(ctx context.Context, bucket, objectPath string) ([]string, error) {
+	var raw []byte
+	err := a.DB.QueryRowContext(
+		withQueryName(ctx, "media.variant_paths"),
+		`SELECT variants FROM public.media WHERE bucket = $1 AND path = $2`,
+		bucket,
+		objectPath,
+	).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []mediaVariant
+	if err := json.Unmarshal(raw, &variants); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		paths = append(paths, variant.Path)
+	}
+	return paths, nil
+}
+
+// imageVariantsByURL looks up the variants of images referenced by public
+// content. Links that are not known media, or have no variants, are absent.
+func (a *App) imageVariantsByURL(ctx context.Context, links []string) map[string]imageVariantURLs {
+	if a.Storage == nil || len(links) == 0 {
+		return nil
+	}
+
+	keyToLink := map[string][]string{}
+	for _, link := range uniqueNonEmpty(links...) {
+		bucket, objectPath, ok := resolveStorageURL(a.Storage, link)
+		if !ok {
+			continue
+		}
+		key := mediaKey(bucket, objectPath)
+		keyToLink[key] = append(keyToLink[key], link)
+	}
+	if len(keyToLink) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(keyToLink))
+	for key := range keyToLink {
+		keys = append(keys, key)
+	}
+
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "media.variants_by_url"),
+		`SELECT bucket, path, variants
+		FROM public.media
+		WHERE bucket || '/' || path = ANY($1)
+		  AND jsonb_array_length(variants) > 0`,
+		keys,
+	)
+	if err != nil {
+		logFromContext(ctx).Warn("image variant lookup failed", "error", err)
+		return nil
+	}
+	defer rows.Close()
+
+	result := map[string]imageVariantURLs{}
+	for rows.Next() {
+		var (
+			bucket, objectPath string
+			raw                []byte
+			variants           []mediaVariant
+		)
+		if err := rows.Scan(&bucket, &objectPath, &raw); err != nil {
+			logFromContext(ctx).Warn("image variant lookup failed", "error", err)
+			return nil
+		}
+		if err := json.Unmarshal(raw, &variants); err != nil {
+			continue
+		}
+
+		urls := imageVariantURLs{}
+		for _, variant := range variants {
+			if urls[variant.Name] == nil {
+				urls[variant.Name] = map[string]string{}
+			}
+			urls[variant.Name][variant.Format] = a.Storage.PublicURL(bucket, variant.Path)
+		}
+		for _, link := range keyToLink[mediaKey(bucket, objectPath)] {
+			result[link] = urls
+		}
+	}
+	return result
+}
+
+// adminMediaHandler serves the media browser:
+//
+//	GET   /admin/media            list (search, tag, bucket, mime, limit, offset)
+//	GET   /admin/media/tags       tag cloud
+//	GET   /admin/media/audit      orphaned files and broken links (?refresh=1)
+//	POST  /admin/media/orphans/cleanup
+//	GET   /admin/media/{id}       one file plus the rows that use it
+//	PATCH /admin/media/{id}       update alt_text and tags
+func (a *App) adminMediaHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdminToken(w, r) {
+		return
+	}
+
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/admin/media/tags":
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+				"status":  "error",
+				"message": "method not allowed",
+			})
+			return
+		}
+		a.adminMediaTags(w, r)
+		return
+	case "/admin/media/audit":
+		a.adminMediaAuditHandler(w, r)
+		return
+	case "/admin/media/orphans/cleanup":
+		a.adminMediaOrphanCleanupHandler(w, r)
+		return
+	}
+
+	id, hasID, err := parseResourceID(r, "/admin/media")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "invalid id",
+		})
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && hasID:
+		a.adminMediaFetchOne(w, r, id)
+	case r.Method == http.MethodGet:
+		a.adminMediaList(w, r)
+	case (r.Method == http.MethodPatch || r.Method == http.MethodPut) && hasID:
+		a.adminMediaUpdate(w, r, id)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+	}
+}
+
+const mediaSelectColumns = `id, backend, bucket, path, public_url, size_bytes, mime_type, width, height,
+	COALESCE(checksum_sha256, ''), COALESCE(alt_text, ''), array_to_json(tags), COALESCE(uploaded_by, ''), created_at, updated_at, variants`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMediaItem(row rowScanner) (mediaItem, error) {
+	var (
+		item          mediaItem
+		width, height sql.NullInt64
+		tags          []byte
+		variants      []byte
+	)
+	if err := row.Scan(
+		&item.ID, &item.Backend, &item.Bucket, &item.Path, &item.PublicURL, &item.SizeBytes, &item.MimeType,
+		&width, &height, &item.Checksum, &item.AltText, &tags, &item.UploadedBy, &item.CreatedAt, &item.UpdatedAt, &variants,
+	); err != nil {
+		return mediaItem{}, err
+	}
+	if width.Valid {
+		value := int(width.Int64)
+		item.Width = &value
+	}
+	if height.Valid {
+		value := int(height.Int64)
+		item.Height = &value
+	}
+	item.Tags = parseStringArray(tags)
+	if err := json.Unmarshal(variants, &item.Variants); err != nil || item.Variants == nil {
+		item.Variants = []mediaVariant{}
+	}
+	return item, nil
+}
+
+func (a *App) adminMediaList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parseIntOrDefault(query.Get("limit"), defaultMediaLimit)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "limit must be an integer",
+		})
+		return
+	}
+	limit = max(1, min(limit, maxMediaLimit))
+
+	offset, err := parseIntOrDefault(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "offset must be a non-negative integer",
+		})
+		return
+	}
+
+	conditions := []string{"TRUE"}
+	args := []any{}
+	addArg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if search := strings.TrimSpace(query.Get("search")); search != "" {
+		placeholder := addArg("%" + search + "%")
+		conditions = append(conditions, fmt.Sprintf("(path ILIKE %[1]s OR alt_text ILIKE %[1]s)", placeholder))
+	}
+	if tag := strings.ToLower(strings.TrimSpace(query.Get("tag"))); tag != "" {
+		conditions = append(conditions, addArg(tag)+" = ANY(tags)")
+	}
+	if bucket := strings.TrimSpace(query.Get("bucket")); bucket != "" {
+		conditions = append(conditions, "bucket = "+addArg(bucket))
+	}
+	if mimeType := strings.TrimSpace(query.Get("mime")); mimeType != "" {
+		// "image/" matches every image type.
+		conditions = append(conditions, "mime_type LIKE "+addArg(mimeType+"%"))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	var total int64
+	if err := a.DB.QueryRowContext(withQueryName(ctx, "media.count"), `SELECT COUNT(*) FROM public.media WHERE `+where, args...).Scan(&total); err != nil {
+		logFromContext(ctx).Error("media count failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to fetch media",
+		})
+		return
+	}
+
+	listSQL := fmt.Sprintf(
+		`SELECT %s FROM public.media WHERE %s ORDER BY created_at DESC, id DESC LIMIT %s OFFSET %s`,
+		mediaSelectColumns, where, addArg(limit), addArg(offset),
+	)
+	rows, err := a.DB.QueryContext(withQueryName(ctx, "media.list"), listSQL, args...)
+	if err != nil {
+		logFromContext(ctx).Error("media list failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to fetch media",
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]mediaItem, 0, limit)
+	for rows.Next() {
+		item, err := scanMediaItem(rows)
+		if err != nil {
+			logFromContext(ctx).Error("media list scan failed", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to fetch media",
+			})
+			return
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		logFromContext(ctx).Error("media list rows failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to fetch media",
+		})
+		return
+	}
+
+	if refs, err := a.collectMediaReferences(ctx); err != nil {
+		logFromContext(ctx).Warn("media usage lookup failed", "error", err)
+	} else {
+		index := mediaReferenceIndex(refs)
+		for i := range items {
+			count := len(index[mediaKey(items[i].Bucket, items[i].Path)])
+			items[i].UsageCount = &count
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   items,
+		"meta": map[string]any{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+func (a *App) adminMediaFetchOne(w http.ResponseWriter, r *http.Request, id int64) {
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	item, err := scanMediaItem(a.DB.QueryRowContext(
+		withQueryName(ctx, "media.get"),
+		`SELECT `+mediaSelectColumns+` FROM public.media WHERE id = $1`,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]any{
+				"status":  "error",
+				"message": "record not found",
+			})
+			return
+		}
+		logFromContext(ctx).Error("media fetch failed", "id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to fetch media",
+		})
+		return
+	}
+
+	refs, err := a.collectMediaReferences(ctx)
+	if err != nil {
+		logFromContext(ctx).Error("media usage lookup failed", "id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to resolve media usage",
+		})
+		return
+	}
+	usages := mediaReferenceIndex(refs)[mediaKey(item.Bucket, item.Path)]
+	if usages == nil {
+		usages = []mediaReference{}
+	}
+	count := len(usages)
+	item.UsageCount = &count
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"media":  item,
+			"usages": usages,
+		},
+	})
+}
+
+func (a *App) adminMediaUpdate(w http.ResponseWriter, r *http.Request, id int64) {
+	payload, ok := decodeJSONMap(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		altText any
+		tags    any
+	)
+	validationErrors := map[string]string{}
+	for key, value := range payload {
+		switch key {
+		case "alt_text":
+			text, isString := value.(string)
+			if value != nil && !isString {
+				validationErrors[key] = "must be a string"
+				continue
+			}
+			altText = strings.TrimSpace(text)
+		case "tags":
+			rawTags, isList := value.([]any)
+			if !isList {
+				validationErrors[key] = "must be an array of strings"
+				continue
+			}
+			values := make([]string, 0, len(rawTags))
+			for _, rawTag := range rawTags {
+				tag, isString := rawTag.(string)
+				if !isString {
+					validationErrors[key] = "must be an array of strings"
+					break
+				}
+				values = append(values, tag)
+			}
+			normalized, err := normalizeMediaTags(values)
+			if err != nil {
+				validationErrors[key] = err.Error()
+				continue
+			}
+			tags = normalized
+		default:
+			validationErrors[key] = "unknown field"
+		}
+	}
+	if altText == nil && tags == nil && len(validationErrors) == 0 {
+		validationErrors["payload"] = "alt_text or tags is required"
+	}
+	if len(validationErrors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"message": "validation error",
+			"errors":  validationErrors,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
+	defer cancel()
+
+	item, err := scanMediaItem(a.DB.QueryRowContext(
+		withQueryName(ctx, "media.update"),
+		`UPDATE public.media
+		SET alt_text = CASE WHEN $2::boolean THEN $3 ELSE alt_text END,
+			tags = COALESCE($4::text[], tags),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+mediaSelectColumns,
+		id,
+		altText != nil,
+		altText,
+		tags,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]any{
+				"status":  "error",
+				"message": "record not found",
+			})
+			return
+		}
+		logFromContext(ctx).Error("media update failed", "id", id, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to update media",
+		})
+		return
+	}
+	a.Responses.invalidate("media")
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   item,
+	})
+}
+
+func (a *App) adminMediaTags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "media.tags"),
+		`SELECT tag, COUNT(*) FROM public.media, unnest(tags) AS tag GROUP BY tag ORDER BY COUNT(*) DESC, tag ASC`,
+	)
+	if err != nil {
+		logFromContext(ctx).Error("media tags failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to fetch tags",
+		})
+		return
+	}
+	defer rows.Close()
+
+	type tagCount struct {
+		Tag   string `json:"tag"`
+		Count int64  `json:"count"`
+	}
+	tags := []tagCount{}
+	for rows.Next() {
+		var item tagCount
+		if err := rows.Scan(&item.Tag, &item.Count); err != nil {
+			logFromContext(ctx).Error("media tags scan failed", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to fetch tags",
+			})
+			return
+		}
+		tags = append(tags, item)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   tags,
+	})
+}
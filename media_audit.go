@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMediaAuditInterval = 24 * time.Hour
+	defaultMediaOrphanGrace   = 24 * time.Hour
+	mediaAuditTimeout         = 2 * time.Minute
+	storageWalkPageSize       = 1000
+	maxOrphanCleanupItems     = 500
+)
+
+// mediaAuditState keeps the latest orphan/broken report in memory. Only one
+// audit runs at a time per process.
+type mediaAuditState struct {
+	running sync.Mutex
+	mu      sync.RWMutex
+	last    *mediaAuditReport
+}
+
+type mediaAuditReport struct {
+	StartedAt       time.Time           `json:"started_at"`
+	FinishedAt      time.Time           `json:"finished_at"`
+	DurationMS      float64             `json:"duration_ms"`
+	Backend         string              `json:"backend"`
+	Buckets         []string            `json:"buckets"`
+	ObjectCount     int                 `json:"object_count"`
+	ReferenceCount  int                 `json:"reference_count"`
+	ExternalLinks   int                 `json:"external_links"`
+	OrphanedBytes   int64               `json:"orphaned_bytes"`
+	Orphaned        []mediaOrphan       `json:"orphaned"`
+	Broken          []brokenMediaLink   `json:"broken"`
+	StaleMediaRows  []staleMediaRow     `json:"stale_media_rows"`
+	BucketErrors    map[string]string   `json:"bucket_errors,omitempty"`
+	OrphanGraceTime string              `json:"orphan_grace"`
+	refs            map[string]struct{} // bucket/path keys still referenced
+}
+
+// mediaOrphan is a stored object no content row links to. Objects newer than
+// the grace period are reported but not deletable: they may belong to a form
+// the admin has not saved yet.
+type mediaOrphan struct {
+	Bucket    string    `json:"bucket"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	PublicURL string    `json:"public_url"`
+	Deletable bool      `json:"deletable"`
+}
+
+// brokenMediaLink is a content cell pointing at an object that does not exist.
+type brokenMediaLink struct {
+	mediaReference
+	Bucket string `json:"bucket"`
+	Path   string `json:"path"`
+}
+
+type staleMediaRow struct {
+	ID     int64  `json:"id"`
+	Bucket string `json:"bucket"`
+	Path   string `json:"path"`
+}
+
+func mediaAuditInterval() time.Duration {
+	raw := strings.ToLower(strings.TrimSpace(os.Getenv("MEDIA_AUDIT_INTERVAL")))
+	if raw == "0" || raw == "off" || raw == "false" {
+		return 0
+	}
+	return parseDurationOrDefault(raw, defaultMediaAuditInterval)
+}
+
+func mediaOrphanGrace() time.Duration {
+	return parseDurationOrDefault(os.Getenv("MEDIA_ORPHAN_GRACE"), defaultMediaOrphanGrace)
+}
+
+// runMediaAuditLoop refreshes the report every MEDIA_AUDIT_INTERVAL
+// (default 24h, "off" disables) until ctx is cancelled.
+func (a *App) runMediaAuditLoop(ctx context.Context) {
+	interval := mediaAuditInterval()
+	if interval <= 0 || a.Storage == nil {
+		logFromContext(ctx).Info("media audit job disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel := context.WithTimeout(ctx, mediaAuditTimeout)
+			report, err := a.runMediaAudit(runCtx)
+			cancel()
+			if err != nil {
+				logFromContext(ctx).Error("media audit failed", "error", err)
+				continue
+			}
+			logFromContext(ctx).Info("media audit finished",
+				"objects", report.ObjectCount,
+				"orphaned", len(report.Orphaned),
+				"broken", len(report.Broken),
+				"stale_media_rows", len(report.StaleMediaRows),
+			)
+		}
+	}
+}
+
+// runMediaAudit cross-references every bucket listing with mediaURLColumns
+// and the media table.
+func (a *App) runMediaAudit(ctx context.Context) (*mediaAuditReport, error) {
+	if a.Storage == nil {
+		return nil, errStorageNotConfigured
+	}
+	a.mediaAudit.running.Lock()
+	defer a.mediaAudit.running.Unlock()
+
+	report := &mediaAuditReport{
+		StartedAt:       time.Now().UTC(),
+		Backend:         a.Storage.Backend(),
+		Orphaned:        []mediaOrphan{},
+		Broken:          []brokenMediaLink{},
+		StaleMediaRows:  []staleMediaRow{},
+		BucketErrors:    map[string]string{},
+		OrphanGraceTime: mediaOrphanGrace().String(),
+		refs:            map[string]struct{}{},
+	}
+
+	refs, err := a.collectMediaReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.ReferenceCount = len(refs)
+	for _, ref := range refs {
+		if ref.Bucket == "" {
+			report.ExternalLinks++
+			continue
+		}
+		report.refs[mediaKey(ref.Bucket, ref.Path)] = struct{}{}
+	}
+
+	// Consultation photos are owned by their consultation, not by content.
+	attachmentKeys, err := a.consultationAttachmentKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load consultation attachments: %w", err)
+	}
+	for key := range attachmentKeys {
+		report.refs[key] = struct{}{}
+	}
+
+	// Variants live and die with their original.
+	variantIndex, err := a.mediaVariantIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load media variants: %w", err)
+	}
+	for key, variantPaths := range variantIndex {
+		if _, used := report.refs[key]; !used {
+			continue
+		}
+		bucket, _, _ := strings.Cut(key, "/")
+		for _, variantPath := range variantPaths {
+			report.refs[mediaKey(bucket, variantPath)] = struct{}{}
+		}
+	}
+
+	buckets, err := a.Storage.ListBuckets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list buckets: %w", err)
+	}
+	report.Buckets = buckets
+
+	objects := map[string]struct{}{}
+	cutoff := time.Now().Add(-mediaOrphanGrace())
+	for _, bucket := range buckets {
+		err := walkStorageBucket(ctx, a.Storage, bucket, func(object storageObject) error {
+			report.ObjectCount++
+			key := mediaKey(bucket, object.Path)
+			objects[key] = struct{}{}
+			if _, used := report.refs[key]; used {
+				return nil
+			}
+			report.OrphanedBytes += object.Size
+			report.Orphaned = append(report.Orphaned, mediaOrphan{
+				Bucket:    bucket,
+				Path:      object.Path,
+				Size:      object.Size,
+				UpdatedAt: object.UpdatedAt,
+				PublicURL: a.Storage.PublicURL(bucket, object.Path),
+				Deletable: !object.UpdatedAt.IsZero() && object.UpdatedAt.Before(cutoff),
+			})
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			report.BucketErrors[bucket] = err.Error()
+		}
+	}
+
+	// A link is only "broken" when its bucket was listed successfully (or does
+	// not exist at all); after a listing error we simply do not know.
+	for _, ref := range refs {
+		if ref.Bucket == "" {
+			continue
+		}
+		if _, failed := report.BucketErrors[ref.Bucket]; failed {
+			continue
+		}
+		if _, ok := objects[mediaKey(ref.Bucket, ref.Path)]; ok {
+			continue
+		}
+		report.Broken = append(report.Broken, brokenMediaLink{mediaReference: ref, Bucket: ref.Bucket, Path: ref.Path})
+	}
+
+	stale, err := a.staleMediaRows(ctx, objects, report.BucketErrors)
+	if err != nil {
+		return nil, err
+	}
+	report.StaleMediaRows = stale
+
+	report.FinishedAt = time.Now().UTC()
+	report.DurationMS = float64(report.FinishedAt.Sub(report.StartedAt).Microseconds()) / 1000
+
+	a.mediaAudit.mu.Lock()
+	a.mediaAudit.last = report
+	a.mediaAudit.mu.Unlock()
+	return report, nil
+}
+
+// staleMediaRows finds media rows whose object is gone from storage.
+func (a *App) staleMediaRows(ctx context.Context, objects map[string]struct{}, bucketErrors map[string]string) ([]staleMediaRow, error) {
+	rows, err := a.DB.QueryContext(withQueryName(ctx, "media.audit_rows"), `SELECT id, bucket, path FROM public.media ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("load media rows: %w", err)
+	}
+	defer rows.Close()
+
+	stale := []staleMediaRow{}
+	for rows.Next() {
+		var row staleMediaRow
+		if err := rows.Scan(&row.ID, &row.Bucket, &row.Path); err != nil {
+			return nil, fmt.Errorf("load media rows: %w", err)
+		}
+		if _, failed := bucketErrors[row.Bucket]; failed {
+			continue
+		}
+		if _, ok := objects[mediaKey(row.Bucket, row.Path)]; !ok {
+			stale = append(stale, row)
+		}
+	}
+	return stale, rows.Err()
+}
+
+// walkStorageBucket visits every object in a bucket, descending into folders.
+func walkStorageBucket(ctx context.Context, storage Storage, bucket string, fn func(storageObject) error) error {
+	return walkStoragePrefix(ctx, storage, bucket, "", fn)
+}
+
+// walkStoragePrefix calls fn for every object under prefix, recursing into
+// sub-folders.
+func walkStoragePrefix(ctx context.Context, storage Storage, bucket, prefix string, fn func(storageObject) error) error {
+	prefixes := []string{prefix}
+	for len(prefixes) > 0 {
+		prefix := prefixes[0]
+		prefixes = prefixes[1:]
+
+		for offset := 0; ; offset += storageWalkPageSize {
+			page, err := storage.List(ctx, bucket, storageListOptions{
+				Prefix:     prefix,
+				Limit:      storageWalkPageSize,
+				Offset:     offset,
+				SortColumn: "name",
+				SortOrder:  "asc",
+			})
+			if err != nil {
+				return err
+			}
+			for _, object := range page {
+				if object.IsFolder {
+					prefixes = append(prefixes, object.Path)
+					continue
+				}
+				// Supabase keeps a placeholder file in otherwise empty folders.
+				if object.Name == ".emptyFolderPlaceholder" {
+					continue
+				}
+				if err := fn(object); err != nil {
+					return err
+				}
+			}
+			if len(page) < storageWalkPageSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (a *App) lastMediaAudit() *mediaAuditReport {
+	a.mediaAudit.mu.RLock()
+	defer a.mediaAudit.mu.RUnlock()
+	return a.mediaAudit.last
+}
+
+// adminMediaAuditHandler serves GET /admin/media/audit. The cached report is
+// returned unless ?refresh=1 is passed or none exists yet.
+func (a *App) adminMediaAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+		return
+	}
+	if _, ok := a.storageOrError(w); !ok {
+		return
+	}
+
+	report := a.lastMediaAudit()
+	if report == nil || isTruthy(r.URL.Query().Get("refresh")) {
+		ctx, cancel := context.WithTimeout(r.Context(), mediaAuditTimeout)
+		defer cancel()
+
+		var err error
+		report, err = a.runMediaAudit(ctx)
+		if err != nil {
+			logFromContext(ctx).Error("media audit failed", "error", err)
+			writeJSON(w, http.StatusBadGateway, map[string]any{
+				"status":  "error",
+				"message": "media audit failed",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   report,
+		"meta": map[string]any{
+			"orphaned": len(report.Orphaned),
+			"broken":   len(report.Broken),
+			"stale":    len(report.StaleMediaRows),
+		},
+	})
+}
+
+type orphanCleanupRequest struct {
+	Items []struct {
+		Bucket string `json:"bucket"`
+		Path   string `json:"path"`
+	} `json:"items"`
+	All    bool `json:"all"`
+	DryRun bool `json:"dry_run"`
+}
+
+// adminMediaOrphanCleanupHandler serves POST /admin/media/orphans/cleanup.
+// Every candidate is re-checked against a fresh audit: still unreferenced and
+// older than the grace period, otherwise it is skipped.
+func (a *App) adminMediaOrphanCleanupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+		return
+	}
+	storage, ok := a.storageOrError(w)
+	if !ok {
+		return
+	}
+
+	var req orphanCleanupRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "invalid JSON body",
+		})
+		return
+	}
+	if !req.All && len(req.Items) == 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"message": "items or all=true is required",
+		})
+		return
+	}
+	if len(req.Items) > maxOrphanCleanupItems {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"message": fmt.Sprintf("at most %d items per request", maxOrphanCleanupItems),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), mediaAuditTimeout)
+	defer cancel()
+
+	report, err := a.runMediaAudit(ctx)
+	if err != nil {
+		logFromContext(ctx).Error("media audit before cleanup failed", "error", err)
+		writeJSON(w, http.StatusBadGateway, map[string]any{
+			"status":  "error",
+			"message": "media audit failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	orphans := make(map[string]mediaOrphan, len(report.Orphaned))
+	for _, orphan := range report.Orphaned {
+		orphans[mediaKey(orphan.Bucket, orphan.Path)] = orphan
+	}
+
+	type skipped struct {
+		Bucket string `json:"bucket"`
+		Path   string `json:"path"`
+		Reason string `json:"reason"`
+	}
+	var (
+		candidates []mediaOrphan
+		skips      = []skipped{}
+	)
+	if req.All {
+		for _, orphan := range report.Orphaned {
+			if orphan.Deletable {
+				candidates = append(candidates, orphan)
+			}
+		}
+		if len(candidates) > maxOrphanCleanupItems {
+			candidates = candidates[:maxOrphanCleanupItems]
+		}
+	} else {
+		for _, item := range req.Items {
+			bucket, bucketErr := cleanStorageBucket(item.Bucket)
+			objectPath, pathErr := cleanStoragePath(item.Path)
+			if bucketErr != nil || pathErr != nil {
+				skips = append(skips, skipped{Bucket: item.Bucket, Path: item.Path, Reason: "invalid bucket or path"})
+				continue
+			}
+			key := mediaKey(bucket, objectPath)
+			orphan, isOrphan := orphans[key]
+			switch {
+			case !isOrphan:
+				reason := "not found"
+				if _, used := report.refs[key]; used {
+					reason = "still referenced"
+				}
+				skips = append(skips, skipped{Bucket: bucket, Path: objectPath, Reason: reason})
+			case !orphan.Deletable:
+				skips = append(skips, skipped{Bucket: bucket, Path: objectPath, Reason: "newer than orphan grace period"})
+			default:
+				candidates = append(candidates, orphan)
+			}
+		}
+	}
+
+	deleted := []mediaOrphan{}
+	var freed int64
+	for _, orphan := range candidates {
+		if !req.DryRun {
+			if err := storage.Delete(ctx, orphan.Bucket, orphan.Path); err != nil && !errors.Is(err, errStorageNotFound) {
+				skips = append(skips, skipped{Bucket: orphan.Bucket, Path: orphan.Path, Reason: err.Error()})
+				continue
+			}
+			if err := a.forgetMedia(ctx, orphan.Bucket, orphan.Path); err != nil {
+				logFromContext(ctx).Error("media row cleanup failed", "bucket", orphan.Bucket, "path", orphan.Path, "error", err)
+			}
+		}
+		deleted = append(deleted, orphan)
+		freed += orphan.Size
+	}
+
+	logFromContext(ctx).Info("orphaned media cleanup",
+		"dry_run", req.DryRun,
+		"deleted", len(deleted),
+		"skipped", len(skips),
+		"freed_bytes", freed,
+	)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"dry_run":     req.DryRun,
+			"deleted":     deleted,
+			"skipped":     skips,
+			"freed_bytes": freed,
+		},
+	})
+}
+
+// mediaUsages returns the content rows referencing bucket/path.
+func (a *App) mediaUsages(ctx context.Context, bucket, objectPath string) ([]mediaReference, error) {
+	refs, err := a.collectMediaReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mediaReferenceIndex(refs)[mediaKey(bucket, objectPath)], nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsNamespace       = "carbon"
+	maxServiceTypeLabels   = 50
+	maxServiceTypeLabelLen = 40
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route pattern, method and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"route", "method", "status"})
+
+	storageRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "storage_requests_total",
+		Help:      "Storage backend calls by operation and outcome (ok, error, not_found, conflict, http_4xx, http_5xx).",
+	}, []string{"operation", "outcome"})
+
+	storageRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "storage_request_duration_seconds",
+		Help:      "Storage backend call latency by operation.",
+		Buckets:   []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+
+	notificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "notifications_total",
+		Help:      "Manager notification deliveries by channel and outcome.",
+	}, []string{"channel", "outcome"})
+
+	consultationSubmissionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "consultation_submissions_total",
+		Help:      "Consultation form submissions by service type and outcome.",
+	}, []string{"service_type", "outcome"})
+
+	imageCacheRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "image_cache_requests_total",
+		Help:      "Image proxy cache lookups by result (hit, miss).",
+	}, []string{"result"})
+
+	imageCacheBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "image_cache_bytes",
+		Help:      "Bytes currently held in the image proxy disk cache.",
+	})
+
+	responseCacheRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "response_cache_requests_total",
+		Help:      "Public response cache lookups by route and result (hit, miss).",
+	}, []string{"route", "result"})
+
+	responseCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "response_cache_entries",
+		Help:      "Responses currently held in the public response cache.",
+	})
+
+	responseCacheInvalidatedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "response_cache_invalidated_total",
+		Help:      "Public response cache entries dropped because their tables changed.",
+	})
+
+	serviceTypeLabels = boundedLabelSet{max: maxServiceTypeLabels, values: map[string]struct{}{}}
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		storageRequestsTotal,
+		storageRequestDuration,
+		notificationsTotal,
+		consultationSubmissionsTotal,
+		imageCacheRequestsTotal,
+		imageCacheBytes,
+		responseCacheRequestsTotal,
+		responseCacheEntries,
+		responseCacheInvalidatedTotal,
+	)
+}
+
+// registerDBMetrics exposes sql.DB pool statistics.
+func registerDBMetrics(db *sql.DB) {
+	metricsRegistry.MustRegister(collectors.NewDBStatsCollector(db, "main"))
+}
+
+// metricsMiddleware records request counts and latency. It must wrap the mux
+// directly (without cloning the request) so r.Pattern is visible afterwards.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+			if r.Method == http.MethodOptions {
+				route = "preflight"
+			}
+		}
+		status := strconv.Itoa(rec.statusCode())
+		httpRequestsTotal.WithLabelValues(route, r.Method, status).Inc()
+		httpRequestDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// doStorageRequest sends an outbound storage API call and records its outcome.
+func doStorageRequest(client *http.Client, req *http.Request, operation string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := tracedHTTPDo(client, req, "storage "+operation)
+
+	outcome := "ok"
+	switch {
+	case err != nil:
+		outcome = "error"
+	case resp.StatusCode >= 500:
+		outcome = "http_5xx"
+	case resp.StatusCode >= 400:
+		outcome = "http_4xx"
+	}
+	recordStorageRequest(operation, time.Since(start), outcome)
+	return resp, err
+}
+
+func recordStorageRequest(operation string, elapsed time.Duration, outcome string) {
+	storageRequestDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+	storageRequestsTotal.WithLabelValues(operation, outcome).Inc()
+}
+
+func recordNotification(channel, outcome string) {
+	notificationsTotal.WithLabelValues(channel, outcome).Inc()
+}
+
+func recordConsultationSubmission(serviceType, outcome string) {
+	consultationSubmissionsTotal.WithLabelValues(serviceTypeLabels.label(serviceType), outcome).Inc()
+}
+
+// boundedLabelSet caps the number of distinct values of a user-supplied label
+// so a bot cannot blow up metric cardinality.
+type boundedLabelSet struct {
+	mu     sync.Mutex
+	max    int
+	values map[string]struct{}
+}
+
+func (b *boundedLabelSet) label(raw string) string {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	if value == "" {
+		return "unknown"
+	}
+	if runes := []rune(value); len(runes) > maxServiceTypeLabelLen {
+		value = string(runes[:maxServiceTypeLabelLen])
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.values[value]; ok {
+		return value
+	}
+	if len(b.values) >= b.max {
+		return "other"
+	}
+	b.values[value] = struct{}{}
+	return value
+}
+
+type metricsConfig struct {
+	Token string
+	Addr  string
+}
+
+func loadMetricsConfig() metricsConfig {
+	return metricsConfig{
+		Token: strings.TrimSpace(os.Getenv("METRICS_TOKEN")),
+		Addr:  strings.TrimSpace(os.Getenv("METRICS_ADDR")),
+	}
+}
+
+func (c metricsConfig) enabled() bool {
+	return c.Token != "" || c.Addr != ""
+}
+
+// metricsHandler serves the Prometheus registry. When a token is configured
+// it is required as `Authorization: Bearer <METRICS_TOKEN>`.
+func metricsHandler(cfg metricsConfig) http.Handler {
+	handler := promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+	if cfg.Token == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimSpace(r.Header.Get("Authorization"))
+		provided := ""
+		if len(auth) >= 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			provided = strings.TrimSpace(auth[7:])
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(cfg.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// startMetricsServer serves /metrics on METRICS_ADDR (e.g. 127.0.0.1:9090),
+// keeping it off the public listener.
+func startMetricsServer(cfg metricsConfig) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(cfg))
+	server := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("metrics server error", "addr", cfg.Addr, "error", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	homePageName    = "home"
+	pagesRoute      = "/api/pages"
+	homePageRoute   = "/api/home"
+	maxPageSections = 20
+)
+
+// pageSource is a public endpoint a page section can be built from. With a
+// limit, Paginated sources are asked for ?limit= (and answer with their
+// {"data","pagination"} form); the others are cut to the first items.
+type pageSource struct {
+	Route     string
+	Paginated bool
+	Handler   func(*App, http.ResponseWriter, *http.Request)
+}
+
+var pageSources = map[string]pageSource{
+	"banners":           {Route: "/banners", Handler: (*App).bannersHandler},
+	"partners":          {Route: "/partners", Handler: (*App).partnersHandler},
+	"service_offerings": {Route: "/service_offerings", Paginated: true, Handler: (*App).serviceOfferingsHandler},
+	"tuning":            {Route: "/tuning", Paginated: true, Handler: (*App).tuningHandler},
+	"portfolio_items":   {Route: "/portfolio_items", Paginated: true, Handler: (*App).portfolioItemsHandler},
+	"work_post":         {Route: "/work_post", Paginated: true, Handler: (*App).workPostHandler},
+	"contact":           {Route: "/contact", Handler: (*App).contactHandler},
+	"about":             {Route: "/about", Handler: (*App).aboutHandler},
+	"privacy_sections":  {Route: "/privacy_sections", Handler: (*App).privacySectionsHandler},
+}
+
+// pageSection configures one section of a composite page. Params are passed
+// to the source as query parameters (filters, sort); Fields keeps only those
+// top-level fields of each item.
+type pageSection struct {
+	Limit  int               `json:"limit,omitempty"`
+	Fields []string          `json:"fields,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// pageComposite maps section names (keys of pageSources) to their settings.
+type pageComposite map[string]pageSection
+
+// defaultHomePage is what the mobile app loads on launch.
+var defaultHomePage = pageComposite{
+	"banners":           {},
+	"partners":          {},
+	"service_offerings": {},
+	"tuning":            {Limit: 10},
+	"portfolio_items":   {Limit: 10},
+	"contact":           {},
+}
+
+// loadPageComposites reads PAGE_COMPOSITES, a JSON object of page name to
+// pageComposite, on top of the built-in "home" page.
+func loadPageComposites() (map[string]pageComposite, error) {
+	pages := map[string]pageComposite{homePageName: defaultHomePage}
+	raw := strings.TrimSpace(os.Getenv("PAGE_COMPOSITES"))
+	if raw == "" {
+		return pages, nil
+	}
+
+	var configured map[string]pageComposite
+	if err := json.Unmarshal([]byte(raw), &configured); err != nil {
+		return nil, fmt.Errorf("PAGE_COMPOSITES must be a JSON object of pages: %w", err)
+	}
+	for name, page := range configured {
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("PAGE_COMPOSITES: invalid page name %q", name)
+		}
+		if len(page) == 0 || len(page) > maxPageSections {
+			return nil, fmt.Errorf("PAGE_COMPOSITES: page %q needs 1 to %d sections", name, maxPageSections)
+		}
+		for section, cfg := range page {
+			if _, ok := pageSources[section]; !ok {
+				return nil, fmt.Errorf("PAGE_COMPOSITES: page %q has unknown section %q", name, section)
+			}
+			if cfg.Limit < 0 || cfg.Limit > maxPublicListLimit {
+				return nil, fmt.Errorf("PAGE_COMPOSITES: %s.%s limit must be between 0 and %d", name, section, maxPublicListLimit)
+			}
+		}
+		pages[name] = page
+	}
+	return pages, nil
+}
+
+// forRequest applies ?sections=a,b, ?<section>.limit= and ?<section>.fields=
+// to a copy of the page.
+func (p pageComposite) forRequest(values url.Values) (pageComposite, error) {
+	out := pageComposite{}
+	if raw := strings.TrimSpace(values.Get("sections")); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			section, ok := p[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown section %q", name)
+			}
+			out[name] = section
+		}
+	} else {
+		for name, section := range p {
+			out[name] = section
+		}
+	}
+
+	for name, section := range out {
+		if raw := strings.TrimSpace(values.Get(name + ".limit")); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit <= 0 || limit > maxPublicListLimit {
+				return nil, fmt.Errorf("%s.limit must be between 1 and %d", name, maxPublicListLimit)
+			}
+			section.Limit = limit
+		}
+		if raw := strings.TrimSpace(values.Get(name + ".fields")); raw != "" {
+			section.Fields = nil
+			for _, field := range strings.Split(raw, ",") {
+				if field = strings.TrimSpace(field); field != "" {
+					section.Fields = append(section.Fields, field)
+				}
+			}
+		}
+		out[name] = section
+	}
+	return out, nil
+}
+
+// pageHandler serves GET /api/home and GET /api/pages/{name}: every section
+// of the page is fetched concurrently from its public endpoint and returned
+// in one document keyed by section name. A failed section is null and listed
+// under "errors".
+func (a *App) pageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := homePageName
+	if r.URL.Path != homePageRoute {
+		name = strings.Trim(strings.TrimPrefix(r.URL.Path, pagesRoute), "/")
+	}
+	page, ok := a.Pages[name]
+	if !ok {
+		http.Error(w, "page not found", http.StatusNotFound)
+		return
+	}
+	sections, err := page.forRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		out  = make(map[string]any, len(sections)+1)
+		errs = map[string]string{}
+	)
+	for sectionName, section := range sections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := a.renderPageSection(ctx, r, pageSources[sectionName], section)
+			mu.Lock()
+			defer mu.Unlock()
+			out[sectionName] = value
+			if err != nil {
+				logFromContext(ctx).Error("page section failed", "page", name, "section", sectionName, "error", err)
+				errs[sectionName] = "failed to fetch " + sectionName
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		out["errors"] = errs
+	}
+	requestLocales(r).setHeaders(w)
+	writeJSON(w, http.StatusOK, out)
+}
+
+// renderPageSection runs the source's handler (through its response cache)
+// for an internal GET built from the section and the caller's locale.
+func (a *App) renderPageSection(ctx context.Context, r *http.Request, source pageSource, section pageSection) (any, error) {
+	query := url.Values{}
+	for key, value := range section.Params {
+		query.Set(key, value)
+	}
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		query.Set("lang", lang)
+	}
+	if section.Limit > 0 && source.Paginated {
+		query.Set("limit", strconv.Itoa(section.Limit))
+	}
+
+	sub := r.Clone(ctx)
+	sub.URL = &url.URL{Path: source.Route, RawQuery: query.Encode()}
+	sub.RequestURI = sub.URL.RequestURI()
+	sub.Header.Del("If-None-Match")
+	sub.Header.Del("If-Modified-Since")
+
+	rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+	a.httpCached(source.Route, func(w http.ResponseWriter, r *http.Request) {
+		source.Handler(a, w, r)
+	})(rec, sub)
+	if rec.status != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %d: %s", source.Route, rec.status, strings.TrimSpace(rec.body.String()))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(rec.body.Bytes()))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", source.Route, err)
+	}
+
+	if items, ok := value.([]any); ok && section.Limit > 0 && len(items) > section.Limit {
+		value = items[:section.Limit]
+	}
+	if len(section.Fields) > 0 {
+		value = selectPageFields(value, section.Fields)
+	}
+	return value, nil
+}
+
+// selectPageFields keeps only fields of an object, of each object in a list,
+// or of each item in a paginated {"data": [...]} document.
+func selectPageFields(value any, fields []string) any {
+	switch typed := value.(type) {
+	case []any:
+		for i, item := range typed {
+			typed[i] = selectPageFields(item, fields)
+		}
+		return typed
+	case map[string]any:
+		if data, ok := typed["data"].([]any); ok {
+			if _, paginated := typed["pagination"]; paginated {
+				typed["data"] = selectPageFields(data, fields)
+				return typed
+			}
+		}
+		for key := range typed {
+			if !slices.Contains(fields, key) {
+				delete(typed, key)
+			}
+		}
+		return typed
+	}
+	return value
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPublicListLimit = 20
+	maxPublicListLimit     = 100
+)
+
+// publicListSpec describes what a public list endpoint can be filtered and
+// sorted by. Column names refer to the output of the endpoint's SELECT, which
+// is wrapped as a subquery "q", so every schema fallback shares one spec.
+type publicListSpec struct {
+	// Sorts maps a sort key to the SQL type of its column; keys must be
+	// NOT NULL columns so keyset cursors stay stable.
+	Sorts       map[string]string
+	DefaultSort string
+	// Equals filters match case-insensitively; "a,b" matches either.
+	Equals map[string]string
+	// Contains filters are case-insensitive substring matches.
+	Contains map[string]string
+	// CreatedAt enables created_from/created_to on q.created_at.
+	CreatedAt bool
+}
+
+var (
+	tuningListSpec = publicListSpec{
+		Sorts:       map[string]string{"created_at": "timestamptz", "updated_at": "timestamptz", "id": "bigint"},
+		DefaultSort: "-created_at",
+		Equals:      map[string]string{"brand": "brand"},
+		Contains:    map[string]string{"model": "model"},
+		CreatedAt:   true,
+	}
+	portfolioListSpec = publicListSpec{
+		Sorts:       map[string]string{"created_at": "timestamptz", "id": "bigint"},
+		DefaultSort: "-created_at",
+		Equals:      map[string]string{"brand": "brand"},
+		Contains:    map[string]string{"model": "title"},
+		CreatedAt:   true,
+	}
+	workPostListSpec = publicListSpec{
+		Sorts:       map[string]string{"created_at": "timestamptz", "updated_at": "timestamptz", "id": "bigint"},
+		DefaultSort: "-created_at",
+		Contains:    map[string]string{"model": "title_model"},
+		CreatedAt:   true,
+	}
+	serviceOfferingsListSpec = publicListSpec{
+		Sorts:       map[string]string{"position": "bigint", "created_at": "timestamptz", "updated_at": "timestamptz", "id": "bigint"},
+		DefaultSort: "position",
+		Equals:      map[string]string{"service_type": "service_type"},
+		CreatedAt:   true,
+	}
+)
+
+// publicListQuery is a parsed ?limit=&cursor=&sort=&<filters>. Without limit
+// or cursor the endpoint keeps answering with the plain array it always
+// returned; filters and sort apply either way. Single marks the
+// GET {base}/{slug} form, which answers with one object.
+type publicListQuery struct {
+	Single    bool
+	Paginated bool
+	Limit     int
+	SortKey   string
+	SortType  string
+	Desc      bool
+	Cursor    *publicListCursor
+
+	where []string
+	args  []any
+}
+
+// publicListCursor points after the last row of a page: its sort value and
+// id, plus the sort it belongs to.
+type publicListCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+func parsePublicListQuery(values url.Values, spec publicListSpec) (publicListQuery, error) {
+	q := publicListQuery{Limit: defaultPublicListLimit}
+
+	sort := firstNonEmpty(strings.TrimSpace(values.Get("sort")), spec.DefaultSort)
+	q.Desc = strings.HasPrefix(sort, "-")
+	q.SortKey = strings.TrimPrefix(sort, "-")
+	sortType, ok := spec.Sorts[q.SortKey]
+	if !ok {
+		return publicListQuery{}, fmt.Errorf("unsupported sort %q", sort)
+	}
+	q.SortType = sortType
+
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return publicListQuery{}, errors.New("limit must be a positive integer")
+		}
+		q.Limit = min(limit, maxPublicListLimit)
+		q.Paginated = true
+	}
+	if raw := strings.TrimSpace(values.Get("cursor")); raw != "" {
+		cursor, err := decodePublicListCursor(raw)
+		if err != nil || cursor.Sort != sort || !validCursorValue(cursor.Value, sortType) {
+			return publicListQuery{}, errors.New("invalid cursor")
+		}
+		q.Cursor = &cursor
+		q.Paginated = true
+	}
+
+	// Sorted so the same filters always produce the same SQL text.
+	for _, param := range slices.Sorted(maps.Keys(spec.Equals)) {
+		column := spec.Equals[param]
+		raw := strings.TrimSpace(values.Get(param))
+		if raw == "" {
+			continue
+		}
+		var options []string
+		for _, option := range strings.Split(raw, ",") {
+			if option = strings.ToLower(strings.TrimSpace(option)); option != "" {
+				options = append(options, option)
+			}
+		}
+		if len(options) > 0 {
+			q.addFilter("lower(q."+quoteIdentifier(column)+") = ANY(%s)", options)
+		}
+	}
+	for _, param := range slices.Sorted(maps.Keys(spec.Contains)) {
+		column := spec.Contains[param]
+		if raw := strings.TrimSpace(values.Get(param)); raw != "" {
+			q.addFilter("q."+quoteIdentifier(column)+` ILIKE '%%' || %s || '%%'`, escapeLikePattern(raw))
+		}
+	}
+	if spec.CreatedAt {
+		if raw := strings.TrimSpace(values.Get("created_from")); raw != "" {
+			from, _, err := parseListDate(raw)
+			if err != nil {
+				return publicListQuery{}, fmt.Errorf("created_from: %w", err)
+			}
+			q.addFilter("q.created_at >= %s", from)
+		}
+		if raw := strings.TrimSpace(values.Get("created_to")); raw != "" {
+			to, dateOnly, err := parseListDate(raw)
+			if err != nil {
+				return publicListQuery{}, fmt.Errorf("created_to: %w", err)
+			}
+			// A bare date includes that whole day.
+			if dateOnly {
+				q.addFilter("q.created_at < %s", to.AddDate(0, 0, 1))
+			} else {
+				q.addFilter("q.created_at <= %s", to)
+			}
+		}
+	}
+	return q, nil
+}
+
+// publicItemQuery selects the row with the given id through the same SELECT
+// the list uses.
+func publicItemQuery(id int64) publicListQuery {
+	q := publicListQuery{Single: true, SortKey: "id", SortType: "bigint"}
+	q.addFilter("q.id = %s", id)
+	return q
+}
+
+func (q *publicListQuery) addFilter(format string, arg any) {
+	q.args = append(q.args, arg)
+	q.where = append(q.where, fmt.Sprintf(format, "$"+strconv.Itoa(len(q.args))))
+}
+
+// wrap turns an endpoint's base SELECT into the filtered, sorted and (when
+// paginated) limited query. Two columns are appended to every row:
+// list_cursor (the sort value as text) and list_total (rows matching the
+// filters, ignoring the cursor).
+func (q publicListQuery) wrap(base string) (string, []any) {
+	args := append([]any(nil), q.args...)
+	column := "q." + quoteIdentifier(q.SortKey)
+
+	cursorExpr := column + "::text"
+	if q.SortType == "timestamptz" {
+		cursorExpr = "to_char(" + column + ` AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')`
+	}
+	filters := "TRUE"
+	if len(q.where) > 0 {
+		filters = strings.Join(q.where, " AND ")
+	}
+
+	direction, compare := "ASC", ">"
+	if q.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	query := `SELECT * FROM (
+		SELECT q.*, ` + cursorExpr + ` AS list_cursor, COUNT(*) OVER () AS list_total
+		FROM (` + base + `) q
+		WHERE ` + filters + `
+	) q`
+	if q.Cursor != nil {
+		args = append(args, q.Cursor.Value, q.Cursor.ID)
+		query += fmt.Sprintf(` WHERE (%s, q.id) %s ($%d::%s, $%d::bigint)`, column, compare, len(args)-1, q.SortType, len(args))
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, q.id %s`, column, direction, direction)
+	if q.Paginated {
+		query += fmt.Sprintf(` LIMIT %d`, q.Limit+1)
+	}
+	return query, args
+}
+
+// publicListPage collects the bookkeeping columns while a handler scans rows.
+type publicListPage struct {
+	query   publicListQuery
+	ids     []int64
+	cursors []string
+	total   int64
+}
+
+func newPublicListPage(query publicListQuery) *publicListPage {
+	return &publicListPage{query: query}
+}
+
+// add records one scanned row; cursor and total are the list_cursor and
+// list_total columns.
+func (p *publicListPage) add(id int64, cursor string, total int64) {
+	p.ids = append(p.ids, id)
+	p.cursors = append(p.cursors, cursor)
+	p.total = total
+}
+
+// size is how many of the scanned rows belong on this page; the query
+// fetches one extra row to learn whether another page exists.
+func (p *publicListPage) size() int {
+	if !p.query.Paginated {
+		return len(p.ids)
+	}
+	return min(len(p.ids), p.query.Limit)
+}
+
+// writePublicList answers with the legacy array, with data plus pagination
+// when the client asked for a page, or with the one item of a single-item
+// query. items must already be cut to size().
+func writePublicList[T any](w http.ResponseWriter, p *publicListPage, items []T) {
+	if p.query.Single {
+		if len(items) == 0 {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, items[0])
+		return
+	}
+	if !p.query.Paginated {
+		writeJSON(w, http.StatusOK, items)
+		return
+	}
+
+	pagination := map[string]any{
+		"limit":       p.query.Limit,
+		"total":       p.total,
+		"has_more":    false,
+		"next_cursor": nil,
+	}
+	if len(p.ids) > p.query.Limit {
+		last := p.query.Limit - 1
+		sort := p.query.SortKey
+		if p.query.Desc {
+			sort = "-" + sort
+		}
+		pagination["has_more"] = true
+		pagination["next_cursor"] = encodePublicListCursor(publicListCursor{Sort: sort, Value: p.cursors[last], ID: p.ids[last]})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":       items,
+		"pagination": pagination,
+	})
+}
+
+func encodePublicListCursor(cursor publicListCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePublicListCursor(value string) (publicListCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return publicListCursor{}, err
+	}
+	var cursor publicListCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return publicListCursor{}, err
+	}
+	return cursor, nil
+}
+
+// validCursorValue keeps malformed cursors from reaching the SQL casts.
+func validCursorValue(value, sqlType string) bool {
+	if sqlType == "timestamptz" {
+		_, err := time.Parse(time.RFC3339Nano, value)
+		return err == nil
+	}
+	_, err := strconv.ParseInt(value, 10, 64)
+	return err == nil
+}
+
+// parseListDate accepts RFC 3339 or YYYY-MM-DD and reports which it was.
+func parseListDate(value string) (time.Time, bool, error) {
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.New("expected YYYY-MM-DD or RFC 3339 time")
+	}
+	return parsed, false, nil
+}
+
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
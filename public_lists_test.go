@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+)
+
+func TestPublicListCursorRoundTrip(t *testing.T) {
+	cursors := []publicListCursor{
+		{Sort: "-created_at", Value: "2024-05-01T10:20:30.123456Z", ID: 42},
+		{Sort: "position", Value: "7", ID: 1},
+		{Sort: "id", Value: "-3", ID: 9223372036854775807},
+	}
+	for _, cursor := range cursors {
+		encoded := encodePublicListCursor(cursor)
+		decoded, err := decodePublicListCursor(encoded)
+		if err != nil {
+			t.Fatalf("decode(%q): %v", encoded, err)
+		}
+		if decoded != cursor {
+			t.Errorf("round trip of %+v gave %+v", cursor, decoded)
+		}
+	}
+}
+
+func TestParsePublicListQueryCursor(t *testing.T) {
+	valid := encodePublicListCursor(publicListCursor{Sort: "-created_at", Value: "2024-05-01T10:20:30Z", ID: 42})
+	raw := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+	tests := []struct {
+		name    string
+		values  url.Values
+		wantErr bool
+	}{
+		{name: "valid", values: url.Values{"cursor": {valid}}},
+		{name: "valid with matching sort", values: url.Values{"cursor": {valid}, "sort": {"-created_at"}}},
+		{name: "other sort", values: url.Values{"cursor": {valid}, "sort": {"created_at"}}, wantErr: true},
+		{name: "not base64", values: url.Values{"cursor": {"!!not-base64!!"}}, wantErr: true},
+		{name: "padded base64", values: url.Values{"cursor": {valid + "=="}}, wantErr: true},
+		{name: "not json", values: url.Values{"cursor": {raw("created_at,42")}}, wantErr: true},
+		{name: "sort rewritten", values: url.Values{"cursor": {raw(`{"s":"-updated_at","v":"2024-05-01T10:20:30Z","id":42}`)}}, wantErr: true},
+		{name: "value injection", values: url.Values{"cursor": {raw(`{"s":"-created_at","v":"2024-05-01'; DROP TABLE tuning; --","id":42}`)}}, wantErr: true},
+		{name: "date only value", values: url.Values{"cursor": {raw(`{"s":"-created_at","v":"2024-05-01","id":42}`)}}, wantErr: true},
+		{name: "id as string", values: url.Values{"cursor": {raw(`{"s":"-created_at","v":"2024-05-01T10:20:30Z","id":"42"}`)}}, wantErr: true},
+		{name: "non-numeric value for id sort", values: url.Values{"sort": {"id"}, "cursor": {raw(`{"s":"id","v":"1 OR 1=1","id":1}`)}}, wantErr: true},
+		{name: "numeric value for id sort", values: url.Values{"sort": {"id"}, "cursor": {raw(`{"s":"id","v":"17","id":17}`)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parsePublicListQuery(tt.values, tuningListSpec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("cursor accepted: %+v", q.Cursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.Cursor == nil || !q.Paginated {
+				t.Fatalf("cursor not applied: %+v", q)
+			}
+		})
+	}
+}
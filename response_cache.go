@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultResponseCacheTTL        = 5 * time.Minute
+	defaultResponseCacheMaxEntries = 2000
+
+	// contentChangesChannel is the NOTIFY channel of touch_content_change();
+	// the payload is the changed table name.
+	contentChangesChannel = "content_changes"
+
+	minListenerBackoff = 2 * time.Second
+	maxListenerBackoff = time.Minute
+)
+
+// cachedResponse is a rendered public GET response with its validators.
+type cachedResponse struct {
+	Status       int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+}
+
+type responseCacheEntry struct {
+	response cachedResponse
+	tables   []string
+	expires  time.Time
+}
+
+// responseCache keeps successful public GET responses in memory, keyed by
+// route, URL and resolved locale. Entries are dropped when a table they were
+// built from changes: directly after admin writes, and on every instance via
+// NOTIFY content_changes from the table triggers.
+type responseCache struct {
+	ttl        time.Duration
+	routeTTL   map[string]time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*responseCacheEntry
+	// generation counts invalidations, so a response rendered while one
+	// happened is not stored with data from before it.
+	generation uint64
+}
+
+// loadResponseCache reads RESPONSE_CACHE_* from env; it returns nil (cache
+// off) when RESPONSE_CACHE_TTL is 0. RESPONSE_CACHE_ROUTES overrides the TTL
+// per route as ";"-separated route=duration pairs, 0 turning a route off.
+func loadResponseCache() (*responseCache, error) {
+	ttl := defaultResponseCacheTTL
+	if raw := strings.TrimSpace(os.Getenv("RESPONSE_CACHE_TTL")); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			return nil, errors.New("RESPONSE_CACHE_TTL must be a non-negative duration")
+		}
+		ttl = parsed
+	}
+	if ttl == 0 {
+		return nil, nil
+	}
+
+	maxEntries, err := parseIntOrDefault(os.Getenv("RESPONSE_CACHE_MAX_ENTRIES"), defaultResponseCacheMaxEntries)
+	if err != nil || maxEntries <= 0 {
+		return nil, errors.New("RESPONSE_CACHE_MAX_ENTRIES must be a positive integer")
+	}
+
+	routeTTL := map[string]time.Duration{}
+	for _, entry := range strings.Split(os.Getenv("RESPONSE_CACHE_ROUTES"), ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		route, value, _ := strings.Cut(entry, "=")
+		route = strings.TrimSpace(route)
+		if _, ok := cachedRouteTables[route]; !ok || uncachedResponseRoutes[route] {
+			return nil, errors.New("RESPONSE_CACHE_ROUTES: " + route + " is not a cacheable route")
+		}
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || parsed < 0 {
+			return nil, errors.New("RESPONSE_CACHE_ROUTES: " + route + " needs a non-negative duration")
+		}
+		routeTTL[route] = parsed
+	}
+
+	return &responseCache{
+		ttl:        ttl,
+		routeTTL:   routeTTL,
+		maxEntries: maxEntries,
+		entries:    map[string]*responseCacheEntry{},
+	}, nil
+}
+
+func (c *responseCache) routeTTLOrDefault(route string) time.Duration {
+	if uncachedResponseRoutes[route] {
+		return 0
+	}
+	if ttl, ok := c.routeTTL[route]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+// uncachedResponseRoutes never go through the response cache: every free
+// text query would be its own entry and push out the hot pages.
+var uncachedResponseRoutes = map[string]bool{"/search": true}
+
+// cachedListSpecs are the list specs of the cached list routes, whose query
+// parameters are part of the cache key.
+var cachedListSpecs = map[string]publicListSpec{
+	"/tuning":            tuningListSpec,
+	"/portfolio_items":   portfolioListSpec,
+	"/work_post":         workPostListSpec,
+	"/service_offerings": serviceOfferingsListSpec,
+}
+
+// responseCacheKey identifies what route's handler writes for r: the path,
+// the first value of each query parameter the handler reads (sorted, any
+// other parameter dropped) and the locale chain from ?lang= and
+// Accept-Language. Junk parameters therefore share one entry.
+func responseCacheKey(route string, r *http.Request) string {
+	kept := url.Values{}
+	for name, values := range r.URL.Query() {
+		if responseCacheParam(route, name) {
+			kept.Set(name, values[0])
+		}
+	}
+	return r.URL.Path + "?" + kept.Encode() + "\n" + strings.Join(requestLocales(r), ",")
+}
+
+// responseCacheParam reports whether route's handler reads the query
+// parameter name. The key also feeds stamp ETags, so uncached routes list
+// their parameters too.
+func responseCacheParam(route, name string) bool {
+	if route == "/search" {
+		return name == "q" || name == "type" || name == "limit"
+	}
+	if spec, ok := cachedListSpecs[route]; ok {
+		switch name {
+		case "sort", "limit", "cursor":
+			return true
+		case "created_from", "created_to":
+			return spec.CreatedAt
+		}
+		_, equals := spec.Equals[name]
+		_, contains := spec.Contains[name]
+		return equals || contains
+	}
+	if route == homePageRoute || route == pagesRoute {
+		if name == "sections" {
+			return true
+		}
+		section, ok := strings.CutSuffix(name, ".limit")
+		if !ok {
+			section, ok = strings.CutSuffix(name, ".fields")
+		}
+		_, known := pageSources[section]
+		return ok && known
+	}
+	return false
+}
+
+func (c *responseCache) get(route, key string) (cachedResponse, bool) {
+	if c == nil || c.routeTTLOrDefault(route) == 0 {
+		return cachedResponse{}, false
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[route+"\x00"+key]
+	if ok && time.Now().After(entry.expires) {
+		delete(c.entries, route+"\x00"+key)
+		ok = false
+	}
+	size := len(c.entries)
+	c.mu.Unlock()
+
+	responseCacheEntries.Set(float64(size))
+	if !ok {
+		responseCacheRequestsTotal.WithLabelValues(route, "miss").Inc()
+		return cachedResponse{}, false
+	}
+	responseCacheRequestsTotal.WithLabelValues(route, "hit").Inc()
+	return entry.response, true
+}
+
+func (c *responseCache) currentGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// put stores response unless an invalidation ran since generation was read.
+// A full cache first drops expired entries, then arbitrary ones.
+func (c *responseCache) put(route, key string, response cachedResponse, generation uint64) {
+	if c == nil {
+		return
+	}
+	ttl := c.routeTTLOrDefault(route)
+	if ttl == 0 {
+		return
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	if len(c.entries) >= c.maxEntries {
+		for entryKey, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, entryKey)
+			}
+		}
+		for entryKey := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, entryKey)
+		}
+	}
+	c.entries[route+"\x00"+key] = &responseCacheEntry{
+		response: response,
+		tables:   cachedRouteTables[route],
+		expires:  now.Add(ttl),
+	}
+	responseCacheEntries.Set(float64(len(c.entries)))
+}
+
+// invalidate drops the responses built from any of tables ("tuning" or
+// "public.tuning"); with no tables it drops everything.
+func (c *responseCache) invalidate(tables ...string) {
+	if c == nil {
+		return
+	}
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		names = append(names, strings.TrimPrefix(table, "public."))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	removed := 0
+	for key, entry := range c.entries {
+		if len(names) == 0 || slices.ContainsFunc(entry.tables, func(table string) bool {
+			return slices.Contains(names, table)
+		}) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	responseCacheEntries.Set(float64(len(c.entries)))
+	responseCacheInvalidatedTotal.Add(float64(removed))
+}
+
+// runResponseCacheListener LISTENs on content_changes over a dedicated
+// connection and invalidates the changed tables. Notifications sent while
+// disconnected are lost, so every (re)connect flushes the whole cache.
+func (a *App) runResponseCacheListener(ctx context.Context, dsn string) {
+	if a.Responses == nil {
+		return
+	}
+	dsn, err := responseCacheListenDSN(dsn)
+	if err != nil {
+		logFromContext(ctx).Error("response cache listener disabled; changes made through other instances only show after RESPONSE_CACHE_TTL", "error", err)
+		return
+	}
+	backoff := minListenerBackoff
+	for {
+		started := time.Now()
+		err := a.listenContentChanges(ctx, dsn)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxListenerBackoff {
+			backoff = minListenerBackoff
+		}
+		logFromContext(ctx).Warn("response cache listener disconnected", "error", err, "retry_in", backoff.String())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxListenerBackoff)
+	}
+}
+
+// responseCacheListenDSN returns RESPONSE_CACHE_LISTEN_DSN, or the app DSN
+// when it is a session connection. LISTEN needs the session to stay on one
+// server connection, which a transaction pooler (pgbouncer=true, Supabase's
+// port 6543) does not guarantee.
+func responseCacheListenDSN(dsn string) (string, error) {
+	if listenDSN := strings.TrimSpace(os.Getenv("RESPONSE_CACHE_LISTEN_DSN")); listenDSN != "" {
+		return listenDSN, nil
+	}
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return "", err
+	}
+	if config.Port == 6543 || strings.EqualFold(config.RuntimeParams["pgbouncer"], "true") {
+		return "", errors.New("the database DSN goes through a transaction pooler, which cannot LISTEN; set RESPONSE_CACHE_LISTEN_DSN to a direct or session-mode connection (Supabase: port 5432)")
+	}
+	return dsn, nil
+}
+
+func (a *App) listenContentChanges(ctx context.Context, dsn string) error {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return err
+	}
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+contentChangesChannel); err != nil {
+		return err
+	}
+	a.Responses.invalidate()
+	logFromContext(ctx).Info("response cache listening for content changes")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		a.Responses.invalidate(notification.Payload)
+	}
+}
@@ -2,22 +2,36 @@
 -- Run this file once in a clean DB (or repeatedly; all CREATE statements are idempotent).
 -- Active API routes in main.go:
 -- /healthz
+-- /livez
+-- /readyz
 -- /contact
 -- /about
 -- /banners
 -- /partners
--- /tuning
--- /service_offerings
+-- /tuning, /tuning/{id-or-slug}
+-- /service_offerings, /service_offerings/{slug}
 -- /privacy_sections
 -- /api/consultations
--- /portfolio_items
--- /work_post
+-- /portfolio_items, /portfolio_items/{slug}
+-- /work_post, /work_post/{slug}
+-- /search
+-- /api/home, /api/pages/{name}
+-- /api/v1/* (the public routes above in the v1 envelope)
+-- /img/{bucket}/{path}
 
 BEGIN;
 
 -- Optional but explicit.
 CREATE SCHEMA IF NOT EXISTS public;
 
+-- Applied schema versions, reported by GET /healthz?verbose=1.
+-- Bump by appending a row at the end of this file whenever the schema changes.
+CREATE TABLE IF NOT EXISTS public.schema_migrations (
+    version INTEGER PRIMARY KEY,
+    description TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
 -- 2. Banners (used by GET /banners)
 CREATE TABLE IF NOT EXISTS public.banners (
     id SERIAL PRIMARY KEY,
@@ -86,6 +100,26 @@ CREATE TABLE IF NOT EXISTS public.tuning (
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- 17. Resumable upload sessions (/admin/storage/uploads). Chunks are spooled to
+-- UPLOAD_SESSION_DIR on the API host; rows track the confirmed offset.
+CREATE TABLE IF NOT EXISTS public.upload_sessions (
+    id TEXT PRIMARY KEY,
+    bucket TEXT NOT NULL,
+    folder TEXT NOT NULL DEFAULT '',
+    filename TEXT NOT NULL,
+    total_size BIGINT NOT NULL CHECK (total_size > 0),
+    received_size BIGINT NOT NULL DEFAULT 0,
+    checksum_sha256 TEXT,
+    upsert BOOLEAN NOT NULL DEFAULT TRUE,
+    process BOOLEAN NOT NULL DEFAULT TRUE,
+    alt_text TEXT,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    uploaded_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
 -- Ensure compatibility for already existing databases.
 -- This block safely creates/converts full_image_url to JSONB array.
 DO $$
@@ -208,10 +242,25 @@ CREATE TABLE IF NOT EXISTS public.consultations (
     preferred_call_time TEXT,
     comments TEXT,
     status TEXT NOT NULL DEFAULT 'new'
-        CHECK (status IN ('new', 'in_progress', 'completed')),
+        CHECK (status IN ('new', 'in_progress', 'completed', 'spam')),
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- Existing databases: allow the 'spam' status used by the anti-spam checks.
+ALTER TABLE IF EXISTS public.consultations
+    DROP CONSTRAINT IF EXISTS consultations_status_check;
+
+ALTER TABLE IF EXISTS public.consultations
+    ADD CONSTRAINT consultations_status_check
+    CHECK (status IN ('new', 'in_progress', 'completed', 'spam'));
+
+-- 11.2 Shared token buckets for POST /api/consultations (RATE_LIMIT_STORE=postgres)
+CREATE TABLE IF NOT EXISTS public.rate_limit_buckets (
+    bucket_key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
 -- 12. Privacy policy
 CREATE TABLE IF NOT EXISTS public.privacy_sections (
     id SERIAL PRIMARY KEY,
@@ -253,6 +302,271 @@ CREATE TABLE IF NOT EXISTS public.blog_posts (
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- 16. Media library (rows written by POST /admin/storage/upload, browsed via /admin/media)
+CREATE TABLE IF NOT EXISTS public.media (
+    id BIGSERIAL PRIMARY KEY,
+    backend TEXT NOT NULL,
+    bucket TEXT NOT NULL,
+    path TEXT NOT NULL,
+    public_url TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    mime_type TEXT NOT NULL DEFAULT 'application/octet-stream',
+    width INTEGER,
+    height INTEGER,
+    checksum_sha256 TEXT,
+    alt_text TEXT,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    uploaded_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT media_bucket_path_key UNIQUE (bucket, path)
+);
+
+-- Resized/WebP variants generated on upload: [{name, format, path, width, height, ...}].
+ALTER TABLE IF EXISTS public.media
+    ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- 18. Customer files sent with POST /api/consultations (multipart). Objects live
+-- in the private CONSULTATION_ATTACHMENTS_BUCKET and are shown via signed URLs.
+CREATE TABLE IF NOT EXISTS public.consultation_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    consultation_id BIGINT NOT NULL REFERENCES public.consultations (id) ON DELETE CASCADE,
+    bucket TEXT NOT NULL,
+    path TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    checksum_sha256 TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT consultation_attachments_bucket_path_key UNIQUE (bucket, path)
+);
+
+-- 19. Public slugs (GET /tuning/{id-or-slug}, /work_post/{slug},
+-- /portfolio_items/{slug}, /service_offerings/{slug}). The admin CRUD assigns
+-- them on create and the API backfills NULLs on startup. A renamed slug stays
+-- in slug_redirects and answers with 301 to the current one.
+ALTER TABLE IF EXISTS public.tuning
+    ADD COLUMN IF NOT EXISTS slug TEXT;
+
+ALTER TABLE IF EXISTS public.portfolio_items
+    ADD COLUMN IF NOT EXISTS slug TEXT;
+
+ALTER TABLE IF EXISTS public.service_offerings
+    ADD COLUMN IF NOT EXISTS slug TEXT;
+
+ALTER TABLE IF EXISTS public.work_post
+    ADD COLUMN IF NOT EXISTS slug TEXT;
+
+ALTER TABLE IF EXISTS public.blog_posts
+    ADD COLUMN IF NOT EXISTS slug TEXT;
+
+CREATE TABLE IF NOT EXISTS public.slug_redirects (
+    table_name TEXT NOT NULL,
+    old_slug TEXT NOT NULL,
+    record_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (table_name, old_slug)
+);
+
+-- 20. Full-text search (GET /search). search_vector holds Russian and English
+-- lexemes weighted title (A) > summary (B) > body (C); search_title feeds the
+-- pg_trgm fallback for typos and brand names. Both are generated columns, so
+-- every admin write keeps them in sync.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE IF EXISTS public.tuning
+    ADD COLUMN IF NOT EXISTS search_title TEXT GENERATED ALWAYS AS (
+        btrim(coalesce(brand, '') || ' ' || coalesce(model, ''))
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.tuning
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(brand, '') || ' ' || coalesce(model, '')), 'A')
+        || setweight(to_tsvector('english', coalesce(brand, '') || ' ' || coalesce(model, '')), 'A')
+        || setweight(to_tsvector('russian', coalesce(card_description, '') || ' ' || coalesce(description, '')), 'B')
+        || setweight(to_tsvector('english', coalesce(card_description, '') || ' ' || coalesce(description, '')), 'B')
+        || setweight(to_tsvector('russian', coalesce(full_description, '')), 'C')
+        || setweight(to_tsvector('english', coalesce(full_description, '')), 'C')
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.portfolio_items
+    ADD COLUMN IF NOT EXISTS search_title TEXT GENERATED ALWAYS AS (
+        btrim(coalesce(brand, '') || ' ' || title)
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.portfolio_items
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(brand, '') || ' ' || title), 'A')
+        || setweight(to_tsvector('english', coalesce(brand, '') || ' ' || title), 'A')
+        || setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+        || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.service_offerings
+    ADD COLUMN IF NOT EXISTS search_title TEXT GENERATED ALWAYS AS (
+        title
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.service_offerings
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title || ' ' || service_type), 'A')
+        || setweight(to_tsvector('english', title || ' ' || service_type), 'A')
+        || setweight(to_tsvector('russian', coalesce(detailed_description, '')), 'B')
+        || setweight(to_tsvector('english', coalesce(detailed_description, '')), 'B')
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.work_post
+    ADD COLUMN IF NOT EXISTS search_title TEXT GENERATED ALWAYS AS (
+        title_model
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.work_post
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title_model), 'A')
+        || setweight(to_tsvector('english', title_model), 'A')
+        || setweight(to_tsvector('russian', coalesce(card_description, '')), 'B')
+        || setweight(to_tsvector('english', coalesce(card_description, '')), 'B')
+        || setweight(to_tsvector('russian', coalesce(full_description, '')), 'C')
+        || setweight(to_tsvector('english', coalesce(full_description, '')), 'C')
+        || setweight(jsonb_to_tsvector('russian', coalesce(work_list, '[]'::jsonb), '["string"]'), 'B')
+        || setweight(jsonb_to_tsvector('english', coalesce(work_list, '[]'::jsonb), '["string"]'), 'B')
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.blog_posts
+    ADD COLUMN IF NOT EXISTS search_title TEXT GENERATED ALWAYS AS (
+        title_model
+    ) STORED;
+
+ALTER TABLE IF EXISTS public.blog_posts
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title_model), 'A')
+        || setweight(to_tsvector('english', title_model), 'A')
+        || setweight(to_tsvector('russian', coalesce(card_description, '')), 'B')
+        || setweight(to_tsvector('english', coalesce(card_description, '')), 'B')
+        || setweight(to_tsvector('russian', coalesce(full_description, '')), 'C')
+        || setweight(to_tsvector('english', coalesce(full_description, '')), 'C')
+        || setweight(jsonb_to_tsvector('russian', coalesce(work_list, '[]'::jsonb), '["string"]'), 'B')
+        || setweight(jsonb_to_tsvector('english', coalesce(work_list, '[]'::jsonb), '["string"]'), 'B')
+    ) STORED;
+
+-- 21. Translations for ru/uz/en. Text columns hold Russian; other locales live
+-- in translations as {"uz": {"title": "..."}, "en": {...}} and are picked by
+-- ?lang= or Accept-Language on the public routes.
+ALTER TABLE IF EXISTS public.banners
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.contact
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.contact_page
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.about_page
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.about_metrics
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.about_sections
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.tuning
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.service_offerings
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.privacy_sections
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.portfolio_items
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.work_post
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS public.blog_posts
+    ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- 22. Change stamps for HTTP caching. A statement-level trigger on each public
+-- content table records when it last changed; public GETs derive ETag and
+-- Last-Modified from these rows instead of re-running their queries.
+CREATE TABLE IF NOT EXISTS public.content_changes (
+    table_name TEXT PRIMARY KEY,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION public.touch_content_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO public.content_changes (table_name, changed_at)
+    VALUES (TG_TABLE_NAME, clock_timestamp())
+    ON CONFLICT (table_name) DO UPDATE SET changed_at = EXCLUDED.changed_at;
+    -- Every instance LISTENs here to drop its cached responses for the table.
+    PERFORM pg_notify('content_changes', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$;
+
+-- Existing tables start from their newest updated_at (or created_at).
+DO $$
+DECLARE
+    tbl TEXT;
+    stamp_column TEXT;
+BEGIN
+    FOREACH tbl IN ARRAY ARRAY[
+        'banners', 'contact', 'contact_page', 'about_page', 'about_metrics',
+        'about_sections', 'partners', 'tuning', 'service_offerings',
+        'privacy_sections', 'portfolio_items', 'work_post', 'blog_posts', 'media'
+    ] LOOP
+        IF to_regclass('public.' || tbl) IS NULL THEN
+            CONTINUE;
+        END IF;
+
+        EXECUTE format('DROP TRIGGER IF EXISTS content_changes_touch ON public.%I', tbl);
+        EXECUTE format(
+            'CREATE TRIGGER content_changes_touch
+                AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON public.%I
+                FOR EACH STATEMENT EXECUTE FUNCTION public.touch_content_change()',
+            tbl
+        );
+
+        SELECT column_name
+          INTO stamp_column
+          FROM information_schema.columns
+         WHERE table_schema = 'public'
+           AND table_name = tbl
+           AND column_name IN ('updated_at', 'created_at')
+         ORDER BY column_name DESC
+         LIMIT 1;
+
+        IF stamp_column IS NULL THEN
+            INSERT INTO public.content_changes (table_name)
+            VALUES (tbl)
+            ON CONFLICT (table_name) DO NOTHING;
+        ELSE
+            EXECUTE format(
+                'INSERT INTO public.content_changes (table_name, changed_at)
+                 SELECT %L, COALESCE(MAX(%I), NOW()) FROM public.%I
+                 ON CONFLICT (table_name) DO NOTHING',
+                tbl, stamp_column, tbl
+            );
+        END IF;
+    END LOOP;
+END $$;
+
+-- 23. Nonces of signed app requests (X-App-Nonce), each accepted once within
+-- the timestamp skew window.
+CREATE TABLE IF NOT EXISTS public.app_request_nonces (
+    app_id TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (app_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_request_nonces_expires_at
+    ON public.app_request_nonces (expires_at);
+
 -- Ensure compatibility for already existing databases.
 ALTER TABLE IF EXISTS public.work_post
     ADD COLUMN IF NOT EXISTS gallery_images JSONB;
@@ -291,6 +605,75 @@ CREATE INDEX IF NOT EXISTS idx_consultations_created_at_id
 CREATE INDEX IF NOT EXISTS idx_consultations_status_created_at
     ON public.consultations (status, created_at DESC, id DESC);
 
+CREATE INDEX IF NOT EXISTS idx_consultations_phone_created_at
+    ON public.consultations (phone, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at
+    ON public.rate_limit_buckets (updated_at);
+
+CREATE INDEX IF NOT EXISTS idx_media_created_at_id
+    ON public.media (created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_media_tags
+    ON public.media USING GIN (tags);
+
+CREATE INDEX IF NOT EXISTS idx_media_checksum
+    ON public.media (checksum_sha256);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at
+    ON public.upload_sessions (expires_at);
+
+CREATE INDEX IF NOT EXISTS idx_consultation_attachments_consultation_id
+    ON public.consultation_attachments (consultation_id, id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tuning_slug
+    ON public.tuning (slug);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_portfolio_items_slug
+    ON public.portfolio_items (slug);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_offerings_slug
+    ON public.service_offerings (slug);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_work_post_slug
+    ON public.work_post (slug);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_blog_posts_slug
+    ON public.blog_posts (slug);
+
+CREATE INDEX IF NOT EXISTS idx_slug_redirects_record
+    ON public.slug_redirects (table_name, record_id);
+
+CREATE INDEX IF NOT EXISTS idx_tuning_search_vector
+    ON public.tuning USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_tuning_search_title_trgm
+    ON public.tuning USING GIN (search_title gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_portfolio_items_search_vector
+    ON public.portfolio_items USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_portfolio_items_search_title_trgm
+    ON public.portfolio_items USING GIN (search_title gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_service_offerings_search_vector
+    ON public.service_offerings USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_service_offerings_search_title_trgm
+    ON public.service_offerings USING GIN (search_title gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_work_post_search_vector
+    ON public.work_post USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_work_post_search_title_trgm
+    ON public.work_post USING GIN (search_title gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_blog_posts_search_vector
+    ON public.blog_posts USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_blog_posts_search_title_trgm
+    ON public.blog_posts USING GIN (search_title gin_trgm_ops);
+
 -- Seed data for active routes (insert only when table is empty).
 INSERT INTO public.banners (section, title, image_url, priority)
 SELECT 'home', 'Main banner', 'https://example.com/banner-1.jpg', 1
@@ -393,6 +776,22 @@ FROM (
 ) AS src(section_key, title, description, position)
 WHERE NOT EXISTS (SELECT 1 FROM public.about_sections WHERE about_id = 1);
 
+INSERT INTO public.schema_migrations (version, description)
+VALUES
+    (1, 'baseline content tables'),
+    (2, 'consultation spam status and rate limit buckets'),
+    (3, 'media library'),
+    (4, 'media image variants'),
+    (5, 'resumable upload sessions'),
+    (6, 'consultation attachments'),
+    (7, 'public slugs'),
+    (8, 'full-text search'),
+    (9, 'content translations'),
+    (10, 'content change stamps'),
+    (11, 'content change notifications'),
+    (12, 'app request nonces')
+ON CONFLICT (version) DO NOTHING;
+
 COMMIT;
 
 -- Optional checks after execution:
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 50
+	maxSearchQueryLength = 200
+	maxSearchTerms       = 12
+)
+
+// adminHiddenColumns are the generated search columns; admin CRUD responses
+// leave them out since they cannot be written and only add noise.
+const adminHiddenColumns = `'{search_vector,search_title}'::text[]`
+
+// searchSource is one content table searched by GET /search. The SQL
+// fragments refer to the table as "c".
+type searchSource struct {
+	Type  string
+	Table string
+	// FallbackFor names the table this one stands in for when that one is
+	// missing (blog_posts for work_post, as in workPostHandler).
+	FallbackFor string
+	Route       string
+	// AllowID builds id URLs for rows without a slug (/tuning/{id}).
+	AllowID bool
+	Title   string
+	// TitleKey is the translations key of the title, "" when it has none.
+	TitleKey string
+	// Body lists the text columns the snippet is cut from.
+	Body  []string
+	Image string
+}
+
+var searchSources = []searchSource{
+	{
+		Type:    "tuning",
+		Table:   "tuning",
+		Route:   "/tuning",
+		AllowID: true,
+		Title:   "c.search_title",
+		Body:    []string{"card_description", "description", "full_description"},
+		Image:   "c.card_image_url",
+	},
+	{
+		Type:     "work_post",
+		Table:    "work_post",
+		Route:    "/work_post",
+		Title:    "c.title_model",
+		TitleKey: "title_model",
+		Body:     []string{"card_description", "full_description"},
+		Image:    "COALESCE(NULLIF(c.card_image_url, ''), NULLIF(c.full_image_url, ''), NULLIF(c.video_image_url, ''))",
+	},
+	{
+		Type:        "work_post",
+		Table:       "blog_posts",
+		FallbackFor: "work_post",
+		Route:       "/work_post",
+		Title:       "c.title_model",
+		TitleKey:    "title_model",
+		Body:        []string{"card_description", "full_description"},
+		Image:       "COALESCE(NULLIF(c.card_image_url, ''), NULLIF(c.full_image_url, ''), NULLIF(c.video_image_url, ''))",
+	},
+	{
+		Type:     "portfolio",
+		Table:    "portfolio_items",
+		Route:    "/portfolio_items",
+		Title:    "c.title",
+		TitleKey: "title",
+		Body:     []string{"description"},
+		Image:    "c.image_url",
+	},
+	{
+		Type:     "service",
+		Table:    "service_offerings",
+		Route:    "/service_offerings",
+		Title:    "c.title",
+		TitleKey: "title",
+		Body:     []string{"detailed_description"},
+		Image:    "c.gallery_images->>0",
+	},
+}
+
+// bodySQL joins the Body columns, each read from the first locale in $4
+// that has a translation for it, like localeChain.text, or from the column.
+func (s searchSource) bodySQL() string {
+	parts := make([]string, 0, len(s.Body))
+	for _, column := range s.Body {
+		translated := `to_jsonb(c)->'translations'->l.locale->>'` + column + `'`
+		parts = append(parts, `COALESCE((SELECT `+translated+`
+				FROM unnest($4::text[]) WITH ORDINALITY AS l(locale, n)
+				WHERE btrim(`+translated+`) <> ''
+				ORDER BY l.n LIMIT 1), c.`+column+`)`)
+	}
+	return "concat_ws(' ', " + strings.Join(parts, ", ") + ")"
+}
+
+type searchResult struct {
+	Type     string  `json:"type"`
+	ID       int64   `json:"id"`
+	Slug     *string `json:"slug"`
+	Title    string  `json:"title"`
+	Snippet  *string `json:"snippet"`
+	ImageURL *string `json:"image_url"`
+	URL      *string `json:"url"`
+	Rank     float64 `json:"rank"`
+}
+
+// searchTerms splits q into words for the any-word tsquery. Only letters and
+// digits survive, so the result is always valid to_tsquery input.
+func searchTerms(q string) []string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	seen := map[string]bool{}
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word+":*")
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// searchHandler serves GET /search?q=&type=&limit=. Rows match when their
+// tsvector shares any (prefix) term with q, or when search_title is close to
+// q by trigram word similarity, which catches typos in brand names. Rows
+// matching every term rank above rows matching some. Snippets are escaped
+// HTML with the matches wrapped in <mark>. Titles and snippets follow the
+// request's locales like the content endpoints.
+func (a *App) searchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	q := strings.TrimSpace(values.Get("q"))
+	if len([]rune(q)) > maxSearchQueryLength {
+		q = string([]rune(q)[:maxSearchQueryLength])
+	}
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxSearchLimit)
+	}
+
+	types := map[string]bool{}
+	for _, value := range strings.Split(values.Get("type"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		known := false
+		for _, source := range searchSources {
+			known = known || source.Type == value
+		}
+		if !known {
+			http.Error(w, "unknown type "+strconv.Quote(value), http.StatusBadRequest)
+			return
+		}
+		types[value] = true
+	}
+
+	locales := requestLocales(r)
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	available, err := a.searchableTables(ctx)
+	if err != nil {
+		logFromContext(ctx).Error("search tables lookup failed", "error", err)
+		http.Error(w, "failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	var selects []string
+	for _, source := range searchSources {
+		if !available[source.Table] || (source.FallbackFor != "" && available[source.FallbackFor]) {
+			continue
+		}
+		if len(types) > 0 && !types[source.Type] {
+			continue
+		}
+		selects = append(selects, `SELECT '`+source.Type+`' AS type, '`+source.Route+`' AS route, `+strconv.FormatBool(source.AllowID)+` AS allow_id,
+			c.id::bigint AS id, c.slug, `+source.Title+` AS title, '`+source.TitleKey+`' AS title_key, to_jsonb(c)->'translations' AS translations,
+			NULLIF(ts_headline('russian', `+escapeHTMLSQL(source.bodySQL())+`, s.any_q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10'), '') AS snippet,
+			NULLIF(`+source.Image+`, '') AS image_url,
+			ts_rank_cd(c.search_vector, s.all_q) * 2 + ts_rank_cd(c.search_vector, s.any_q) + word_similarity(s.raw, c.search_title) AS rank
+			FROM public.`+source.Table+` c, s
+			WHERE c.search_vector @@ s.any_q OR s.raw <% c.search_title`)
+	}
+	if len(selects) == 0 {
+		if len(available) == 0 {
+			logFromContext(ctx).Warn("search columns missing; apply schema.sql")
+		}
+		locales.setHeaders(w)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"query": q,
+			"total": 0,
+			"data":  []searchResult{},
+		})
+		return
+	}
+
+	query := `WITH s AS (
+		SELECT $1::text AS raw,
+			websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS all_q,
+			to_tsquery('russian', $2) || to_tsquery('english', $2) AS any_q
+	)
+	SELECT type, route, allow_id, id, slug, title, title_key, translations, snippet, image_url, rank, COUNT(*) OVER () AS total
+	FROM (` + strings.Join(selects, "\n\t\tUNION ALL\n\t\t") + `) results
+	ORDER BY rank DESC, type, id DESC
+	LIMIT $3`
+
+	rows, err := a.DB.QueryContext(withQueryName(ctx, "search"), query, q, strings.Join(terms, " | "), limit, locales.translated())
+	if err != nil {
+		logFromContext(ctx).Error("search failed", "error", err)
+		http.Error(w, "failed to search", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := make([]searchResult, 0, limit)
+	var total int64
+	for rows.Next() {
+		var (
+			item            searchResult
+			route, titleKey string
+			allowID         bool
+			translationsRaw []byte
+		)
+		if err := rows.Scan(&item.Type, &route, &allowID, &item.ID, &item.Slug, &item.Title, &titleKey, &translationsRaw, &item.Snippet, &item.ImageURL, &item.Rank, &total); err != nil {
+			http.Error(w, "failed to read search results", http.StatusInternalServerError)
+			return
+		}
+		if titleKey != "" {
+			item.Title = locales.text(parseContentTranslations(translationsRaw), titleKey, item.Title)
+		}
+		switch {
+		case item.Slug != nil && *item.Slug != "":
+			link := route + "/" + *item.Slug
+			item.URL = &link
+		case allowID:
+			link := route + "/" + strconv.FormatInt(item.ID, 10)
+			item.URL = &link
+		}
+		results = append(results, item)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "failed to read search results", http.StatusInternalServerError)
+		return
+	}
+
+	locales.setHeaders(w)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"query": q,
+		"total": total,
+		"data":  results,
+	})
+}
+
+// escapeHTMLSQL wraps a text SQL expression so it comes out HTML-escaped.
+// ts_headline copies its input verbatim around the <mark> tags, so the body
+// is escaped first and the snippet is safe to render as HTML.
+func escapeHTMLSQL(expr string) string {
+	return `replace(replace(replace(` + expr + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')`
+}
+
+// searchableTables returns the public tables that have the generated search
+// columns, so databases not yet migrated keep answering (with fewer results).
+func (a *App) searchableTables(ctx context.Context) (map[string]bool, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "search.tables"),
+		`SELECT table_name
+		FROM information_schema.columns
+		WHERE table_schema = 'public'
+		  AND column_name = 'search_vector'`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables[name] = true
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const maxSlugLength = 80
+
+// russianTranslit follows the common passport-style romanization, which is
+// what people type when guessing a URL.
+var russianTranslit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// slugConfig enables slugs on an admin CRUD table. The slug is built from
+// the Source columns on create and stays put when those columns change;
+// only an explicit "slug" in an update renames it.
+type slugConfig struct {
+	Source []string
+	// Fallback is used when the source columns are empty or all digits.
+	Fallback string
+}
+
+// slugify lowercases value, transliterates Cyrillic and joins the remaining
+// latin letters and digits with single dashes.
+func slugify(value string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(value) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case russianTranslit[r] != "":
+			b.WriteString(russianTranslit[r])
+			dash = false
+		case r == 'ъ' || r == 'ь' || r == '\'' || r == '’':
+			// Signs and apostrophes vanish without splitting the word.
+		default:
+			if !dash && b.Len() > 0 {
+				b.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
+
+// isNumericSlug reports slugs that would be mistaken for ids on /tuning/{id}.
+func isNumericSlug(slug string) bool {
+	_, err := strconv.ParseInt(slug, 10, 64)
+	return err == nil
+}
+
+// base builds the slug for a row from its column values.
+func (c slugConfig) base(values map[string]any) string {
+	parts := make([]string, 0, len(c.Source))
+	for _, column := range c.Source {
+		if text, ok := values[column].(string); ok {
+			parts = append(parts, text)
+		}
+	}
+	slug := slugify(strings.Join(parts, " "))
+	switch {
+	case slug == "":
+		return c.Fallback
+	case isNumericSlug(slug):
+		return slugify(c.Fallback + "-" + slug)
+	}
+	return slug
+}
+
+// assignSlug sets payload["slug"] before an admin create (id 0) or update.
+// Creates always get a slug; updates only touch it when "slug" is in the
+// payload, where an empty value means "rebuild from the current columns".
+// It writes the error response itself and reports false on failure.
+func (a *App) assignSlug(ctx context.Context, w http.ResponseWriter, cfg tableCRUDConfig, payload map[string]any, id int64) bool {
+	raw, provided := payload["slug"]
+	if id != 0 && !provided {
+		return true
+	}
+	text, isText := raw.(string)
+	if raw != nil && !isText {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"message": "validation error",
+			"errors":  map[string]string{"slug": "must be a string"},
+		})
+		return false
+	}
+
+	if text = strings.TrimSpace(text); text != "" {
+		slug := slugify(text)
+		if slug == "" || isNumericSlug(slug) {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+				"status":  "error",
+				"message": "validation error",
+				"errors":  map[string]string{"slug": "must contain latin or cyrillic letters"},
+			})
+			return false
+		}
+		var taken bool
+		err := a.DB.QueryRowContext(
+			withQueryName(ctx, "slugs.taken"),
+			fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE slug = $1 AND id <> $2)`, quoteTableName(cfg.Table)),
+			slug,
+			id,
+		).Scan(&taken)
+		if err != nil {
+			logFromContext(ctx).Error("slug check failed", "table", cfg.Table, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to check slug",
+			})
+			return false
+		}
+		if taken {
+			writeSlugConflict(w, slug)
+			return false
+		}
+		payload["slug"] = slug
+		return true
+	}
+
+	values := payload
+	if id != 0 {
+		stored, err := a.storedRow(ctx, cfg.Table, id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logFromContext(ctx).Error("slug source lookup failed", "table", cfg.Table, "id", id, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to fetch data",
+			})
+			return false
+		}
+		for key, value := range payload {
+			stored[key] = value
+		}
+		values = stored
+	}
+	slug, err := a.uniqueSlug(ctx, cfg.Table, cfg.Slug.base(values), id)
+	if err != nil {
+		logFromContext(ctx).Error("slug generation failed", "table", cfg.Table, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to generate slug",
+		})
+		return false
+	}
+	payload["slug"] = slug
+	return true
+}
+
+func writeSlugConflict(w http.ResponseWriter, slug string) {
+	writeJSON(w, http.StatusConflict, map[string]any{
+		"status":  "error",
+		"message": fmt.Sprintf("slug %q is already in use", slug),
+	})
+}
+
+func (a *App) storedRow(ctx context.Context, table string, id int64) (map[string]any, error) {
+	var raw []byte
+	query := fmt.Sprintf(`SELECT to_jsonb(t) FROM %s t WHERE t.id = $1`, quoteTableName(table))
+	if err := a.DB.QueryRowContext(ctx, query, id).Scan(&raw); err != nil {
+		return map[string]any{}, err
+	}
+	row := map[string]any{}
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return map[string]any{}, err
+	}
+	return row, nil
+}
+
+// uniqueSlug returns base, or base-2, base-3, ... if taken. Old slugs of
+// other rows count as taken so existing links never switch to new content.
+func (a *App) uniqueSlug(ctx context.Context, table, base string, excludeID int64) (string, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "slugs.unique"),
+		fmt.Sprintf(`SELECT slug FROM %s WHERE (slug = $1 OR slug LIKE $2) AND id <> $3
+		UNION
+		SELECT old_slug FROM public.slug_redirects
+		WHERE table_name = $4 AND record_id <> $3 AND (old_slug = $1 OR old_slug LIKE $2)`, quoteTableName(table)),
+		base,
+		escapeLikePattern(base)+"-%",
+		excludeID,
+		table,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", err
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return freeSlug(base, taken), nil
+}
+
+// freeSlug returns the first of base, base-2, base-3, ... not in taken,
+// shortening base so the result stays within maxSlugLength.
+func freeSlug(base string, taken map[string]bool) string {
+	if !taken[base] {
+		return base
+	}
+	for n := 2; ; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		prefix := base
+		if len(prefix)+len(suffix) > maxSlugLength {
+			prefix = strings.TrimRight(prefix[:maxSlugLength-len(suffix)], "-")
+		}
+		if candidate := prefix + suffix; !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// slugRenameQuery is the admin UPDATE for payloads carrying a slug. Besides
+// the update it keeps the previous slug in slug_redirects and drops any
+// redirect the new slug takes back. $idArg is the row id, $tableArg the
+// table name as stored in slug_redirects.
+func slugRenameQuery(table, setClause string, idArg, tableArg int) string {
+	return fmt.Sprintf(
+		`WITH old AS (
+			SELECT id, slug FROM %[1]s WHERE id = $%[3]d
+		), upd AS (
+			UPDATE %[1]s SET %[2]s WHERE id = $%[3]d RETURNING *
+		), moved AS (
+			INSERT INTO public.slug_redirects (table_name, old_slug, record_id)
+			SELECT $%[4]d::text, old.slug, old.id
+			FROM old JOIN upd ON upd.id = old.id
+			WHERE old.slug IS NOT NULL AND old.slug IS DISTINCT FROM upd.slug
+			ON CONFLICT (table_name, old_slug) DO UPDATE SET record_id = EXCLUDED.record_id, created_at = NOW()
+		), reclaimed AS (
+			DELETE FROM public.slug_redirects
+			WHERE table_name = $%[4]d::text AND old_slug IN (SELECT slug FROM upd)
+		)
+		SELECT to_jsonb(upd) - `+adminHiddenColumns+` FROM upd`,
+		quoteTableName(table),
+		setClause,
+		idArg,
+		tableArg,
+	)
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// backfillSlugs gives rows created before slugs existed (or written straight
+// to the database) their slug. It runs once at startup; a unique violation
+// just means another instance got there first.
+func (a *App) backfillSlugs(ctx context.Context) {
+	for _, cfg := range adminCRUDConfigs() {
+		if cfg.Slug == nil {
+			continue
+		}
+		if err := a.backfillTableSlugs(ctx, cfg); err != nil && ctx.Err() == nil {
+			logFromContext(ctx).Warn("slug backfill failed", "table", cfg.Table, "error", err)
+		}
+	}
+}
+
+func (a *App) backfillTableSlugs(ctx context.Context, cfg tableCRUDConfig) error {
+	exists, err := hasColumn(ctx, a.DB, strings.TrimPrefix(cfg.Table, "public."), "slug")
+	if err != nil || !exists {
+		return err
+	}
+
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "slugs.backfill"),
+		fmt.Sprintf(`SELECT t.id, to_jsonb(t) FROM %s t WHERE t.slug IS NULL ORDER BY t.id`, quoteTableName(cfg.Table)),
+	)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		id     int64
+		values map[string]any
+	}
+	var missing []pending
+	for rows.Next() {
+		var item pending
+		var raw []byte
+		if err := rows.Scan(&item.id, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal(raw, &item.values); err != nil {
+			rows.Close()
+			return err
+		}
+		missing = append(missing, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	assigned := 0
+	for _, item := range missing {
+		slug, err := a.uniqueSlug(ctx, cfg.Table, cfg.Slug.base(item.values), item.id)
+		if err != nil {
+			return err
+		}
+		_, err = a.DB.ExecContext(
+			ctx,
+			fmt.Sprintf(`UPDATE %s SET slug = $1 WHERE id = $2 AND slug IS NULL`, quoteTableName(cfg.Table)),
+			slug,
+			item.id,
+		)
+		if err != nil && !isUniqueViolation(err) {
+			return err
+		}
+		if err == nil {
+			assigned++
+		}
+	}
+	if assigned > 0 {
+		a.Responses.invalidate(cfg.Table)
+		logFromContext(ctx).Info("slugs backfilled", "table", cfg.Table, "rows", assigned)
+	}
+	return nil
+}
+
+// publicItemKey returns the {id-or-slug} part of GET {base}/{key}; false
+// means the list itself was requested.
+func publicItemKey(r *http.Request, base string) (string, bool) {
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, base), "/")
+	return key, key != ""
+}
+
+// publicItemID resolves key to a row id in table. Numeric keys are ids when
+// allowID is set. A slug that was renamed answers with 301 to the current
+// one. It writes the response itself and reports false when it did.
+func (a *App) publicItemID(ctx context.Context, w http.ResponseWriter, r *http.Request, base, table, key string, allowID bool) (int64, bool) {
+	if allowID {
+		if id, err := strconv.ParseInt(key, 10, 64); err == nil {
+			return id, true
+		}
+	}
+
+	var id int64
+	err := a.DB.QueryRowContext(
+		withQueryName(ctx, "slugs.resolve"),
+		fmt.Sprintf(`SELECT id FROM %s WHERE slug = $1`, quoteTableName(table)),
+		key,
+	).Scan(&id)
+	if err == nil {
+		return id, true
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		logFromContext(ctx).Error("slug lookup failed", "table", table, "slug", key, "error", err)
+		http.Error(w, "failed to fetch item", http.StatusInternalServerError)
+		return 0, false
+	}
+
+	var current string
+	err = a.DB.QueryRowContext(
+		withQueryName(ctx, "slugs.redirect"),
+		fmt.Sprintf(`SELECT t.slug FROM public.slug_redirects r
+		JOIN %s t ON t.id = r.record_id
+		WHERE r.table_name = $1 AND r.old_slug = $2 AND t.slug IS NOT NULL`, quoteTableName(table)),
+		table,
+		key,
+	).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.NotFound(w, r)
+	case err != nil:
+		logFromContext(ctx).Error("slug redirect lookup failed", "table", table, "slug", key, "error", err)
+		http.Error(w, "failed to fetch item", http.StatusInternalServerError)
+	default:
+		location := base + "/" + url.PathEscape(current)
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, http.StatusMovedPermanently)
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "latin", value: "BMW M5 Competition", want: "bmw-m5-competition"},
+		{name: "cyrillic", value: "Чип-тюнинг Шевроле", want: "chip-tyuning-shevrole"},
+		{name: "multi-letter transliteration", value: "Щука Жёлтая Хонда", want: "shchuka-zheltaya-khonda"},
+		{name: "signs vanish inside words", value: "Подъезд объявление", want: "podezd-obyavlenie"},
+		{name: "apostrophes vanish", value: "O'zbekiston Driver’s", want: "ozbekiston-drivers"},
+		{name: "punctuation collapses", value: "  --Audi / RS6!!  (2024)  ", want: "audi-rs6-2024"},
+		{name: "nothing left", value: "!!! ???", want: ""},
+		{name: "truncated without trailing dash", value: strings.Repeat("a", 79) + " b", want: strings.Repeat("a", 79)},
+		{name: "truncated to max length", value: strings.Repeat("ab", 50), want: strings.Repeat("ab", 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slugify(tt.value); got != tt.want {
+				t.Errorf("slugify(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugConfigBase(t *testing.T) {
+	cfg := slugConfig{Source: []string{"brand", "model"}, Fallback: "tuning"}
+	tests := []struct {
+		name   string
+		values map[string]any
+		want   string
+	}{
+		{name: "joins sources", values: map[string]any{"brand": "Toyota", "model": "Camry 70"}, want: "toyota-camry-70"},
+		{name: "skips missing", values: map[string]any{"model": "Малибу"}, want: "malibu"},
+		{name: "empty falls back", values: map[string]any{"brand": "", "model": "***"}, want: "tuning"},
+		{name: "numeric gets prefix", values: map[string]any{"brand": "2024"}, want: "tuning-2024"},
+		{name: "digits with dashes kept", values: map[string]any{"brand": "2024", "model": "15"}, want: "2024-15"},
+		{name: "non-string ignored", values: map[string]any{"brand": 42, "model": "Nexia"}, want: "nexia"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.base(tt.values); got != tt.want {
+				t.Errorf("base(%v) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeSlug(t *testing.T) {
+	long := strings.Repeat("x", maxSlugLength-1) + "y"
+	tests := []struct {
+		name  string
+		base  string
+		taken []string
+		want  string
+	}{
+		{name: "free", base: "camry", want: "camry"},
+		{name: "first suffix", base: "camry", taken: []string{"camry"}, want: "camry-2"},
+		{name: "skips taken suffixes", base: "camry", taken: []string{"camry", "camry-2", "camry-3"}, want: "camry-4"},
+		{name: "truncated for suffix", base: long, taken: []string{long}, want: long[:maxSlugLength-2] + "-2"},
+		{name: "truncated for longer suffix", base: long, taken: []string{long, long[:maxSlugLength-2] + "-2"}, want: long[:maxSlugLength-2] + "-3"},
+		{name: "no dash before suffix", base: strings.Repeat("x", maxSlugLength-3) + "-yy", taken: []string{strings.Repeat("x", maxSlugLength-3) + "-yy"}, want: strings.Repeat("x", maxSlugLength-3) + "-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := map[string]bool{}
+			for _, slug := range tt.taken {
+				taken[slug] = true
+			}
+			got := freeSlug(tt.base, taken)
+			if got != tt.want {
+				t.Errorf("freeSlug(%q) = %q, want %q", tt.base, got, tt.want)
+			}
+			if len(got) > maxSlugLength {
+				t.Errorf("freeSlug(%q) is %d bytes, over %d", tt.base, len(got), maxSlugLength)
+			}
+		})
+	}
+}
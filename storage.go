@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	errStorageNotFound      = errors.New("object not found")
+	errStorageExists        = errors.New("object already exists")
+	errStorageNotConfigured = errors.New("storage is not configured")
+)
+
+// Storage is the object store behind /admin/storage/*. Paths are already
+// cleaned with cleanStoragePath; implementations must not trust them further
+// than that.
+type Storage interface {
+	Backend() string
+	ListBuckets(ctx context.Context) ([]string, error)
+	Put(ctx context.Context, bucket, objectPath string, body io.Reader, opts storagePutOptions) (storageObject, error)
+	Get(ctx context.Context, bucket, objectPath string) (io.ReadCloser, storageObject, error)
+	Stat(ctx context.Context, bucket, objectPath string) (storageObject, error)
+	List(ctx context.Context, bucket string, opts storageListOptions) ([]storageObject, error)
+	Delete(ctx context.Context, bucket, objectPath string) error
+	// Copy duplicates an object server-side; without upsert an existing
+	// destination fails with errStorageExists.
+	Copy(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string, upsert bool) (storageObject, error)
+	PublicURL(bucket, objectPath string) string
+	// SignedURL returns a download link valid for about expires, also for
+	// private buckets, and the moment it stops working.
+	SignedURL(ctx context.Context, bucket, objectPath string, expires time.Duration) (string, time.Time, error)
+	// SignedUploadURL returns a link that accepts one PUT of the raw object
+	// body. Backends may grant less time than asked; the returned expiry is
+	// the real one.
+	SignedUploadURL(ctx context.Context, bucket, objectPath string, expires time.Duration, upsert bool) (string, time.Time, error)
+}
+
+type storagePutOptions struct {
+	ContentType  string
+	CacheControl string
+	// Size is the body length in bytes, or -1 when unknown.
+	Size   int64
+	Upsert bool
+}
+
+type storageListOptions struct {
+	Prefix     string
+	Limit      int
+	Offset     int
+	SortColumn string
+	SortOrder  string
+	Search     string
+}
+
+type storageObject struct {
+	Bucket      string    `json:"bucket"`
+	Path        string    `json:"path"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"mime_type,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitzero"`
+	IsFolder    bool      `json:"is_folder,omitempty"`
+}
+
+// newStorageFromEnv builds the backend selected by STORAGE_BACKEND
+// (supabase, local or s3; default supabase).
+func newStorageFromEnv() (Storage, error) {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_BACKEND")))
+	switch backend {
+	case "", "supabase":
+		cfg, err := loadSupabaseStorageConfig()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errStorageNotConfigured, err)
+		}
+		return newSupabaseStorage(cfg), nil
+	case "local", "disk":
+		return newLocalStorage(loadLocalStorageConfig())
+	case "s3", "minio":
+		cfg, err := loadS3StorageConfig()
+		if err != nil {
+			return nil, err
+		}
+		return newS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q (expected supabase, local or s3)", backend)
+	}
+}
+
+func defaultStorageBucket() string {
+	return firstNonEmpty(strings.TrimSpace(os.Getenv("STORAGE_BUCKET")), "cars")
+}
+
+// storageOrError returns the configured backend or writes a 500 response.
+func (a *App) storageOrError(w http.ResponseWriter) (Storage, bool) {
+	if a.Storage == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": errStorageNotConfigured.Error(),
+		})
+		return nil, false
+	}
+	return a.Storage, true
+}
+
+// writeStorageError maps backend errors onto HTTP responses.
+func writeStorageError(w http.ResponseWriter, message string, err error) {
+	writeJSON(w, storageErrorStatus(err), map[string]any{
+		"status":  "error",
+		"message": message,
+		"details": err.Error(),
+	})
+}
+
+// observeStorage wraps a backend call that does not go through
+// doStorageRequest with the same span and metrics.
+func observeStorage(ctx context.Context, operation string, fn func(context.Context) error) error {
+	ctx, span := tracer.Start(ctx, "storage "+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("storage.operation", operation)),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := fn(ctx)
+
+	outcome := "ok"
+	switch {
+	case err == nil:
+	case errors.Is(err, errStorageNotFound):
+		outcome = "not_found"
+	case errors.Is(err, errStorageExists):
+		outcome = "conflict"
+	default:
+		outcome = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	recordStorageRequest(operation, time.Since(start), outcome)
+	return err
+}
+
+// pageStorageObjects applies search, sorting and offset/limit for backends
+// whose list API cannot do it natively. Folders always come first.
+func pageStorageObjects(objects []storageObject, opts storageListOptions) []storageObject {
+	search := strings.ToLower(opts.Search)
+	filtered := objects[:0]
+	for _, object := range objects {
+		if search != "" && !strings.Contains(strings.ToLower(object.Name), search) {
+			continue
+		}
+		filtered = append(filtered, object)
+	}
+
+	desc := opts.SortOrder == "desc"
+	sort.SliceStable(filtered, func(i, j int) bool {
+		left, right := filtered[i], filtered[j]
+		if left.IsFolder != right.IsFolder {
+			return left.IsFolder
+		}
+		var less bool
+		switch opts.SortColumn {
+		case "updated_at", "created_at", "last_accessed_at":
+			less = left.UpdatedAt.Before(right.UpdatedAt)
+		default:
+			less = left.Name < right.Name
+		}
+		if desc {
+			return !less
+		}
+		return less
+	})
+
+	if opts.Offset >= len(filtered) {
+		return []storageObject{}
+	}
+	filtered = filtered[opts.Offset:]
+	if opts.Limit > 0 && len(filtered) > opts.Limit {
+		filtered = filtered[:opts.Limit]
+	}
+	return filtered
+}
+
+// joinStoragePath builds "<prefix>/<name>" for list results.
+func joinStoragePath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return path.Join(prefix, name)
+}
+
+// storageListEntry renders a list item. The nested metadata object keeps the
+// field names of the Supabase list API the admin panel was written against.
+// Objects in private buckets get no URL; /admin/storage/sign issues one.
+func storageListEntry(storage Storage, object storageObject, private bool) map[string]any {
+	entry := map[string]any{
+		"name":      object.Name,
+		"path":      object.Path,
+		"is_folder": object.IsFolder,
+	}
+	if object.IsFolder {
+		entry["metadata"] = nil
+		return entry
+	}
+
+	entry["size"] = object.Size
+	entry["mime_type"] = object.ContentType
+	if private {
+		entry["public_url"] = nil
+		entry["private"] = true
+	} else {
+		entry["public_url"] = storage.PublicURL(object.Bucket, object.Path)
+	}
+	if !object.UpdatedAt.IsZero() {
+		entry["updated_at"] = object.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	entry["metadata"] = map[string]any{
+		"size":     object.Size,
+		"mimetype": object.ContentType,
+		"eTag":     object.ETag,
+	}
+	return entry
+}
+
+func objectBaseName(objectPath string) string {
+	return path.Base(objectPath)
+}
+
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errStorageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errStorageExists):
+		return http.StatusConflict
+	}
+	return http.StatusBadGateway
+}
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const localStorageRoute = "/files/"
+
+// localSignedUploadMaxBytes caps a PUT to a signed /files URL in buckets
+// without max_size_mb, matching the S3 single-PUT limit.
+const localSignedUploadMaxBytes = 5 << 30
+
+// inlineFileTypes are served from /files for display; everything else is
+// sent as a download.
+var inlineFileTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/avif": true,
+}
+
+type localStorageConfig struct {
+	Dir            string
+	PublicURL      string
+	SigningSecret  []byte
+	PrivateBuckets map[string]bool
+}
+
+// loadLocalStorageConfig reads STORAGE_LOCAL_DIR (default ./storage_data),
+// STORAGE_PUBLIC_URL, the external base for links (default: relative /files),
+// and STORAGE_SIGNING_SECRET for signed links. Without a secret a random one
+// is used, so signed links stop working on restart.
+func loadLocalStorageConfig() localStorageConfig {
+	return localStorageConfig{
+		Dir:            firstNonEmpty(strings.TrimSpace(os.Getenv("STORAGE_LOCAL_DIR")), "storage_data"),
+		PublicURL:      strings.TrimRight(strings.TrimSpace(os.Getenv("STORAGE_PUBLIC_URL")), "/"),
+		SigningSecret:  []byte(strings.TrimSpace(os.Getenv("STORAGE_SIGNING_SECRET"))),
+		PrivateBuckets: loadStorageAccessConfig().PrivateBuckets,
+	}
+}
+
+// localStorage keeps objects under <dir>/<bucket>/<path> and is served by the
+// app itself on /files/. Meant for offline development and single-node setups.
+type localStorage struct {
+	root      string
+	publicURL string
+	secret    []byte
+	private   map[string]bool
+	// uploadPolicies judges the bodies PUT to signed upload links.
+	uploadPolicies uploadPolicySet
+}
+
+func newLocalStorage(cfg localStorageConfig) (*localStorage, error) {
+	root, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve STORAGE_LOCAL_DIR: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, defaultStorageBucket()), 0o755); err != nil {
+		return nil, fmt.Errorf("create local storage dir: %w", err)
+	}
+	secret := cfg.SigningSecret
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("generate signing secret: %w", err)
+		}
+	}
+	return &localStorage{root: root, publicURL: cfg.PublicURL, secret: secret, private: cfg.PrivateBuckets}, nil
+}
+
+func (s *localStorage) Backend() string {
+	return "local"
+}
+
+// filePath maps bucket/objectPath to a file under the root, refusing
+// anything that would escape it.
+func (s *localStorage) filePath(bucket, objectPath string) (string, error) {
+	full := filepath.Join(s.root, bucket, filepath.FromSlash(objectPath))
+	rel, err := filepath.Rel(s.root, full)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", errors.New("invalid path")
+	}
+	return full, nil
+}
+
+func (s *localStorage) ListBuckets(ctx context.Context) ([]string, error) {
+	var buckets []string
+	err := observeStorage(ctx, "list_buckets", func(context.Context) error {
+		entries, err := os.ReadDir(s.root)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				buckets = append(buckets, entry.Name())
+			}
+		}
+		return nil
+	})
+	sort.Strings(buckets)
+	return buckets, err
+}
+
+func (s *localStorage) Put(ctx context.Context, bucket, objectPath string, body io.Reader, opts storagePutOptions) (storageObject, error) {
+	var object storageObject
+	err := observeStorage(ctx, "upload", func(context.Context) error {
+		target, err := s.filePath(bucket, objectPath)
+		if err != nil {
+			return err
+		}
+		if !opts.Upsert {
+			if _, err := os.Stat(target); err == nil {
+				return errStorageExists
+			}
+		}
+		if err := writeFileAtomic(target, body); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		object = localObject(bucket, objectPath, info)
+		return nil
+	})
+	return object, err
+}
+
+func (s *localStorage) Get(ctx context.Context, bucket, objectPath string) (io.ReadCloser, storageObject, error) {
+	var (
+		file   *os.File
+		object storageObject
+	)
+	err := observeStorage(ctx, "download", func(context.Context) error {
+		target, err := s.filePath(bucket, objectPath)
+		if err != nil {
+			return err
+		}
+		file, err = os.Open(target)
+		if err != nil {
+			return localStorageError(err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if info.IsDir() {
+			file.Close()
+			return errStorageNotFound
+		}
+		object = localObject(bucket, objectPath, info)
+		return nil
+	})
+	if err != nil {
+		return nil, storageObject{}, err
+	}
+	return file, object, nil
+}
+
+func (s *localStorage) Stat(ctx context.Context, bucket, objectPath string) (storageObject, error) {
+	var object storageObject
+	err := observeStorage(ctx, "stat", func(context.Context) error {
+		target, err := s.filePath(bucket, objectPath)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return localStorageError(err)
+		}
+		if info.IsDir() {
+			return errStorageNotFound
+		}
+		object = localObject(bucket, objectPath, info)
+		return nil
+	})
+	return object, err
+}
+
+func (s *localStorage) List(ctx context.Context, bucket string, opts storageListOptions) ([]storageObject, error) {
+	var objects []storageObject
+	err := observeStorage(ctx, "list", func(context.Context) error {
+		dir := filepath.Join(s.root, bucket)
+		if opts.Prefix != "" {
+			var err error
+			if dir, err = s.filePath(bucket, opts.Prefix); err != nil {
+				return err
+			}
+		}
+
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			objectPath := joinStoragePath(opts.Prefix, entry.Name())
+			if entry.IsDir() {
+				objects = append(objects, storageObject{Bucket: bucket, Path: objectPath, Name: entry.Name(), IsFolder: true})
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			objects = append(objects, localObject(bucket, objectPath, info))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pageStorageObjects(objects, opts), nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, bucket, objectPath string) error {
+	return observeStorage(ctx, "delete", func(context.Context) error {
+		target, err := s.filePath(bucket, objectPath)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return localStorageError(err)
+		}
+		if info.IsDir() {
+			return errStorageNotFound
+		}
+		if err := os.Remove(target); err != nil {
+			return localStorageError(err)
+		}
+		s.pruneEmptyDirs(bucket, filepath.Dir(target))
+		return nil
+	})
+}
+
+func (s *localStorage) Copy(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string, upsert bool) (storageObject, error) {
+	var object storageObject
+	err := observeStorage(ctx, "copy", func(context.Context) error {
+		source, err := s.filePath(srcBucket, srcPath)
+		if err != nil {
+			return err
+		}
+		target, err := s.filePath(dstBucket, dstPath)
+		if err != nil {
+			return err
+		}
+		if !upsert {
+			if _, err := os.Stat(target); err == nil {
+				return errStorageExists
+			}
+		}
+
+		file, err := os.Open(source)
+		if err != nil {
+			return localStorageError(err)
+		}
+		defer file.Close()
+		if info, err := file.Stat(); err != nil || info.IsDir() {
+			return errStorageNotFound
+		}
+		if err := writeFileAtomic(target, file); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		object = localObject(dstBucket, dstPath, info)
+		return nil
+	})
+	return object, err
+}
+
+// writeFileAtomic writes next to the target and renames so readers never
+// see a partial file.
+func writeFileAtomic(target string, body io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// pruneEmptyDirs removes folders left empty by a delete, stopping at the bucket.
+func (s *localStorage) pruneEmptyDirs(bucket, dir string) {
+	bucketDir := filepath.Join(s.root, bucket)
+	for dir != bucketDir && strings.HasPrefix(dir, bucketDir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (s *localStorage) PublicURL(bucket, objectPath string) string {
+	return s.publicURL + localStorageRoute + encodeStoragePath(bucket) + "/" + encodeStoragePath(objectPath)
+}
+
+func (s *localStorage) SignedURL(ctx context.Context, bucket, objectPath string, expires time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(expires)
+	return s.signedURL(http.MethodGet, bucket, objectPath, expiresAt, false), expiresAt, nil
+}
+
+func (s *localStorage) SignedUploadURL(ctx context.Context, bucket, objectPath string, expires time.Duration, upsert bool) (string, time.Time, error) {
+	expiresAt := time.Now().Add(expires)
+	return s.signedURL(http.MethodPut, bucket, objectPath, expiresAt, upsert), expiresAt, nil
+}
+
+func (s *localStorage) signedURL(method, bucket, objectPath string, expiresAt time.Time, upsert bool) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	if upsert {
+		query.Set("upsert", "1")
+	}
+	query.Set("signature", s.signature(method, bucket, objectPath, query))
+	return s.PublicURL(bucket, objectPath) + "?" + query.Encode()
+}
+
+// signature is an HMAC over the method, object and the signed query
+// parameters, so a download link cannot be replayed as an upload.
+func (s *localStorage) signature(method, bucket, objectPath string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, bucket, objectPath, query.Get("expires"), query.Get("upsert"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *localStorage) verifySignature(method, bucket, objectPath string, query url.Values) bool {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	expected := s.signature(method, bucket, objectPath, query)
+	return hmac.Equal([]byte(query.Get("signature")), []byte(expected))
+}
+
+// filesHandler serves GET /files/{bucket}/{path...} for the local backend.
+// Private buckets need a signed link; PUT stores the body through a signed
+// upload link.
+func (s *localStorage) filesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bucketValue, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, localStorageRoute), "/")
+	bucket, err := cleanStorageBucket(bucketValue)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	objectPath, err := cleanStoragePath(rest)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	private := s.private[bucket]
+	if private || r.Method == http.MethodPut || query.Has("signature") {
+		if !s.verifySignature(r.Method, bucket, objectPath, query) {
+			http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+	}
+	if r.Method == http.MethodPut {
+		s.signedUpload(w, r, bucket, objectPath, query.Get("upsert") == "1")
+		return
+	}
+
+	body, object, err := s.Get(r.Context(), bucket, objectPath)
+	if err != nil {
+		if errors.Is(err, errStorageNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		logFromContext(r.Context()).Error("local storage read failed", "bucket", bucket, "path", objectPath, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	file := body.(*os.File)
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Stored files share the API origin; never let one run script there.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	if !inlineFileTypes[object.ContentType] {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": object.Name}))
+	}
+	if private {
+		w.Header().Set("Cache-Control", "private, no-store")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+	w.Header().Set("ETag", `"`+object.ETag+`"`)
+	http.ServeContent(w, r, object.Name, object.UpdatedAt, file)
+}
+
+// signedUpload stores the body of a PUT to a signed upload link. The link
+// only fixed the path, so the body is spooled and run through the bucket's
+// upload policy like any other upload before it reaches the bucket.
+func (s *localStorage) signedUpload(w http.ResponseWriter, r *http.Request, bucket, objectPath string, upsert bool) {
+	ctx := r.Context()
+	policy := s.uploadPolicies.forBucket(bucket)
+	maxBytes := int64(localSignedUploadMaxBytes)
+	if policy.MaxSizeMB > 0 {
+		maxBytes = policy.maxBytes()
+	}
+
+	spool, err := os.CreateTemp(s.root, ".signed-upload-*")
+	if err != nil {
+		logFromContext(ctx).Error("local signed upload spool failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := io.Copy(spool, http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		logFromContext(ctx).Error("local signed upload spool failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	name := path.Base(objectPath)
+	check, err := policy.check(spool, size, bucket, objectPath, name)
+	if err == nil && check.Filename != name {
+		err = &uploadRejection{
+			StatusCode: http.StatusUnsupportedMediaType,
+			Message:    fmt.Sprintf("%s content needs one of the extensions %s", check.ContentType, strings.Join(uploadTypeExtensions[check.ContentType], ", ")),
+		}
+	}
+	if err != nil {
+		var rejection *uploadRejection
+		if errors.As(err, &rejection) {
+			logFromContext(ctx).Warn("signed upload rejected by policy", "bucket", bucket, "path", objectPath, "reason", err.Error())
+			writeUploadRejection(w, rejection)
+			return
+		}
+		logFromContext(ctx).Error("local signed upload check failed", "bucket", bucket, "path", objectPath, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	object, err := s.Put(ctx, bucket, objectPath, spool, storagePutOptions{
+		ContentType: check.ContentType,
+		Size:        size,
+		Upsert:      upsert,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errStorageExists):
+			http.Error(w, "object already exists", http.StatusConflict)
+		default:
+			logFromContext(ctx).Error("local signed upload failed", "bucket", bucket, "path", objectPath, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, object)
+}
+
+func localObject(bucket, objectPath string, info fs.FileInfo) storageObject {
+	contentType := mime.TypeByExtension(path.Ext(objectPath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return storageObject{
+		Bucket:      bucket,
+		Path:        objectPath,
+		Name:        info.Name(),
+		Size:        info.Size(),
+		ContentType: contentType,
+		ETag:        strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36),
+		UpdatedAt:   info.ModTime().UTC(),
+	}
+}
+
+func localStorageError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return errStorageNotFound
+	}
+	return err
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type s3StorageConfig struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+	PublicURL string
+}
+
+// loadS3StorageConfig reads S3_ENDPOINT (host[:port], e.g. localhost:9000 for
+// MinIO), S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY, S3_REGION, S3_USE_SSL and
+// STORAGE_PUBLIC_URL (CDN or proxy base; default <endpoint>/<bucket>/<path>).
+func loadS3StorageConfig() (s3StorageConfig, error) {
+	endpoint := strings.TrimSpace(os.Getenv("S3_ENDPOINT"))
+	useSSL := true
+	if raw := strings.TrimSpace(os.Getenv("S3_USE_SSL")); raw != "" {
+		useSSL = isTruthy(raw)
+	}
+	// Accept a full URL too; minio wants host[:port] plus a TLS flag.
+	if parsed, err := url.Parse(endpoint); err == nil && parsed.Host != "" {
+		endpoint = parsed.Host
+		useSSL = parsed.Scheme == "https"
+	}
+
+	cfg := s3StorageConfig{
+		Endpoint:  endpoint,
+		AccessKey: strings.TrimSpace(os.Getenv("S3_ACCESS_KEY_ID")),
+		SecretKey: strings.TrimSpace(os.Getenv("S3_SECRET_ACCESS_KEY")),
+		Region:    strings.TrimSpace(os.Getenv("S3_REGION")),
+		UseSSL:    useSSL,
+		PublicURL: strings.TrimRight(strings.TrimSpace(os.Getenv("STORAGE_PUBLIC_URL")), "/"),
+	}
+	if cfg.Endpoint == "" {
+		return s3StorageConfig{}, errors.New("S3_ENDPOINT is not set")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return s3StorageConfig{}, errors.New("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY must be set")
+	}
+	if cfg.PublicURL == "" {
+		scheme := "http"
+		if cfg.UseSSL {
+			scheme = "https"
+		}
+		cfg.PublicURL = scheme + "://" + cfg.Endpoint
+	}
+	return cfg, nil
+}
+
+// s3Storage works against any S3-compatible API (MinIO, AWS, R2, ...).
+// Buckets map 1:1 onto S3 buckets.
+type s3Storage struct {
+	client    *minio.Client
+	publicURL string
+}
+
+func newS3Storage(cfg s3StorageConfig) (*s3Storage, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create S3 client: %w", err)
+	}
+	return &s3Storage{client: client, publicURL: cfg.PublicURL}, nil
+}
+
+func (s *s3Storage) Backend() string {
+	return "s3"
+}
+
+func (s *s3Storage) ListBuckets(ctx context.Context) ([]string, error) {
+	var buckets []string
+	err := observeStorage(ctx, "list_buckets", func(ctx context.Context) error {
+		infos, err := s.client.ListBuckets(ctx)
+		if err != nil {
+			return s3StorageError(err)
+		}
+		for _, info := range infos {
+			buckets = append(buckets, info.Name)
+		}
+		return nil
+	})
+	sort.Strings(buckets)
+	return buckets, err
+}
+
+func (s *s3Storage) Put(ctx context.Context, bucket, objectPath string, body io.Reader, opts storagePutOptions) (storageObject, error) {
+	var object storageObject
+	err := observeStorage(ctx, "upload", func(ctx context.Context) error {
+		if !opts.Upsert {
+			_, err := s.client.StatObject(ctx, bucket, objectPath, minio.StatObjectOptions{})
+			if err == nil {
+				return errStorageExists
+			}
+			if err := s3StorageError(err); !errors.Is(err, errStorageNotFound) {
+				return err
+			}
+		}
+
+		size := opts.Size
+		if size <= 0 {
+			size = -1
+		}
+		contentType := firstNonEmpty(opts.ContentType, "application/octet-stream")
+		info, err := s.client.PutObject(ctx, bucket, objectPath, body, size, minio.PutObjectOptions{
+			ContentType:  contentType,
+			CacheControl: opts.CacheControl,
+		})
+		if err != nil {
+			return s3StorageError(err)
+		}
+
+		object = storageObject{
+			Bucket:      bucket,
+			Path:        objectPath,
+			Name:        objectBaseName(objectPath),
+			Size:        info.Size,
+			ContentType: contentType,
+			ETag:        info.ETag,
+			UpdatedAt:   info.LastModified,
+		}
+		return nil
+	})
+	return object, err
+}
+
+func (s *s3Storage) Get(ctx context.Context, bucket, objectPath string) (io.ReadCloser, storageObject, error) {
+	var (
+		reader *minio.Object
+		object storageObject
+	)
+	err := observeStorage(ctx, "download", func(ctx context.Context) error {
+		var err error
+		reader, err = s.client.GetObject(ctx, bucket, objectPath, minio.GetObjectOptions{})
+		if err != nil {
+			return s3StorageError(err)
+		}
+		// GetObject is lazy; Stat surfaces a missing key before we hand it out.
+		info, err := reader.Stat()
+		if err != nil {
+			reader.Close()
+			return s3StorageError(err)
+		}
+		object = s3Object(bucket, info)
+		return nil
+	})
+	if err != nil {
+		return nil, storageObject{}, err
+	}
+	return reader, object, nil
+}
+
+func (s *s3Storage) Stat(ctx context.Context, bucket, objectPath string) (storageObject, error) {
+	var object storageObject
+	err := observeStorage(ctx, "stat", func(ctx context.Context) error {
+		info, err := s.client.StatObject(ctx, bucket, objectPath, minio.StatObjectOptions{})
+		if err != nil {
+			return s3StorageError(err)
+		}
+		object = s3Object(bucket, info)
+		return nil
+	})
+	return object, err
+}
+
+func (s *s3Storage) List(ctx context.Context, bucket string, opts storageListOptions) ([]storageObject, error) {
+	var objects []storageObject
+	err := observeStorage(ctx, "list", func(ctx context.Context) error {
+		prefix := ""
+		if opts.Prefix != "" {
+			prefix = opts.Prefix + "/"
+		}
+
+		for info := range s.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+			if info.Err != nil {
+				return s3StorageError(info.Err)
+			}
+			name := strings.TrimPrefix(info.Key, prefix)
+			if strings.HasSuffix(name, "/") {
+				name = strings.TrimSuffix(name, "/")
+				objects = append(objects, storageObject{Bucket: bucket, Path: prefix + name, Name: name, IsFolder: true})
+				continue
+			}
+			objects = append(objects, s3Object(bucket, info))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pageStorageObjects(objects, opts), nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, bucket, objectPath string) error {
+	return observeStorage(ctx, "delete", func(ctx context.Context) error {
+		// S3 deletes are idempotent; stat first so a missing key reports 404
+		// like the other backends.
+		if _, err := s.client.StatObject(ctx, bucket, objectPath, minio.StatObjectOptions{}); err != nil {
+			return s3StorageError(err)
+		}
+		return s3StorageError(s.client.RemoveObject(ctx, bucket, objectPath, minio.RemoveObjectOptions{}))
+	})
+}
+
+func (s *s3Storage) Copy(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string, upsert bool) (storageObject, error) {
+	var object storageObject
+	err := observeStorage(ctx, "copy", func(ctx context.Context) error {
+		if !upsert {
+			_, err := s.client.StatObject(ctx, dstBucket, dstPath, minio.StatObjectOptions{})
+			if err == nil {
+				return errStorageExists
+			}
+			if err := s3StorageError(err); !errors.Is(err, errStorageNotFound) {
+				return err
+			}
+		}
+		info, err := s.client.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: dstBucket, Object: dstPath},
+			minio.CopySrcOptions{Bucket: srcBucket, Object: srcPath},
+		)
+		if err != nil {
+			return s3StorageError(err)
+		}
+		stat, err := s.client.StatObject(ctx, dstBucket, dstPath, minio.StatObjectOptions{})
+		if err != nil {
+			object = storageObject{Bucket: dstBucket, Path: dstPath, Name: objectBaseName(dstPath), Size: info.Size, ETag: info.ETag}
+			return nil
+		}
+		object = s3Object(dstBucket, stat)
+		return nil
+	})
+	return object, err
+}
+
+func (s *s3Storage) PublicURL(bucket, objectPath string) string {
+	return s.publicURL + "/" + url.PathEscape(bucket) + "/" + encodeStoragePath(objectPath)
+}
+
+func (s *s3Storage) SignedURL(ctx context.Context, bucket, objectPath string, expires time.Duration) (string, time.Time, error) {
+	signed, err := s.client.PresignedGetObject(ctx, bucket, objectPath, expires, nil)
+	if err != nil {
+		return "", time.Time{}, s3StorageError(err)
+	}
+	return signed.String(), time.Now().Add(expires), nil
+}
+
+// SignedUploadURL presigns a PUT. Without upsert the signature also covers
+// If-None-Match: *, so S3 answers 412 instead of overwriting an object that
+// appeared after the link was issued; see SignedUploadHeaders.
+func (s *s3Storage) SignedUploadURL(ctx context.Context, bucket, objectPath string, expires time.Duration, upsert bool) (string, time.Time, error) {
+	signed, err := s.client.PresignHeader(ctx, http.MethodPut, bucket, objectPath, expires, nil, s.SignedUploadHeaders(upsert))
+	if err != nil {
+		return "", time.Time{}, s3StorageError(err)
+	}
+	return signed.String(), time.Now().Add(expires), nil
+}
+
+// SignedUploadHeaders are the headers the PUT to a signed upload link must
+// carry for the signature to match.
+func (s *s3Storage) SignedUploadHeaders(upsert bool) http.Header {
+	if upsert {
+		return nil
+	}
+	return http.Header{"If-None-Match": []string{"*"}}
+}
+
+func s3Object(bucket string, info minio.ObjectInfo) storageObject {
+	return storageObject{
+		Bucket:      bucket,
+		Path:        info.Key,
+		Name:        objectBaseName(info.Key),
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ETag:        info.ETag,
+		UpdatedAt:   info.LastModified,
+	}
+}
+
+func s3StorageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	resp := minio.ToErrorResponse(err)
+	switch {
+	case resp.Code == "NoSuchKey", resp.Code == "NoSuchBucket", resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %v", errStorageNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSignedURLTTL = 15 * time.Minute
+	// maxSignedURLTTL is the S3 presign ceiling; the other backends follow it.
+	maxSignedURLTTL = 7 * 24 * time.Hour
+)
+
+// storageAccessConfig says which buckets are private. Objects in them have
+// no working public URL and are reached through signed links only; the
+// bucket itself must also be private on the backend (Supabase bucket
+// setting, S3 bucket policy), this config only changes what the API hands out.
+type storageAccessConfig struct {
+	PrivateBuckets map[string]bool
+	DefaultTTL     time.Duration
+	MaxTTL         time.Duration
+}
+
+// loadStorageAccessConfig reads STORAGE_PRIVATE_BUCKETS (comma-separated, on
+// top of the consultation attachments bucket), STORAGE_SIGNED_URL_TTL
+// (default 15m) and STORAGE_SIGNED_URL_MAX_TTL (default and upper bound 168h).
+func loadStorageAccessConfig() storageAccessConfig {
+	cfg := storageAccessConfig{
+		PrivateBuckets: map[string]bool{},
+		DefaultTTL:     parseDurationOrDefault(os.Getenv("STORAGE_SIGNED_URL_TTL"), defaultSignedURLTTL),
+		MaxTTL:         parseDurationOrDefault(os.Getenv("STORAGE_SIGNED_URL_MAX_TTL"), maxSignedURLTTL),
+	}
+	// Customer photos sent with consultations are never public.
+	cfg.PrivateBuckets[consultationAttachmentsBucket()] = true
+	for _, bucket := range strings.Split(os.Getenv("STORAGE_PRIVATE_BUCKETS"), ",") {
+		if bucket, err := cleanStorageBucket(bucket); err == nil {
+			cfg.PrivateBuckets[bucket] = true
+		}
+	}
+	if cfg.MaxTTL <= 0 || cfg.MaxTTL > maxSignedURLTTL {
+		cfg.MaxTTL = maxSignedURLTTL
+	}
+	if cfg.DefaultTTL <= 0 || cfg.DefaultTTL > cfg.MaxTTL {
+		cfg.DefaultTTL = min(defaultSignedURLTTL, cfg.MaxTTL)
+	}
+	return cfg
+}
+
+func (c storageAccessConfig) isPrivate(bucket string) bool {
+	return c.PrivateBuckets[bucket]
+}
+
+// ttl turns a requested lifetime in seconds into a duration, using the
+// default for 0 and clamping to the configured maximum.
+func (c storageAccessConfig) ttl(seconds int) time.Duration {
+	if seconds <= 0 {
+		return c.DefaultTTL
+	}
+	return min(time.Duration(seconds)*time.Second, c.MaxTTL)
+}
+
+// objectLinks returns the URL fields for an object in API responses: the
+// public URL, or for private buckets a short-lived signed download link.
+func (a *App) objectLinks(ctx context.Context, storage Storage, bucket, objectPath string) map[string]any {
+	if !a.StorageAccess.isPrivate(bucket) {
+		return map[string]any{
+			"public_url": storage.PublicURL(bucket, objectPath),
+		}
+	}
+
+	links := map[string]any{
+		"public_url": nil,
+		"private":    true,
+	}
+	signedURL, expiresAt, err := storage.SignedURL(ctx, bucket, objectPath, a.StorageAccess.DefaultTTL)
+	if err != nil {
+		logFromContext(ctx).Warn("signed url failed", "bucket", bucket, "path", objectPath, "error", err)
+		return links
+	}
+	links["signed_url"] = signedURL
+	links["signed_url_expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+	return links
+}
+
+type storageSignRequest struct {
+	Bucket string `json:"bucket"`
+	Path   string `json:"path"`
+	// Action is "download" (default) or "upload".
+	Action    string `json:"action"`
+	ExpiresIn int    `json:"expires_in"`
+	Upsert    bool   `json:"upsert"`
+}
+
+// signedUploadHeaderer is implemented by backends whose signed upload links
+// only work when the PUT carries certain headers.
+type signedUploadHeaderer interface {
+	SignedUploadHeaders(upsert bool) http.Header
+}
+
+// adminStorageSignHandler serves POST /admin/storage/sign and returns a
+// time-limited URL for one object. Download links work for private buckets;
+// upload links accept a single PUT of the raw file body. Only the bucket's
+// path prefix and extension rules can be checked here; the local backend
+// also runs the uploaded bytes through the policy, while Supabase and S3
+// receive them directly, so there upload links are only issued for private
+// buckets, whose files are never served publicly. Direct uploads skip the
+// media registry. When the backend binds the link to request headers (S3
+// without upsert), they are returned under "headers".
+func (a *App) adminStorageSignHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdminToken(w, r) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+		return
+	}
+	storage, ok := a.storageOrError(w)
+	if !ok {
+		return
+	}
+
+	var req storageSignRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "invalid JSON body",
+		})
+		return
+	}
+	bucket, err := cleanStorageBucket(firstNonEmpty(req.Bucket, defaultStorageBucket()))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	objectPath, err := cleanStoragePath(req.Path)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	action := firstNonEmpty(strings.ToLower(strings.TrimSpace(req.Action)), "download")
+	if action != "download" && action != "upload" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "action must be download or upload",
+		})
+		return
+	}
+	if req.ExpiresIn < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "expires_in must be positive",
+		})
+		return
+	}
+	ttl := a.StorageAccess.ttl(req.ExpiresIn)
+	ctx := r.Context()
+
+	var (
+		signedURL string
+		expiresAt time.Time
+		method    = http.MethodGet
+		headers   http.Header
+	)
+	if action == "upload" {
+		if _, local := storage.(*localStorage); !local && !a.StorageAccess.isPrivate(bucket) {
+			writeJSON(w, http.StatusForbidden, map[string]any{
+				"status":  "error",
+				"message": "upload links for public buckets need the local backend; upload through /admin/storage/upload or " + uploadSessionsRoute + " instead",
+			})
+			return
+		}
+		if rejection := a.UploadPolicies.forBucket(bucket).checkSignedUpload(bucket, objectPath); rejection != nil {
+			writeUploadRejection(w, rejection)
+			return
+		}
+		if !req.Upsert {
+			if _, err := storage.Stat(ctx, bucket, objectPath); err == nil {
+				writeJSON(w, http.StatusConflict, map[string]any{
+					"status":  "error",
+					"message": "object already exists (pass upsert=true to overwrite)",
+				})
+				return
+			} else if !errors.Is(err, errStorageNotFound) {
+				writeStorageError(w, "failed to check object", err)
+				return
+			}
+		}
+		method = http.MethodPut
+		signedURL, expiresAt, err = storage.SignedUploadURL(ctx, bucket, objectPath, ttl, req.Upsert)
+		if headerer, ok := storage.(signedUploadHeaderer); ok {
+			headers = headerer.SignedUploadHeaders(req.Upsert)
+		}
+	} else {
+		if _, err := storage.Stat(ctx, bucket, objectPath); err != nil {
+			writeStorageError(w, "failed to find object", err)
+			return
+		}
+		signedURL, expiresAt, err = storage.SignedURL(ctx, bucket, objectPath, ttl)
+	}
+	if err != nil {
+		logFromContext(ctx).Error("storage sign failed", "backend", storage.Backend(), "bucket", bucket, "path", objectPath, "action", action, "error", err)
+		writeStorageError(w, "failed to sign url", err)
+		return
+	}
+
+	data := map[string]any{
+		"bucket":     bucket,
+		"path":       objectPath,
+		"action":     action,
+		"method":     method,
+		"url":        signedURL,
+		"private":    a.StorageAccess.isPrivate(bucket),
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+		"expires_in": int(time.Until(expiresAt).Round(time.Second).Seconds()),
+	}
+	if len(headers) > 0 {
+		// The PUT must send these exactly, or the signature does not match.
+		required := make(map[string]string, len(headers))
+		for name := range headers {
+			required[name] = headers.Get(name)
+		}
+		data["headers"] = required
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   data,
+	})
+}
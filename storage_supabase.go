@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type supabaseStorageConfig struct {
+	BaseURL     string
+	ServiceRole string
+}
+
+func loadSupabaseStorageConfig() (supabaseStorageConfig, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(os.Getenv("SUPABASE_URL")), "/")
+	serviceRole := strings.TrimSpace(os.Getenv("SUPABASE_SERVICE_ROLE_KEY"))
+
+	if baseURL == "" {
+		return supabaseStorageConfig{}, errors.New("SUPABASE_URL is not set")
+	}
+	if serviceRole == "" {
+		return supabaseStorageConfig{}, errors.New("SUPABASE_SERVICE_ROLE_KEY is not set")
+	}
+
+	return supabaseStorageConfig{
+		BaseURL:     baseURL,
+		ServiceRole: serviceRole,
+	}, nil
+}
+
+// supabaseStorage talks to the Supabase Storage REST API with the service role key.
+type supabaseStorage struct {
+	cfg    supabaseStorageConfig
+	client *http.Client
+	// uploadClient has no timeout: large (resumable) transfers are bounded
+	// by the caller's context instead.
+	uploadClient *http.Client
+}
+
+func newSupabaseStorage(cfg supabaseStorageConfig) *supabaseStorage {
+	return &supabaseStorage{
+		cfg:          cfg,
+		client:       &http.Client{Timeout: 20 * time.Second},
+		uploadClient: &http.Client{},
+	}
+}
+
+func (s *supabaseStorage) Backend() string {
+	return "supabase"
+}
+
+func (s *supabaseStorage) newRequest(ctx context.Context, method, target string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.ServiceRole)
+	req.Header.Set("apikey", s.cfg.ServiceRole)
+	return req, nil
+}
+
+func (s *supabaseStorage) ListBuckets(ctx context.Context) ([]string, error) {
+	listURL := fmt.Sprintf("%s/storage/v1/bucket", s.cfg.BaseURL)
+	req, err := s.newRequest(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build buckets request: %w", err)
+	}
+
+	resp, err := doStorageRequest(s.client, req, "list_buckets")
+	if err != nil {
+		return nil, fmt.Errorf("buckets request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, supabaseStatusError("buckets", resp.StatusCode, raw)
+	}
+
+	var payload []map[string]any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("decode buckets response: %w", err)
+	}
+
+	buckets := make([]string, 0, len(payload))
+	for _, bucket := range payload {
+		name, _ := bucket["name"].(string)
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		buckets = append(buckets, name)
+	}
+	sort.Strings(buckets)
+	return buckets, nil
+}
+
+func (s *supabaseStorage) Put(ctx context.Context, bucket, objectPath string, body io.Reader, opts storagePutOptions) (storageObject, error) {
+	contentType := firstNonEmpty(opts.ContentType, "application/octet-stream")
+
+	req, err := s.newRequest(ctx, http.MethodPost, buildSupabaseObjectURL(s.cfg.BaseURL, bucket, objectPath), body)
+	if err != nil {
+		return storageObject{}, fmt.Errorf("build upload request: %w", err)
+	}
+	req.Header.Set("x-upsert", strconv.FormatBool(opts.Upsert))
+	req.Header.Set("Content-Type", contentType)
+	if opts.CacheControl != "" {
+		req.Header.Set("Cache-Control", opts.CacheControl)
+	}
+	if opts.Size > 0 {
+		req.ContentLength = opts.Size
+	}
+
+	resp, err := doStorageRequest(s.uploadClient, req, "upload")
+	if err != nil {
+		return storageObject{}, fmt.Errorf("upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return storageObject{}, supabaseStatusError("upload", resp.StatusCode, raw)
+	}
+
+	return storageObject{
+		Bucket:      bucket,
+		Path:        objectPath,
+		Name:        objectBaseName(objectPath),
+		Size:        opts.Size,
+		ContentType: contentType,
+		UpdatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+func (s *supabaseStorage) Get(ctx context.Context, bucket, objectPath string) (io.ReadCloser, storageObject, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, buildSupabaseObjectURL(s.cfg.BaseURL, bucket, objectPath), nil)
+	if err != nil {
+		return nil, storageObject{}, fmt.Errorf("build download request: %w", err)
+	}
+
+	resp, err := doStorageRequest(s.uploadClient, req, "download")
+	if err != nil {
+		return nil, storageObject{}, fmt.Errorf("download request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		return nil, storageObject{}, supabaseStatusError("download", resp.StatusCode, raw)
+	}
+
+	return resp.Body, supabaseObjectFromHeaders(bucket, objectPath, resp), nil
+}
+
+func (s *supabaseStorage) Stat(ctx context.Context, bucket, objectPath string) (storageObject, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, buildSupabaseObjectURL(s.cfg.BaseURL, bucket, objectPath), nil)
+	if err != nil {
+		return storageObject{}, fmt.Errorf("build stat request: %w", err)
+	}
+
+	resp, err := doStorageRequest(s.client, req, "stat")
+	if err != nil {
+		return storageObject{}, fmt.Errorf("stat request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return storageObject{}, supabaseStatusError("stat", resp.StatusCode, nil)
+	}
+	return supabaseObjectFromHeaders(bucket, objectPath, resp), nil
+}
+
+func (s *supabaseStorage) List(ctx context.Context, bucket string, opts storageListOptions) ([]storageObject, error) {
+	payload := map[string]any{
+		"prefix": opts.Prefix,
+		"limit":  opts.Limit,
+		"offset": opts.Offset,
+		"sortBy": map[string]any{
+			"column": opts.SortColumn,
+			"order":  opts.SortOrder,
+		},
+	}
+	if opts.Search != "" {
+		payload["search"] = opts.Search
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("build list payload: %w", err)
+	}
+
+	listURL := fmt.Sprintf("%s/storage/v1/object/list/%s", s.cfg.BaseURL, url.PathEscape(bucket))
+	req, err := s.newRequest(ctx, http.MethodPost, listURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build list request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := doStorageRequest(s.client, req, "list")
+	if err != nil {
+		return nil, fmt.Errorf("list request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, supabaseStatusError("list", resp.StatusCode, raw)
+	}
+	if len(raw) == 0 {
+		return []storageObject{}, nil
+	}
+
+	var items []struct {
+		ID        *string `json:"id"`
+		Name      string  `json:"name"`
+		UpdatedAt string  `json:"updated_at"`
+		Metadata  *struct {
+			Size     int64  `json:"size"`
+			MimeType string `json:"mimetype"`
+			ETag     string `json:"eTag"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("decode list response: %w", err)
+	}
+
+	objects := make([]storageObject, 0, len(items))
+	for _, item := range items {
+		object := storageObject{
+			Bucket:   bucket,
+			Path:     joinStoragePath(opts.Prefix, item.Name),
+			Name:     item.Name,
+			IsFolder: item.ID == nil,
+		}
+		if item.Metadata != nil {
+			object.Size = item.Metadata.Size
+			object.ContentType = item.Metadata.MimeType
+			object.ETag = strings.Trim(item.Metadata.ETag, `"`)
+		}
+		if updatedAt, err := time.Parse(time.RFC3339Nano, item.UpdatedAt); err == nil {
+			object.UpdatedAt = updatedAt
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func (s *supabaseStorage) Delete(ctx context.Context, bucket, objectPath string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, buildSupabaseObjectURL(s.cfg.BaseURL, bucket, objectPath), nil)
+	if err != nil {
+		return fmt.Errorf("build delete request: %w", err)
+	}
+
+	resp, err := doStorageRequest(s.client, req, "delete")
+	if err != nil {
+		return fmt.Errorf("delete request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return supabaseStatusError("delete", resp.StatusCode, raw)
+	}
+	return nil
+}
+
+func (s *supabaseStorage) Copy(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string, upsert bool) (storageObject, error) {
+	if !upsert {
+		if _, err := s.Stat(ctx, dstBucket, dstPath); err == nil {
+			return storageObject{}, fmt.Errorf("copy: %w", errStorageExists)
+		} else if !errors.Is(err, errStorageNotFound) {
+			return storageObject{}, err
+		}
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"bucketId":          srcBucket,
+		"sourceKey":         srcPath,
+		"destinationBucket": dstBucket,
+		"destinationKey":    dstPath,
+	})
+	if err != nil {
+		return storageObject{}, err
+	}
+	req, err := s.newRequest(ctx, http.MethodPost, strings.TrimRight(s.cfg.BaseURL, "/")+"/storage/v1/object/copy", bytes.NewReader(payload))
+	if err != nil {
+		return storageObject{}, fmt.Errorf("build copy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-upsert", strconv.FormatBool(upsert))
+
+	resp, err := doStorageRequest(s.uploadClient, req, "copy")
+	if err != nil {
+		return storageObject{}, fmt.Errorf("copy request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return storageObject{}, supabaseStatusError("copy", resp.StatusCode, raw)
+	}
+	return s.Stat(ctx, dstBucket, dstPath)
+}
+
+func (s *supabaseStorage) PublicURL(bucket, objectPath string) string {
+	return buildSupabasePublicURL(s.cfg.BaseURL, bucket, objectPath)
+}
+
+// supabaseSignedUploadTTL is fixed by Supabase Storage; the requested
+// lifetime cannot extend or shorten it.
+const supabaseSignedUploadTTL = 2 * time.Hour
+
+func (s *supabaseStorage) SignedURL(ctx context.Context, bucket, objectPath string, expires time.Duration) (string, time.Time, error) {
+	seconds := max(int(expires.Seconds()), 1)
+	payload, err := json.Marshal(map[string]int{"expiresIn": seconds})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var reply struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := s.sign(ctx, "sign", buildSupabaseSignURL(s.cfg.BaseURL, "sign", bucket, objectPath), payload, nil, &reply); err != nil {
+		return "", time.Time{}, err
+	}
+	if reply.SignedURL == "" {
+		return "", time.Time{}, errors.New("sign: empty signedURL in response")
+	}
+	return s.cfg.BaseURL + "/storage/v1" + reply.SignedURL, time.Now().Add(time.Duration(seconds) * time.Second), nil
+}
+
+func (s *supabaseStorage) SignedUploadURL(ctx context.Context, bucket, objectPath string, expires time.Duration, upsert bool) (string, time.Time, error) {
+	var reply struct {
+		URL string `json:"url"`
+	}
+	header := http.Header{}
+	header.Set("x-upsert", strconv.FormatBool(upsert))
+	if err := s.sign(ctx, "sign_upload", buildSupabaseSignURL(s.cfg.BaseURL, "upload/sign", bucket, objectPath), nil, header, &reply); err != nil {
+		return "", time.Time{}, err
+	}
+	if reply.URL == "" {
+		return "", time.Time{}, errors.New("sign_upload: empty url in response")
+	}
+	return s.cfg.BaseURL + "/storage/v1" + reply.URL, time.Now().Add(supabaseSignedUploadTTL), nil
+}
+
+// sign POSTs to one of the signing endpoints and decodes the JSON reply.
+func (s *supabaseStorage) sign(ctx context.Context, operation, target string, payload []byte, header http.Header, reply any) error {
+	req, err := s.newRequest(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", operation, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := doStorageRequest(s.client, req, operation)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", operation, err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return supabaseStatusError(operation, resp.StatusCode, raw)
+	}
+	if err := json.Unmarshal(raw, reply); err != nil {
+		return fmt.Errorf("decode %s response: %w", operation, err)
+	}
+	return nil
+}
+
+// supabaseStatusError converts a non-2xx reply into an error. Supabase often
+// answers 400 with the real status in the body ({"statusCode":"404",...}).
+func supabaseStatusError(operation string, statusCode int, body []byte) error {
+	effective := strconv.Itoa(statusCode)
+	var reply struct {
+		StatusCode string `json:"statusCode"`
+	}
+	if json.Unmarshal(body, &reply) == nil && reply.StatusCode != "" {
+		effective = reply.StatusCode
+	}
+
+	details := strings.TrimSpace(string(body))
+	switch effective {
+	case "404":
+		return fmt.Errorf("%s: %w", operation, errStorageNotFound)
+	case "409":
+		return fmt.Errorf("%s: %w", operation, errStorageExists)
+	}
+	return fmt.Errorf("%s request status %d: %s", operation, statusCode, details)
+}
+
+func supabaseObjectFromHeaders(bucket, objectPath string, resp *http.Response) storageObject {
+	object := storageObject{
+		Bucket:      bucket,
+		Path:        objectPath,
+		Name:        objectBaseName(objectPath),
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.UpdatedAt = lastModified
+	}
+	return object
+}
+
+func buildSupabaseObjectURL(baseURL, bucket, objectPath string) string {
+	return fmt.Sprintf(
+		"%s/storage/v1/object/%s/%s",
+		strings.TrimRight(baseURL, "/"),
+		url.PathEscape(bucket),
+		encodeStoragePath(objectPath),
+	)
+}
+
+func buildSupabasePublicURL(baseURL, bucket, objectPath string) string {
+	return fmt.Sprintf(
+		"%s/storage/v1/object/public/%s/%s",
+		strings.TrimRight(baseURL, "/"),
+		url.PathEscape(bucket),
+		encodeStoragePath(objectPath),
+	)
+}
+
+func buildSupabaseSignURL(baseURL, kind, bucket, objectPath string) string {
+	return fmt.Sprintf(
+		"%s/storage/v1/object/%s/%s/%s",
+		strings.TrimRight(baseURL, "/"),
+		kind,
+		url.PathEscape(bucket),
+		encodeStoragePath(objectPath),
+	)
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	maxStorageTransferObjects = 1000
+	storageTransferTimeout    = 5 * time.Minute
+)
+
+var errTooManyTransferObjects = fmt.Errorf("at most %d objects can be transferred at once", maxStorageTransferObjects)
+
+type storageTransferRequest struct {
+	Bucket   string `json:"bucket"`
+	Path     string `json:"path"`
+	ToBucket string `json:"to_bucket"`
+	ToPath   string `json:"to_path"`
+	// Name is the new base name for /admin/storage/rename.
+	Name string `json:"name"`
+	// Prefix treats path as a folder and transfers everything below it.
+	Prefix      bool `json:"prefix"`
+	Upsert      bool `json:"upsert"`
+	RewriteURLs bool `json:"rewrite_urls"`
+	Force       bool `json:"force"`
+}
+
+type storageTransferItem struct {
+	FromBucket string `json:"from_bucket"`
+	From       string `json:"from"`
+	ToBucket   string `json:"to_bucket"`
+	To         string `json:"to"`
+}
+
+// adminStorageCopyHandler serves POST /admin/storage/copy.
+func (a *App) adminStorageCopyHandler(w http.ResponseWriter, r *http.Request) {
+	a.adminStorageTransfer(w, r, "copy")
+}
+
+// adminStorageMoveHandler serves POST /admin/storage/move.
+func (a *App) adminStorageMoveHandler(w http.ResponseWriter, r *http.Request) {
+	a.adminStorageTransfer(w, r, "move")
+}
+
+// adminStorageRenameHandler serves POST /admin/storage/rename: a move that
+// keeps the object (or folder, with prefix=true) in its parent folder.
+func (a *App) adminStorageRenameHandler(w http.ResponseWriter, r *http.Request) {
+	a.adminStorageTransfer(w, r, "rename")
+}
+
+func (a *App) adminStorageTransfer(w http.ResponseWriter, r *http.Request, operation string) {
+	if !requireAdminToken(w, r) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+		return
+	}
+	storage, ok := a.storageOrError(w)
+	if !ok {
+		return
+	}
+
+	var req storageTransferRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "invalid JSON body",
+		})
+		return
+	}
+
+	src, dst, err := resolveStorageTransfer(req, operation)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	move := operation != "copy"
+
+	ctx, cancel := context.WithTimeout(r.Context(), storageTransferTimeout)
+	defer cancel()
+
+	items, err := a.planStorageTransfer(ctx, storage, src, dst, req.Prefix)
+	if err != nil {
+		if errors.Is(err, errStorageNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{
+				"status":  "error",
+				"message": "source not found",
+			})
+			return
+		}
+		if errors.Is(err, errTooManyTransferObjects) {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		logFromContext(ctx).Error("storage transfer planning failed", "bucket", src.FromBucket, "path", src.From, "error", err)
+		writeStorageError(w, "failed to list source objects", err)
+		return
+	}
+
+	if !req.Upsert {
+		var conflicts []storageTransferItem
+		for _, item := range items {
+			if _, err := storage.Stat(ctx, item.ToBucket, item.To); err == nil {
+				conflicts = append(conflicts, item)
+			} else if !errors.Is(err, errStorageNotFound) {
+				writeStorageError(w, "failed to check destination", err)
+				return
+			}
+		}
+		if len(conflicts) > 0 {
+			writeJSON(w, http.StatusConflict, map[string]any{
+				"status":    "error",
+				"message":   "destination already exists (pass upsert=true to overwrite)",
+				"conflicts": conflicts,
+			})
+			return
+		}
+	}
+
+	// Moving a referenced file breaks pages unless the links follow it.
+	var refs []mediaReference
+	if move && (req.RewriteURLs || !req.Force) {
+		refs, err = a.collectMediaReferences(ctx)
+		if err != nil {
+			logFromContext(ctx).Error("media usage check failed", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to check file usage (pass force=true to skip)",
+			})
+			return
+		}
+	}
+	if move && !req.RewriteURLs && !req.Force {
+		if usages := transferUsages(refs, items); len(usages) > 0 {
+			writeJSON(w, http.StatusConflict, map[string]any{
+				"status":  "error",
+				"message": "files are still referenced (pass rewrite_urls=true to update the links or force=true to move anyway)",
+				"usages":  usages,
+			})
+			return
+		}
+	}
+
+	// Sources are only deleted once every object is copied and the links
+	// point at the copies, so a failure never leaves content rows pointing
+	// at files that are gone.
+	done := make([]storageTransferItem, 0, len(items))
+	for _, item := range items {
+		if _, err := storage.Copy(ctx, item.FromBucket, item.From, item.ToBucket, item.To, req.Upsert); err != nil {
+			logFromContext(ctx).Error("storage copy failed", "backend", storage.Backend(), "from", mediaKey(item.FromBucket, item.From), "to", mediaKey(item.ToBucket, item.To), "error", err)
+			message := fmt.Sprintf("%s stopped after %d of %d objects", operation, len(done), len(items))
+			if move {
+				message += "; sources were left in place"
+				if !req.Upsert {
+					removeStorageCopies(ctx, storage, done)
+					done = done[:0]
+				}
+			} else if _, _, finishErr := a.finishStorageTransfer(ctx, storage, done, false, false, nil); finishErr != nil {
+				logFromContext(ctx).Error("storage copy finish failed", "error", finishErr)
+			}
+			writeJSON(w, storageErrorStatus(err), map[string]any{
+				"status":  "error",
+				"message": message,
+				"details": err.Error(),
+				"done":    done,
+			})
+			return
+		}
+		done = append(done, item)
+	}
+
+	mediaRows, rewritten, err := a.finishStorageTransfer(ctx, storage, done, move, req.RewriteURLs, refs)
+	if err != nil {
+		logFromContext(ctx).Error("storage transfer finish failed", "operation", operation, "error", err)
+		message := "objects were copied but the media library could not be updated"
+		if move {
+			message = "objects were copied but links could not be updated; sources were left in place"
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": message,
+			"details": err.Error(),
+			"done":    done,
+		})
+		return
+	}
+
+	if move {
+		for _, item := range done {
+			if err := storage.Delete(ctx, item.FromBucket, item.From); err != nil && !errors.Is(err, errStorageNotFound) {
+				logFromContext(ctx).Warn("storage move source delete failed", "bucket", item.FromBucket, "path", item.From, "error", err)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"operation":      operation,
+			"items":          done,
+			"media_rows":     mediaRows,
+			"rewritten_refs": rewritten,
+		},
+	})
+}
+
+// removeStorageCopies undoes the copies of a move that could not finish.
+func removeStorageCopies(ctx context.Context, storage Storage, copies []storageTransferItem) {
+	for _, item := range copies {
+		if err := storage.Delete(ctx, item.ToBucket, item.To); err != nil && !errors.Is(err, errStorageNotFound) {
+			logFromContext(ctx).Warn("storage move rollback failed", "bucket", item.ToBucket, "path", item.To, "error", err)
+		}
+	}
+}
+
+// resolveStorageTransfer validates the request and returns the source and
+// destination (bucket plus object path, or folder when prefix is set).
+func resolveStorageTransfer(req storageTransferRequest, operation string) (storageTransferItem, storageTransferItem, error) {
+	var src, dst storageTransferItem
+	var err error
+	if src.FromBucket, err = cleanStorageBucket(firstNonEmpty(req.Bucket, defaultStorageBucket())); err != nil {
+		return src, dst, err
+	}
+	if src.From, err = cleanStoragePath(req.Path); err != nil {
+		return src, dst, fmt.Errorf("path: %w", err)
+	}
+
+	if operation == "rename" {
+		name := strings.TrimSpace(req.Name)
+		if name == "" || sanitizeFilename(name) != name {
+			return src, dst, errors.New("name must be a plain file or folder name")
+		}
+		dst.ToBucket = src.FromBucket
+		dst.To = joinStoragePath(strings.TrimSuffix(path.Dir(src.From), "."), name)
+	} else {
+		if dst.ToBucket, err = cleanStorageBucket(firstNonEmpty(req.ToBucket, src.FromBucket)); err != nil {
+			return src, dst, fmt.Errorf("to_bucket: %w", err)
+		}
+		if dst.To, err = cleanStoragePath(req.ToPath); err != nil {
+			return src, dst, fmt.Errorf("to_path: %w", err)
+		}
+	}
+
+	if src.FromBucket == dst.ToBucket {
+		if src.From == dst.To {
+			return src, dst, errors.New("source and destination are the same")
+		}
+		if req.Prefix && strings.HasPrefix(dst.To, src.From+"/") {
+			return src, dst, errors.New("cannot move a folder into itself")
+		}
+	}
+	return src, dst, nil
+}
+
+// planStorageTransfer lists the objects to transfer. A single object brings
+// its registered image variants along, renamed to match.
+func (a *App) planStorageTransfer(ctx context.Context, storage Storage, src, dst storageTransferItem, prefix bool) ([]storageTransferItem, error) {
+	var items []storageTransferItem
+	if prefix {
+		err := walkStoragePrefix(ctx, storage, src.FromBucket, src.From, func(object storageObject) error {
+			if len(items) >= maxStorageTransferObjects {
+				return errTooManyTransferObjects
+			}
+			items = append(items, storageTransferItem{
+				FromBucket: src.FromBucket,
+				From:       object.Path,
+				ToBucket:   dst.ToBucket,
+				To:         dst.To + strings.TrimPrefix(object.Path, src.From),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, errStorageNotFound
+		}
+		return items, nil
+	}
+
+	if _, err := storage.Stat(ctx, src.FromBucket, src.From); err != nil {
+		return nil, err
+	}
+	items = append(items, storageTransferItem{FromBucket: src.FromBucket, From: src.From, ToBucket: dst.ToBucket, To: dst.To})
+
+	variantPaths, err := a.mediaVariantPaths(ctx, src.FromBucket, src.From)
+	if err != nil {
+		logFromContext(ctx).Warn("media variant lookup failed", "bucket", src.FromBucket, "path", src.From, "error", err)
+	}
+	for _, variantPath := range variantPaths {
+		items = append(items, storageTransferItem{
+			FromBucket: src.FromBucket,
+			From:       variantPath,
+			ToBucket:   dst.ToBucket,
+			To:         relocateVariantPath(variantPath, src.From, dst.To),
+		})
+	}
+	return items, nil
+}
+
+// relocateVariantPath renames "cars/bmw@thumb.webp" for a move of
+// "cars/bmw.jpg" to "gallery/m3.jpg" into "gallery/m3@thumb.webp".
+func relocateVariantPath(variantPath, from, to string) string {
+	fromStem := strings.TrimSuffix(from, path.Ext(from)) + "@"
+	toStem := strings.TrimSuffix(to, path.Ext(to)) + "@"
+	if !strings.HasPrefix(variantPath, fromStem) {
+		return joinStoragePath(strings.TrimSuffix(path.Dir(to), "."), path.Base(variantPath))
+	}
+	return toStem + strings.TrimPrefix(variantPath, fromStem)
+}
+
+func transferUsages(refs []mediaReference, items []storageTransferItem) []mediaReference {
+	index := mediaReferenceIndex(refs)
+	var usages []mediaReference
+	for _, item := range items {
+		usages = append(usages, index[mediaKey(item.FromBucket, item.From)]...)
+	}
+	return usages
+}
+
+// finishStorageTransfer brings content links (for moves with rewriteURLs)
+// and media rows in line with the objects that were copied. Links are
+// rewritten first, in one transaction, so on any error the sources are
+// still where the content rows point.
+func (a *App) finishStorageTransfer(ctx context.Context, storage Storage, done []storageTransferItem, move, rewriteURLs bool, refs []mediaReference) (int, int, error) {
+	if len(done) == 0 {
+		return 0, 0, nil
+	}
+	destinations := make(map[string]storageTransferItem, len(done))
+	for _, item := range done {
+		destinations[mediaKey(item.FromBucket, item.From)] = item
+	}
+
+	rewritten := 0
+	if move && rewriteURLs {
+		var err error
+		rewritten, err = a.rewriteMediaReferences(ctx, storage, refs, destinations)
+		if err != nil {
+			return 0, 0, fmt.Errorf("rewrite content URLs: %w", err)
+		}
+	}
+
+	mediaRows, err := a.transferMediaRows(ctx, storage, done, destinations, move)
+	if err != nil {
+		return mediaRows, rewritten, fmt.Errorf("transfer media rows: %w", err)
+	}
+	return mediaRows, rewritten, nil
+}
+
+// transferMediaRows moves (or duplicates, for copies) the media library rows
+// of transferred originals, pointing their variants at the new paths.
+func (a *App) transferMediaRows(ctx context.Context, storage Storage, done []storageTransferItem, destinations map[string]storageTransferItem, move bool) (int, error) {
+	paths := make([]string, 0, len(done))
+	for _, item := range done {
+		paths = append(paths, item.From)
+	}
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "media.transfer_lookup"),
+		`SELECT path, variants FROM public.media WHERE bucket = $1 AND path = ANY($2)`,
+		done[0].FromBucket,
+		paths,
+	)
+	if err != nil {
+		return 0, err
+	}
+	variantsByPath := map[string][]mediaVariant{}
+	for rows.Next() {
+		var (
+			objectPath string
+			raw        []byte
+			variants   []mediaVariant
+		)
+		if err := rows.Scan(&objectPath, &raw); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		_ = json.Unmarshal(raw, &variants)
+		variantsByPath[objectPath] = variants
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	defer func() {
+		if updated > 0 {
+			a.Responses.invalidate("media")
+		}
+	}()
+	for _, item := range done {
+		variants, ok := variantsByPath[item.From]
+		if !ok {
+			continue
+		}
+		for i := range variants {
+			target, moved := destinations[mediaKey(item.FromBucket, variants[i].Path)]
+			if moved {
+				variants[i].Path = target.To
+			} else {
+				variants[i].Path = relocateVariantPath(variants[i].Path, item.From, item.To)
+			}
+			variants[i].URL = storage.PublicURL(item.ToBucket, variants[i].Path)
+		}
+		if variants == nil {
+			variants = []mediaVariant{}
+		}
+		rawVariants, err := json.Marshal(variants)
+		if err != nil {
+			return updated, err
+		}
+
+		query := `UPDATE public.media
+			SET bucket = $3, path = $4, public_url = $5, variants = $6::jsonb, backend = $7, updated_at = NOW()
+			WHERE bucket = $1 AND path = $2`
+		if !move {
+			query = `INSERT INTO public.media
+				(backend, bucket, path, public_url, size_bytes, mime_type, width, height, checksum_sha256, alt_text, tags, uploaded_by, variants)
+			SELECT $7, $3, $4, $5, size_bytes, mime_type, width, height, checksum_sha256, alt_text, tags, uploaded_by, $6::jsonb
+			FROM public.media
+			WHERE bucket = $1 AND path = $2
+			ON CONFLICT (bucket, path) DO NOTHING`
+		}
+		result, err := a.DB.ExecContext(
+			withQueryName(ctx, "media.transfer"),
+			query,
+			item.FromBucket,
+			item.From,
+			item.ToBucket,
+			item.To,
+			storage.PublicURL(item.ToBucket, item.To),
+			string(rawVariants),
+			storage.Backend(),
+		)
+		if err != nil {
+			return updated, fmt.Errorf("%s: %w", mediaKey(item.FromBucket, item.From), err)
+		}
+		if affected, err := result.RowsAffected(); err == nil {
+			updated += int(affected)
+		}
+	}
+	return updated, nil
+}
+
+// rewriteMediaReferences swaps links to moved objects in content rows, in
+// one transaction. Plain text cells, JSON arrays (also stored as text) and
+// text[] columns are all handled.
+func (a *App) rewriteMediaReferences(ctx context.Context, storage Storage, refs []mediaReference, destinations map[string]storageTransferItem) (int, error) {
+	columnTypes, err := a.availableMediaColumns(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rewritten := 0
+	var tables []string
+	for _, ref := range refs {
+		target, ok := destinations[mediaKey(ref.Bucket, ref.Path)]
+		if !ok {
+			continue
+		}
+		newURL := storage.PublicURL(target.ToBucket, target.To)
+
+		column := quoteIdentifier(ref.Column)
+		var expression string
+		switch columnTypes[ref.Table+"."+ref.Column] {
+		case "jsonb", "json":
+			expression = fmt.Sprintf(`CASE
+				WHEN jsonb_typeof(%[1]s::jsonb) = 'array' THEN (
+					SELECT COALESCE(jsonb_agg(CASE WHEN e.v = to_jsonb($2::text) THEN to_jsonb($3::text) ELSE e.v END ORDER BY e.n), '[]'::jsonb)
+					FROM jsonb_array_elements(%[1]s::jsonb) WITH ORDINALITY AS e(v, n)
+				)
+				WHEN %[1]s::jsonb = to_jsonb($2::text) THEN to_jsonb($3::text)
+				ELSE %[1]s::jsonb
+			END`, column)
+			if columnTypes[ref.Table+"."+ref.Column] == "json" {
+				expression = "(" + expression + ")::json"
+			}
+		case "ARRAY":
+			expression = fmt.Sprintf(`array_replace(%s, $2::text, $3::text)`, column)
+		default:
+			expression = fmt.Sprintf(`CASE WHEN %[1]s = $2 THEN $3 ELSE replace(%[1]s, to_json($2::text)::text, to_json($3::text)::text) END`, column)
+		}
+
+		result, err := tx.ExecContext(
+			withQueryName(ctx, "media.rewrite_url."+ref.Table),
+			fmt.Sprintf(`UPDATE %s SET %s = %s WHERE id = $1`, quoteTableName("public."+ref.Table), column, expression),
+			ref.RowID,
+			ref.URL,
+			newURL,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("rewrite %s.%s #%d: %w", ref.Table, ref.Column, ref.RowID, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+			rewritten++
+			if !slices.Contains(tables, ref.Table) {
+				tables = append(tables, ref.Table)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	// Public responses still carry the old URLs, whose objects are about
+	// to be deleted; the listener may be off, so drop them here too.
+	if len(tables) > 0 {
+		a.Responses.invalidate(tables...)
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "carbon_go"
+
+var tracer = otel.Tracer(tracerName)
+
+// setupTracing configures the global tracer provider. Tracing is off unless
+// TRACING_ENABLED is set; the exporter honours the standard OTEL_EXPORTER_OTLP_*
+// variables (default endpoint http://localhost:4318).
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !isTruthy(os.Getenv("TRACING_ENABLED")) {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", firstNonEmpty(os.Getenv("OTEL_SERVICE_NAME"), "carbon_go")),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(traceSampleRatio()))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func traceSampleRatio() float64 {
+	raw := strings.TrimSpace(os.Getenv("TRACING_SAMPLE_RATIO"))
+	if raw == "" {
+		return 1
+	}
+	ratio, err := strconv.ParseFloat(raw, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 1
+	}
+	return ratio
+}
+
+// tracingMiddleware starts a server span per request, continuing any
+// incoming W3C trace context. The span is renamed to the matched route.
+func tracingMiddleware(next http.Handler) http.Handler {
+	propagator := otel.GetTextMapPropagator()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("user_agent.original", r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		if info := requestInfoFromContext(ctx); info != nil {
+			span.SetAttributes(attribute.String("request.id", info.ID), attribute.String("client.address", info.ClientIP))
+		}
+
+		traced := r.WithContext(ctx)
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, traced)
+
+		if traced.Pattern != "" {
+			span.SetName(r.Method + " " + traced.Pattern)
+			span.SetAttributes(attribute.String("http.route", traced.Pattern))
+		}
+		status := rec.statusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// tracedHTTPDo sends an outbound request inside a client span and propagates
+// the trace context to the remote side.
+func tracedHTTPDo(client *http.Client, req *http.Request, spanName string) (*http.Response, error) {
+	ctx, span := tracer.Start(req.Context(), spanName, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+type queryNameKey struct{}
+
+// withQueryName labels the next SQL statements issued with ctx, e.g. to tell
+// fallback queries apart ("tuning.list#3").
+func withQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// pgxQueryTracer creates a span for every statement executed through pgx.
+type pgxQueryTracer struct{}
+
+type pgxQuerySpanKey struct{}
+
+func (pgxQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	if name == "" {
+		name = sqlStatementName(data.SQL)
+	}
+
+	ctx, span := tracer.Start(ctx, "sql "+name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement.name", name),
+			attribute.String("db.query.text", compactSQL(data.SQL)),
+		),
+	)
+	return context.WithValue(ctx, pgxQuerySpanKey{}, span)
+}
+
+func (pgxQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(pgxQuerySpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("db.rows", data.CommandTag.RowsAffected()))
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+}
+
+// sqlStatementName derives "<OPERATION> <table>" from a statement.
+func sqlStatementName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+
+	operation := strings.ToUpper(fields[0])
+	target := ""
+	for i := 0; i < len(fields)-1; i++ {
+		switch strings.ToUpper(fields[i]) {
+		case "FROM", "INTO", "UPDATE":
+			target = strings.Trim(fields[i+1], "(),;")
+		}
+		if target != "" {
+			break
+		}
+	}
+	if target == "" {
+		return operation
+	}
+	return operation + " " + target
+}
+
+// compactSQL collapses whitespace so multi-line statements read well in a trace UI.
+func compactSQL(query string) string {
+	compact := strings.Join(strings.Fields(query), " ")
+	if len(compact) > 2000 {
+		compact = compact[:2000] + "..."
+	}
+	return compact
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultContentLocale is the language of the plain text columns; other
+// locales live in each row's translations column as {"uz": {"title": ...}}.
+const defaultContentLocale = "ru"
+
+var contentLocales = []string{"ru", "uz", "en"}
+
+// contentTranslations is a decoded translations column: locale -> column ->
+// text (or a list of strings for JSON array columns such as work_list).
+type contentTranslations map[string]map[string]any
+
+func parseContentTranslations(raw []byte) contentTranslations {
+	if len(raw) == 0 {
+		return nil
+	}
+	var translations contentTranslations
+	if err := json.Unmarshal(raw, &translations); err != nil {
+		return nil
+	}
+	return translations
+}
+
+// localeChain lists the locales to try for each field, best first; it always
+// ends with defaultContentLocale, whose text is the column itself.
+type localeChain []string
+
+// requestLocales resolves ?lang= and Accept-Language into a fallback chain:
+// the explicit lang, then Accept-Language by preference, then the default.
+func requestLocales(r *http.Request) localeChain {
+	var chain localeChain
+	add := func(tag string) {
+		if locale := supportedLocale(tag); locale != "" && !slices.Contains(chain, locale) {
+			chain = append(chain, locale)
+		}
+	}
+	add(r.URL.Query().Get("lang"))
+	for _, tag := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+		add(tag)
+	}
+	add(defaultContentLocale)
+	return chain
+}
+
+// supportedLocale maps a language tag ("uz-Latn-UZ", "EN") to a content
+// locale, or "" when unsupported.
+func supportedLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if base, _, found := strings.Cut(tag, "-"); found {
+		tag = base
+	}
+	if slices.Contains(contentLocales, tag) {
+		return tag
+	}
+	return ""
+}
+
+// acceptedLanguages returns the Accept-Language tags ordered by q, dropping
+// q=0 and "*".
+func acceptedLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		out = append(out, tag.tag)
+	}
+	return out
+}
+
+// setHeaders announces the resolved locale. Vary keeps shared caches from
+// mixing languages for requests without ?lang=.
+func (c localeChain) setHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Language", c[0])
+	w.Header().Add("Vary", "Accept-Language")
+}
+
+// translated returns the locales tried before the default, the ones whose
+// text comes from the translations column.
+func (c localeChain) translated() []string {
+	if i := slices.Index(c, defaultContentLocale); i >= 0 {
+		return c[:i]
+	}
+	return c
+}
+
+// text returns the first translation of column along the chain, or base.
+func (c localeChain) text(translations contentTranslations, column, base string) string {
+	for _, locale := range c {
+		if locale == defaultContentLocale {
+			return base
+		}
+		if value, ok := translations[locale][column].(string); ok && strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return base
+}
+
+// textPtr is text for nullable columns; a translation can fill a NULL base.
+func (c localeChain) textPtr(translations contentTranslations, column string, base *string) *string {
+	value := c.text(translations, column, stringOrEmpty(base))
+	if value == "" {
+		return base
+	}
+	return &value
+}
+
+// list is text for JSON array columns.
+func (c localeChain) list(translations contentTranslations, column string, base []string) []string {
+	for _, locale := range c {
+		if locale == defaultContentLocale {
+			return base
+		}
+		if values, ok := translations[locale][column].([]any); ok && len(values) > 0 {
+			out := make([]string, 0, len(values))
+			for _, value := range values {
+				if text, ok := value.(string); ok && strings.TrimSpace(text) != "" {
+					out = append(out, text)
+				}
+			}
+			if len(out) > 0 {
+				return out
+			}
+		}
+	}
+	return base
+}
+
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// translationErrors validates a "translations" admin payload: an object of
+// non-default locales, each an object of translatable columns with string
+// (or, for JSON columns, string array) values. null removes a locale on
+// update, or a column inside a locale.
+func translationErrors(payload map[string]any, cfg tableCRUDConfig) map[string]string {
+	raw, ok := payload["translations"]
+	if !ok || raw == nil {
+		return nil
+	}
+	errs := map[string]string{}
+	locales, ok := raw.(map[string]any)
+	if !ok {
+		errs["translations"] = "must be an object keyed by locale"
+		return errs
+	}
+	for locale, value := range locales {
+		key := "translations." + locale
+		if locale == defaultContentLocale {
+			errs[key] = fmt.Sprintf("%s text is stored in the columns themselves", defaultContentLocale)
+			continue
+		}
+		if !slices.Contains(contentLocales, locale) {
+			errs[key] = "unsupported locale (expected one of " + strings.Join(contentLocales, ", ") + ")"
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		fields, ok := value.(map[string]any)
+		if !ok {
+			errs[key] = "must be an object keyed by field"
+			continue
+		}
+		for column, text := range fields {
+			fieldKey := key + "." + column
+			if _, ok := cfg.Translatable[column]; !ok {
+				errs[fieldKey] = "field is not translatable"
+				continue
+			}
+			switch typed := text.(type) {
+			case nil, string:
+			case []any:
+				if _, isJSON := cfg.JSONColumns[column]; !isJSON {
+					errs[fieldKey] = "must be a string"
+					continue
+				}
+				for _, item := range typed {
+					if _, ok := item.(string); !ok {
+						errs[fieldKey] = "must be a list of strings"
+						break
+					}
+				}
+			default:
+				errs[fieldKey] = "must be a string"
+			}
+		}
+	}
+	return errs
+}
+
+type missingTranslation struct {
+	ID      int64    `json:"id"`
+	Missing []string `json:"missing"`
+}
+
+// adminMissingTranslationsHandler serves GET /admin/translations/missing and
+// lists, per locale and resource, the records whose filled-in columns have
+// no translation. ?locale= and ?resource= (e.g. tuning) narrow the report.
+func (a *App) adminMissingTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdminToken(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+		return
+	}
+
+	var locales []string
+	if raw := strings.TrimSpace(r.URL.Query().Get("locale")); raw != "" {
+		locale := supportedLocale(raw)
+		if locale == "" || locale == defaultContentLocale {
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"status":  "error",
+				"message": "locale must be one of the translated locales",
+			})
+			return
+		}
+		locales = []string{locale}
+	} else {
+		for _, locale := range contentLocales {
+			if locale != defaultContentLocale {
+				locales = append(locales, locale)
+			}
+		}
+	}
+	resourceFilter := strings.TrimSpace(r.URL.Query().Get("resource"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), readTimeout)
+	defer cancel()
+
+	report := map[string]any{}
+	resources := map[string]map[string][]missingTranslation{}
+	for _, locale := range locales {
+		resources[locale] = map[string][]missingTranslation{}
+	}
+	for _, cfg := range adminCRUDConfigs() {
+		resource := strings.TrimPrefix(cfg.Path, "/admin/")
+		if len(cfg.Translatable) == 0 || (resourceFilter != "" && resource != resourceFilter) {
+			continue
+		}
+		exists, err := hasTable(ctx, a.DB, strings.TrimPrefix(cfg.Table, "public."))
+		if err != nil {
+			logFromContext(ctx).Error("translation report table lookup failed", "table", cfg.Table, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to fetch data",
+			})
+			return
+		}
+		if !exists {
+			continue
+		}
+		missing, err := a.missingTranslations(ctx, cfg, locales)
+		if err != nil {
+			logFromContext(ctx).Error("translation report failed", "table", cfg.Table, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to fetch data",
+			})
+			return
+		}
+		for locale, records := range missing {
+			if len(records) > 0 {
+				resources[locale][resource] = records
+			}
+		}
+	}
+
+	for _, locale := range locales {
+		fields, records := 0, 0
+		for _, items := range resources[locale] {
+			records += len(items)
+			for _, item := range items {
+				fields += len(item.Missing)
+			}
+		}
+		report[locale] = map[string]any{
+			"missing_fields": fields,
+			"records":        records,
+			"resources":      resources[locale],
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+// missingTranslations checks every row of one table. A column counts as
+// missing for a locale when the base text is filled in and the locale has
+// no non-empty translation for it.
+func (a *App) missingTranslations(ctx context.Context, cfg tableCRUDConfig, locales []string) (map[string][]missingTranslation, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "translations.missing"),
+		fmt.Sprintf(`SELECT t.id, to_jsonb(t) FROM %s t ORDER BY t.id`, quoteTableName(cfg.Table)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]string, 0, len(cfg.Translatable))
+	for column := range cfg.Translatable {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	out := map[string][]missingTranslation{}
+	for rows.Next() {
+		var id int64
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		var row map[string]any
+		if err := json.Unmarshal(raw, &row); err != nil {
+			return nil, err
+		}
+		translations, _ := row["translations"].(map[string]any)
+
+		for _, locale := range locales {
+			fields, _ := translations[locale].(map[string]any)
+			var missing []string
+			for _, column := range columns {
+				if isBlankContent(row[column]) {
+					continue
+				}
+				if isBlankContent(fields[column]) {
+					missing = append(missing, column)
+				}
+			}
+			if len(missing) > 0 {
+				out[locale] = append(out[locale], missingTranslation{ID: id, Missing: missing})
+			}
+		}
+	}
+	return out, rows.Err()
+}
+
+func isBlankContent(value any) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(typed) == ""
+	case []any:
+		return len(typed) == 0
+	}
+	return false
+}
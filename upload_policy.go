@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	uploadSniffBytes            = 4096
+	maxSVGScanBytes             = 2 << 20
+	defaultResumableUploadMaxMB = 2048
+
+	uploadNamingOriginal = "original"
+	uploadNamingHash     = "hash"
+)
+
+var defaultUploadAllowedTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"image/heic",
+	"video/mp4",
+	"video/webm",
+	"video/quicktime",
+	"application/pdf",
+}
+
+// blockedUploadTypes are rejected whatever a bucket policy says: they either
+// run on the client machine or execute script when served from our origin.
+var blockedUploadTypes = map[string]struct{}{
+	"application/x-msdownload":            {},
+	"application/x-executable":            {},
+	"application/x-mach-binary":           {},
+	"application/x-sh":                    {},
+	"application/java-archive":            {},
+	"application/javascript":              {},
+	"text/html":                           {},
+	"application/xhtml+xml":               {},
+	"application/x-shockwave-flash":       {},
+	"application/vnd.microsoft.installer": {},
+}
+
+// uploadTypeExtensions lists the filename extensions accepted for each
+// detected type; the first one is used when a name has no extension.
+var uploadTypeExtensions = map[string][]string{
+	"image/jpeg":      {".jpg", ".jpeg", ".jfif"},
+	"image/png":       {".png"},
+	"image/gif":       {".gif"},
+	"image/webp":      {".webp"},
+	"image/avif":      {".avif"},
+	"image/heic":      {".heic", ".heif"},
+	"image/svg+xml":   {".svg"},
+	"image/bmp":       {".bmp"},
+	"video/mp4":       {".mp4", ".m4v"},
+	"video/webm":      {".webm"},
+	"video/quicktime": {".mov", ".qt"},
+	"application/pdf": {".pdf"},
+	"application/zip": {".zip"},
+	"text/plain":      {".txt", ".csv", ".md"},
+}
+
+// svgActiveContentPattern is a best-effort screen for buckets that opt in to
+// image/svg+xml; entity-encoded or animated attributes can slip past it, so
+// SVG stays out of defaultUploadAllowedTypes and /files serves everything
+// under a sandboxing CSP.
+var svgActiveContentPattern = regexp.MustCompile(`(?i)<script|<foreignobject|<iframe|<embed|<object|javascript:|\son[a-z]+\s*=|<!entity`)
+
+// uploadPolicy restricts what may be stored in a bucket. Zero values inherit
+// from the default policy.
+type uploadPolicy struct {
+	AllowedTypes     []string `json:"allowed_types,omitempty"`
+	MaxSizeMB        int      `json:"max_size_mb,omitempty"`
+	MaxWidth         int      `json:"max_width,omitempty"`
+	MaxHeight        int      `json:"max_height,omitempty"`
+	RequiredPrefixes []string `json:"required_prefixes,omitempty"`
+	// Dedup returns the existing object when the same bytes are already
+	// stored in the bucket instead of writing a copy (default true).
+	Dedup *bool `json:"dedup,omitempty"`
+	// Naming is "original" (the sanitised filename) or "hash", which stores
+	// files as <folder>/<sha256>.<ext> so their URLs can be cached forever.
+	Naming string `json:"naming,omitempty"`
+}
+
+type uploadPolicySet struct {
+	Default uploadPolicy
+	Buckets map[string]uploadPolicy
+	// ResumableMaxMB caps chunked uploads to buckets without their own
+	// max_size_mb, since those exist for files above the form limit.
+	ResumableMaxMB int
+}
+
+// loadUploadPolicies reads STORAGE_UPLOAD_POLICIES, a JSON object keyed by
+// bucket ("*" overrides the default for every bucket), e.g.
+// {"cars":{"allowed_types":["image/*"],"max_size_mb":10,"max_width":8000,
+// "max_height":8000,"required_prefixes":["tuning","portfolio"],"dedup":true,
+// "naming":"hash"}}, and RESUMABLE_UPLOAD_MAX_MB.
+func loadUploadPolicies() (uploadPolicySet, error) {
+	set := uploadPolicySet{
+		Default: uploadPolicy{AllowedTypes: defaultUploadAllowedTypes, MaxSizeMB: storageUploadMaxMB, Naming: uploadNamingOriginal},
+		Buckets: map[string]uploadPolicy{},
+	}
+	resumableMB, err := parseIntOrDefault(os.Getenv("RESUMABLE_UPLOAD_MAX_MB"), defaultResumableUploadMaxMB)
+	if err != nil || resumableMB <= 0 {
+		return uploadPolicySet{}, errors.New("RESUMABLE_UPLOAD_MAX_MB must be a positive integer")
+	}
+	set.ResumableMaxMB = resumableMB
+
+	raw := strings.TrimSpace(os.Getenv("STORAGE_UPLOAD_POLICIES"))
+	if raw == "" {
+		return set, nil
+	}
+
+	var parsed map[string]uploadPolicy
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return uploadPolicySet{}, fmt.Errorf("STORAGE_UPLOAD_POLICIES: %w", err)
+	}
+	for bucket, policy := range parsed {
+		if policy.Naming != "" && policy.Naming != uploadNamingOriginal && policy.Naming != uploadNamingHash {
+			return uploadPolicySet{}, fmt.Errorf("STORAGE_UPLOAD_POLICIES: %q: naming must be %s or %s", bucket, uploadNamingOriginal, uploadNamingHash)
+		}
+	}
+	if fallback, ok := parsed["*"]; ok {
+		set.Default = mergeUploadPolicy(set.Default, fallback)
+		delete(parsed, "*")
+	}
+	for bucket, policy := range parsed {
+		if _, err := cleanStorageBucket(bucket); err != nil {
+			return uploadPolicySet{}, fmt.Errorf("STORAGE_UPLOAD_POLICIES: %q: %w", bucket, err)
+		}
+		if policy.MaxSizeMB < 0 || policy.MaxWidth < 0 || policy.MaxHeight < 0 {
+			return uploadPolicySet{}, fmt.Errorf("STORAGE_UPLOAD_POLICIES: %q: limits must not be negative", bucket)
+		}
+		set.Buckets[bucket] = policy
+	}
+	return set, nil
+}
+
+func mergeUploadPolicy(base, override uploadPolicy) uploadPolicy {
+	if len(override.AllowedTypes) > 0 {
+		base.AllowedTypes = override.AllowedTypes
+	}
+	if override.MaxSizeMB > 0 {
+		base.MaxSizeMB = override.MaxSizeMB
+	}
+	if override.MaxWidth > 0 {
+		base.MaxWidth = override.MaxWidth
+	}
+	if override.MaxHeight > 0 {
+		base.MaxHeight = override.MaxHeight
+	}
+	if len(override.RequiredPrefixes) > 0 {
+		base.RequiredPrefixes = override.RequiredPrefixes
+	}
+	if override.Dedup != nil {
+		base.Dedup = override.Dedup
+	}
+	if override.Naming != "" {
+		base.Naming = override.Naming
+	}
+	return base
+}
+
+func (p uploadPolicy) dedupEnabled() bool {
+	return p.Dedup == nil || *p.Dedup
+}
+
+func (s uploadPolicySet) forBucket(bucket string) uploadPolicy {
+	policy, ok := s.Buckets[bucket]
+	if !ok {
+		return s.Default
+	}
+	return mergeUploadPolicy(s.Default, policy)
+}
+
+// forResumable is forBucket with the larger chunked-upload size limit unless
+// the bucket sets max_size_mb itself.
+func (s uploadPolicySet) forResumable(bucket string) uploadPolicy {
+	policy := s.forBucket(bucket)
+	if s.Buckets[bucket].MaxSizeMB == 0 {
+		policy.MaxSizeMB = max(policy.MaxSizeMB, s.ResumableMaxMB)
+	}
+	return policy
+}
+
+// maxUploadBytes is the largest file any bucket accepts; it bounds the
+// request body before the target bucket is known.
+func (s uploadPolicySet) maxUploadBytes() int64 {
+	largest := s.Default.MaxSizeMB
+	for bucket := range s.Buckets {
+		largest = max(largest, s.forBucket(bucket).MaxSizeMB)
+	}
+	return int64(largest) << 20
+}
+
+func (p uploadPolicy) maxBytes() int64 {
+	return int64(p.MaxSizeMB) << 20
+}
+
+// dimensionLimit describes MaxWidth/MaxHeight, e.g. "4000x3000" or "4000 px wide".
+func (p uploadPolicy) dimensionLimit() string {
+	switch {
+	case p.MaxWidth > 0 && p.MaxHeight > 0:
+		return fmt.Sprintf("%dx%d", p.MaxWidth, p.MaxHeight)
+	case p.MaxWidth > 0:
+		return fmt.Sprintf("%d px wide", p.MaxWidth)
+	}
+	return fmt.Sprintf("%d px high", p.MaxHeight)
+}
+
+func (p uploadPolicy) allows(contentType string) bool {
+	family, _, _ := strings.Cut(contentType, "/")
+	for _, allowed := range p.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "*/*" || allowed == contentType || allowed == family+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p uploadPolicy) allowsPath(objectPath string) bool {
+	if len(p.RequiredPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range p.RequiredPrefixes {
+		prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+		if prefix == "" || objectPath == prefix || strings.HasPrefix(objectPath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadRejection is a policy violation with the HTTP status and the
+// limits the client should have respected.
+type uploadRejection struct {
+	StatusCode int
+	Message    string
+	Details    map[string]any
+}
+
+func (e *uploadRejection) Error() string {
+	return e.Message
+}
+
+func writeUploadRejection(w http.ResponseWriter, rejection *uploadRejection) {
+	payload := map[string]any{
+		"status":  "error",
+		"message": rejection.Message,
+	}
+	for key, value := range rejection.Details {
+		payload[key] = value
+	}
+	writeJSON(w, rejection.StatusCode, payload)
+}
+
+func (p uploadPolicy) tooLarge(bucket string, size int64) *uploadRejection {
+	return &uploadRejection{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Message:    fmt.Sprintf("file is larger than %d MB allowed in bucket %s", p.MaxSizeMB, bucket),
+		Details: map[string]any{
+			"size":           size,
+			"max_size_bytes": p.maxBytes(),
+			"max_size_mb":    p.MaxSizeMB,
+		},
+	}
+}
+
+func (p uploadPolicy) outsidePrefixes(bucket string) *uploadRejection {
+	return &uploadRejection{
+		StatusCode: http.StatusBadRequest,
+		Message:    fmt.Sprintf("uploads to bucket %s must go into one of the allowed folders", bucket),
+		Details:    map[string]any{"allowed_prefixes": p.RequiredPrefixes},
+	}
+}
+
+// checkSignedUpload applies the parts of the policy that can be judged from
+// the path alone, for uploads that go straight to storage: the folder prefix
+// and an extension belonging to an allowed type.
+func (p uploadPolicy) checkSignedUpload(bucket, objectPath string) *uploadRejection {
+	if !p.allowsPath(objectPath) {
+		return p.outsidePrefixes(bucket)
+	}
+	ext := strings.ToLower(path.Ext(objectPath))
+	for contentType, extensions := range uploadTypeExtensions {
+		if _, blocked := blockedUploadTypes[contentType]; blocked || !p.allows(contentType) {
+			continue
+		}
+		if slices.Contains(extensions, ext) {
+			return nil
+		}
+	}
+	allowed := slices.Clone(p.AllowedTypes)
+	sort.Strings(allowed)
+	return &uploadRejection{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Message:    fmt.Sprintf("extension %q is not accepted in bucket %s", ext, bucket),
+		Details:    map[string]any{"allowed_types": allowed},
+	}
+}
+
+// uploadCheck is what the policy learned about an accepted upload.
+type uploadCheck struct {
+	ContentType string
+	Filename    string
+}
+
+// check sniffs the file, enforces the bucket policy and returns the
+// detected content type plus a filename whose extension matches it. The
+// client-supplied Content-Type is ignored. file is rewound before returning.
+func (p uploadPolicy) check(file io.ReadSeeker, size int64, bucket, objectPath, filename string) (uploadCheck, error) {
+	if p.MaxSizeMB > 0 && size > p.maxBytes() {
+		return uploadCheck{}, p.tooLarge(bucket, size)
+	}
+	if !p.allowsPath(objectPath) {
+		return uploadCheck{}, p.outsidePrefixes(bucket)
+	}
+
+	head := make([]byte, uploadSniffBytes)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return uploadCheck{}, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return uploadCheck{}, err
+	}
+	contentType := sniffContentType(head[:n])
+
+	unsupported := func(message string) *uploadRejection {
+		allowed := slices.Clone(p.AllowedTypes)
+		sort.Strings(allowed)
+		return &uploadRejection{
+			StatusCode: http.StatusUnsupportedMediaType,
+			Message:    message,
+			Details: map[string]any{
+				"detected_type": contentType,
+				"allowed_types": allowed,
+			},
+		}
+	}
+	if _, blocked := blockedUploadTypes[contentType]; blocked {
+		return uploadCheck{}, unsupported(fmt.Sprintf("%s files are never accepted", contentType))
+	}
+	if !p.allows(contentType) {
+		return uploadCheck{}, unsupported(fmt.Sprintf("file type %s is not allowed in bucket %s", contentType, bucket))
+	}
+
+	if contentType == "image/svg+xml" {
+		body, err := io.ReadAll(io.LimitReader(file, maxSVGScanBytes+1))
+		if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+			return uploadCheck{}, seekErr
+		}
+		if err != nil {
+			return uploadCheck{}, err
+		}
+		if len(body) > maxSVGScanBytes || svgActiveContentPattern.Match(body) {
+			return uploadCheck{}, unsupported("SVG files with scripts, event handlers or embedded documents are not accepted")
+		}
+	}
+
+	if p.MaxWidth > 0 || p.MaxHeight > 0 {
+		header, _, err := image.DecodeConfig(file)
+		if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+			return uploadCheck{}, seekErr
+		}
+		if err == nil && ((p.MaxWidth > 0 && header.Width > p.MaxWidth) || (p.MaxHeight > 0 && header.Height > p.MaxHeight)) {
+			return uploadCheck{}, &uploadRejection{
+				StatusCode: http.StatusRequestEntityTooLarge,
+				Message:    fmt.Sprintf("image is %dx%d; bucket %s allows at most %s", header.Width, header.Height, bucket, p.dimensionLimit()),
+				Details: map[string]any{
+					"width":      header.Width,
+					"height":     header.Height,
+					"max_width":  p.MaxWidth,
+					"max_height": p.MaxHeight,
+				},
+			}
+		}
+	}
+
+	// Keep the extension honest so backends that type files by name (local
+	// disk, CDNs) serve what was actually checked.
+	ext := strings.ToLower(path.Ext(filename))
+	if extensions, known := uploadTypeExtensions[contentType]; known {
+		switch {
+		case ext == "":
+			filename += extensions[0]
+		case !slices.Contains(extensions, ext):
+			return uploadCheck{}, unsupported(fmt.Sprintf("file extension %s does not match detected type %s", ext, contentType))
+		}
+	}
+	return uploadCheck{ContentType: contentType, Filename: filename}, nil
+}
+
+// sniffContentType detects the type from the leading bytes. It extends
+// http.DetectContentType with executables, SVG and ISO-BMFF brands
+// (MOV/HEIC/AVIF), and drops parameters such as charset.
+func sniffContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("MZ")):
+		return "application/x-msdownload"
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return "application/x-executable"
+	case bytes.HasPrefix(head, []byte("#!")):
+		return "application/x-sh"
+	case bytes.HasPrefix(head, []byte{0xCF, 0xFA, 0xED, 0xFE}),
+		bytes.HasPrefix(head, []byte{0xCE, 0xFA, 0xED, 0xFE}),
+		bytes.HasPrefix(head, []byte{0xFE, 0xED, 0xFA, 0xCF}),
+		bytes.HasPrefix(head, []byte{0xCA, 0xFE, 0xBA, 0xBE}):
+		return "application/x-mach-binary"
+	case bytes.HasPrefix(head, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}) && bytes.Contains(head, []byte("I\x00n\x00s\x00t\x00a\x00l\x00l")):
+		return "application/vnd.microsoft.installer"
+	}
+
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		switch brand := string(head[8:12]); brand {
+		case "qt  ":
+			return "video/quicktime"
+		case "heic", "heix", "hevc", "heim", "heis", "mif1", "msf1":
+			return "image/heic"
+		case "avif", "avis":
+			return "image/avif"
+		}
+	}
+
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	switch detected {
+	case "text/xml", "text/plain", "application/xml":
+		lower := bytes.ToLower(head)
+		if bytes.Contains(lower, []byte("<svg")) {
+			return "image/svg+xml"
+		}
+		if bytes.Contains(lower, []byte("<html")) || bytes.Contains(lower, []byte("<script")) {
+			return "text/html"
+		}
+	case "application/zip":
+		// JARs are zips whose first entry is usually the manifest.
+		if bytes.Contains(head, []byte("META-INF/")) {
+			return "application/java-archive"
+		}
+	}
+	if detected == "" {
+		return "application/octet-stream"
+	}
+	return detected
+}
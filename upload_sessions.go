@@ -0,0 +1,733 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	uploadSessionsRoute          = "/admin/storage/uploads"
+	defaultUploadSessionDir      = "upload_sessions"
+	defaultUploadSessionTTL      = 24 * time.Hour
+	defaultUploadChunkMaxMB      = 16
+	uploadSessionCleanupInterval = time.Hour
+	uploadSessionCompleteTimeout = 30 * time.Minute
+)
+
+var (
+	uploadSessionIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	sha256HexPattern       = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	errUploadSpoolTruncated = errors.New("upload spool is shorter than the recorded offset")
+)
+
+// uploadSessionConfig controls resumable uploads. Chunks are spooled to
+// local disk, so a session can only be continued on the instance that
+// started it; sessions and their offsets live in Postgres.
+type uploadSessionConfig struct {
+	Dir        string
+	TTL        time.Duration
+	ChunkMaxMB int
+}
+
+// loadUploadSessionConfig reads UPLOAD_SESSION_DIR, UPLOAD_SESSION_TTL
+// (idle time before an unfinished upload is discarded) and
+// UPLOAD_CHUNK_MAX_MB.
+func loadUploadSessionConfig() (uploadSessionConfig, error) {
+	cfg := uploadSessionConfig{
+		Dir: envOrDefault("UPLOAD_SESSION_DIR", defaultUploadSessionDir),
+		TTL: parseDurationOrDefault(os.Getenv("UPLOAD_SESSION_TTL"), defaultUploadSessionTTL),
+	}
+	chunkMB, err := parseIntOrDefault(os.Getenv("UPLOAD_CHUNK_MAX_MB"), defaultUploadChunkMaxMB)
+	if err != nil || chunkMB <= 0 {
+		return uploadSessionConfig{}, errors.New("UPLOAD_CHUNK_MAX_MB must be a positive integer")
+	}
+	cfg.ChunkMaxMB = chunkMB
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return uploadSessionConfig{}, fmt.Errorf("create UPLOAD_SESSION_DIR: %w", err)
+	}
+	return cfg, nil
+}
+
+type uploadSession struct {
+	ID           string    `json:"id"`
+	Bucket       string    `json:"bucket"`
+	Folder       string    `json:"folder"`
+	Filename     string    `json:"filename"`
+	TotalSize    int64     `json:"size"`
+	ReceivedSize int64     `json:"received"`
+	Checksum     string    `json:"checksum_sha256,omitempty"`
+	Upsert       bool      `json:"upsert"`
+	Process      bool      `json:"process"`
+	AltText      string    `json:"alt_text,omitempty"`
+	Tags         []string  `json:"tags"`
+	UploadedBy   string    `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type createUploadSessionRequest struct {
+	Bucket   string   `json:"bucket"`
+	Folder   string   `json:"folder"`
+	Filename string   `json:"filename"`
+	Size     int64    `json:"size"`
+	Checksum string   `json:"checksum_sha256"`
+	Upsert   *bool    `json:"upsert"`
+	Process  *bool    `json:"process"`
+	AltText  string   `json:"alt_text"`
+	Tags     []string `json:"tags"`
+}
+
+// uploadSessionLocks marks sessions with a chunk or completion in flight so
+// two requests never write the same spool file at once.
+var uploadSessionLocks sync.Map
+
+func (c uploadSessionConfig) spoolPath(id string) string {
+	return filepath.Join(c.Dir, id+".part")
+}
+
+// adminUploadSessionsHandler implements the chunked upload protocol:
+//
+//	POST   /admin/storage/uploads               start a session
+//	GET    /admin/storage/uploads/{id}          current offset (resume point)
+//	PATCH  /admin/storage/uploads/{id}          append a chunk at Upload-Offset
+//	POST   /admin/storage/uploads/{id}/complete verify checksum and store
+//	DELETE /admin/storage/uploads/{id}          abort
+func (a *App) adminUploadSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdminToken(w, r) {
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, uploadSessionsRoute), "/")
+	if rest == "" {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+				"status":  "error",
+				"message": "method not allowed",
+			})
+			return
+		}
+		a.createUploadSession(w, r)
+		return
+	}
+
+	id, action, _ := strings.Cut(rest, "/")
+	if !uploadSessionIDPattern.MatchString(id) || (action != "" && action != "complete") {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"status":  "error",
+			"message": "upload session not found",
+		})
+		return
+	}
+
+	switch {
+	case action == "complete" && r.Method == http.MethodPost:
+		a.completeUploadSession(w, r, id)
+	case action == "" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		a.fetchUploadSession(w, r, id)
+	case action == "" && r.Method == http.MethodPatch:
+		a.appendUploadChunk(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		a.abortUploadSession(w, r, id)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+			"status":  "error",
+			"message": "method not allowed",
+		})
+	}
+}
+
+func (a *App) createUploadSession(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.storageOrError(w); !ok {
+		return
+	}
+
+	var req createUploadSessionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "invalid JSON body",
+		})
+		return
+	}
+
+	validationErrors := map[string]string{}
+	bucket, err := cleanStorageBucket(firstNonEmpty(req.Bucket, defaultStorageBucket()))
+	if err != nil {
+		validationErrors["bucket"] = err.Error()
+	}
+	folder, err := cleanOptionalStoragePath(req.Folder)
+	if err != nil {
+		validationErrors["folder"] = err.Error()
+	}
+	filename := sanitizeFilename(req.Filename)
+	if filename == "" {
+		validationErrors["filename"] = "filename is required"
+	}
+	if req.Size <= 0 {
+		validationErrors["size"] = "size must be a positive number of bytes"
+	}
+	checksum := strings.ToLower(strings.TrimSpace(req.Checksum))
+	if checksum != "" && !sha256HexPattern.MatchString(checksum) {
+		validationErrors["checksum_sha256"] = "must be a hex-encoded SHA-256 digest"
+	}
+	tags, err := normalizeMediaTags(req.Tags)
+	if err != nil {
+		validationErrors["tags"] = err.Error()
+	}
+	if len(validationErrors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"message": "validation failed",
+			"errors":  validationErrors,
+		})
+		return
+	}
+
+	// Size and folder can be enforced now; the type is checked on complete.
+	policy := a.UploadPolicies.forResumable(bucket)
+	if policy.MaxSizeMB > 0 && req.Size > policy.maxBytes() {
+		writeUploadRejection(w, policy.tooLarge(bucket, req.Size))
+		return
+	}
+	if !policy.allowsPath(joinStoragePath(folder, filename)) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":           "error",
+			"message":          fmt.Sprintf("uploads to bucket %s must go into one of the allowed folders", bucket),
+			"allowed_prefixes": policy.RequiredPrefixes,
+		})
+		return
+	}
+
+	idRaw := make([]byte, 16)
+	if _, err := rand.Read(idRaw); err != nil {
+		logFromContext(r.Context()).Error("upload session id failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to create upload session",
+		})
+		return
+	}
+	session := uploadSession{
+		ID:         hex.EncodeToString(idRaw),
+		Bucket:     bucket,
+		Folder:     folder,
+		Filename:   filename,
+		TotalSize:  req.Size,
+		Checksum:   checksum,
+		Upsert:     req.Upsert == nil || *req.Upsert,
+		Process:    req.Process == nil || *req.Process,
+		AltText:    strings.TrimSpace(req.AltText),
+		Tags:       tags,
+		UploadedBy: adminUsernameFromContext(r.Context()),
+	}
+
+	ctx := r.Context()
+	if err := os.WriteFile(a.UploadSessions.spoolPath(session.ID), nil, 0o600); err != nil {
+		logFromContext(ctx).Error("upload spool create failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to create upload session",
+		})
+		return
+	}
+
+	err = a.DB.QueryRowContext(
+		withQueryName(ctx, "upload_sessions.insert"),
+		`INSERT INTO public.upload_sessions
+			(id, bucket, folder, filename, total_size, checksum_sha256, upsert, process, alt_text, tags, uploaded_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::text[], '{}'), $11, NOW() + $12 * INTERVAL '1 second')
+		RETURNING created_at, expires_at`,
+		session.ID,
+		session.Bucket,
+		session.Folder,
+		session.Filename,
+		session.TotalSize,
+		optionalStringDBValue(session.Checksum),
+		session.Upsert,
+		session.Process,
+		optionalStringDBValue(session.AltText),
+		session.Tags,
+		optionalStringDBValue(session.UploadedBy),
+		int64(a.UploadSessions.TTL.Seconds()),
+	).Scan(&session.CreatedAt, &session.ExpiresAt)
+	if err != nil {
+		_ = os.Remove(a.UploadSessions.spoolPath(session.ID))
+		logFromContext(ctx).Error("upload session insert failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"status":  "error",
+			"message": "failed to create upload session",
+		})
+		return
+	}
+
+	w.Header().Set("Location", uploadSessionsRoute+"/"+session.ID)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"status": "success",
+		"data":   session,
+		"meta": map[string]any{
+			"chunk_max_bytes": int64(a.UploadSessions.ChunkMaxMB) << 20,
+			"upload_url":      uploadSessionsRoute + "/" + session.ID,
+			"complete_url":    uploadSessionsRoute + "/" + session.ID + "/complete",
+		},
+	})
+}
+
+func (a *App) loadUploadSession(ctx context.Context, id string) (uploadSession, error) {
+	var (
+		session  uploadSession
+		checksum sql.NullString
+		altText  sql.NullString
+		by       sql.NullString
+		tagsRaw  []byte
+	)
+	err := a.DB.QueryRowContext(
+		withQueryName(ctx, "upload_sessions.get"),
+		`SELECT id, bucket, folder, filename, total_size, received_size, checksum_sha256, upsert, process,
+			alt_text, array_to_json(tags), uploaded_by, created_at, expires_at
+		FROM public.upload_sessions
+		WHERE id = $1 AND expires_at > NOW()`,
+		id,
+	).Scan(
+		&session.ID,
+		&session.Bucket,
+		&session.Folder,
+		&session.Filename,
+		&session.TotalSize,
+		&session.ReceivedSize,
+		&checksum,
+		&session.Upsert,
+		&session.Process,
+		&altText,
+		&tagsRaw,
+		&by,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+	)
+	if err != nil {
+		return uploadSession{}, err
+	}
+	session.Checksum = checksum.String
+	session.AltText = altText.String
+	session.UploadedBy = by.String
+	session.Tags = parseStringArray(tagsRaw)
+	return session, nil
+}
+
+// lockedUploadSession loads a session and marks it busy. The caller must
+// call the returned release func when it is done.
+func (a *App) lockedUploadSession(w http.ResponseWriter, r *http.Request, id string) (uploadSession, func(), bool) {
+	if _, busy := uploadSessionLocks.LoadOrStore(id, struct{}{}); busy {
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"status":  "error",
+			"message": "another request for this upload is in progress",
+		})
+		return uploadSession{}, nil, false
+	}
+	release := func() { uploadSessionLocks.Delete(id) }
+
+	session, err := a.loadUploadSession(r.Context(), id)
+	if err != nil {
+		release()
+		a.writeUploadSessionLookupError(w, r, err)
+		return uploadSession{}, nil, false
+	}
+	return session, release, true
+}
+
+func (a *App) writeUploadSessionLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"status":  "error",
+			"message": "upload session not found or expired",
+		})
+		return
+	}
+	logFromContext(r.Context()).Error("upload session lookup failed", "error", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]any{
+		"status":  "error",
+		"message": "failed to load upload session",
+	})
+}
+
+func setUploadOffsetHeaders(w http.ResponseWriter, session uploadSession) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(session.ReceivedSize, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(session.TotalSize, 10))
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+func (a *App) fetchUploadSession(w http.ResponseWriter, r *http.Request, id string) {
+	session, err := a.loadUploadSession(r.Context(), id)
+	if err != nil {
+		a.writeUploadSessionLookupError(w, r, err)
+		return
+	}
+	setUploadOffsetHeaders(w, session)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   session,
+	})
+}
+
+// appendUploadChunk writes the request body at the offset given by the
+// Upload-Offset header (or ?offset=). Bytes received before a dropped
+// connection are kept, so the client resumes from the returned offset.
+func (a *App) appendUploadChunk(w http.ResponseWriter, r *http.Request, id string) {
+	offset, err := strconv.ParseInt(strings.TrimSpace(firstNonEmpty(r.Header.Get("Upload-Offset"), r.URL.Query().Get("offset"))), 10, 64)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "error",
+			"message": "Upload-Offset header (or offset query parameter) is required",
+		})
+		return
+	}
+
+	session, release, ok := a.lockedUploadSession(w, r, id)
+	if !ok {
+		return
+	}
+	defer release()
+
+	setUploadOffsetHeaders(w, session)
+	if offset != session.ReceivedSize {
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"status":   "error",
+			"message":  "offset does not match the bytes received so far",
+			"received": session.ReceivedSize,
+		})
+		return
+	}
+	remaining := session.TotalSize - session.ReceivedSize
+	if r.ContentLength > remaining {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{
+			"status":    "error",
+			"message":   "chunk goes past the declared upload size",
+			"remaining": remaining,
+		})
+		return
+	}
+
+	spool, ok := a.openUploadSpool(w, r, session)
+	if !ok {
+		return
+	}
+	defer spool.Close()
+
+	chunkMax := int64(a.UploadSessions.ChunkMaxMB) << 20
+	chunkTimeout := uploadReadTimeout(chunkMax)
+	extendRequestDeadlines(w, r, chunkTimeout, chunkTimeout+serverWriteTimeout)
+	body := http.MaxBytesReader(w, r.Body, chunkMax)
+	written, copyErr := io.Copy(spool, io.LimitReader(body, remaining))
+	if written > 0 {
+		if err := spool.Sync(); err != nil {
+			copyErr = errors.Join(copyErr, err)
+			written = 0
+		}
+	}
+
+	ctx := r.Context()
+	if written > 0 {
+		// Detached so an aborted request still records what reached disk.
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), healthTimeout)
+		_, err := a.DB.ExecContext(
+			withQueryName(saveCtx, "upload_sessions.advance"),
+			`UPDATE public.upload_sessions
+			SET received_size = $2, updated_at = NOW(), expires_at = NOW() + $3 * INTERVAL '1 second'
+			WHERE id = $1`,
+			session.ID,
+			session.ReceivedSize+written,
+			int64(a.UploadSessions.TTL.Seconds()),
+		)
+		cancel()
+		if err != nil {
+			logFromContext(ctx).Error("upload session update failed", "id", session.ID, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{
+				"status":  "error",
+				"message": "failed to save upload progress",
+			})
+			return
+		}
+		session.ReceivedSize += written
+		setUploadOffsetHeaders(w, session)
+	}
+
+	if copyErr != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(copyErr, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{
+				"status":          "error",
+				"message":         fmt.Sprintf("chunks are limited to %d MB; resume from the returned offset", a.UploadSessions.ChunkMaxMB),
+				"received":        session.ReceivedSize,
+				"chunk_max_bytes": chunkMax,
+			})
+			return
+		}
+		logFromContext(ctx).Warn("upload chunk interrupted", "id", session.ID, "received", session.ReceivedSize, "error", copyErr)
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":   "error",
+			"message":  "chunk was interrupted; resume from the returned offset",
+			"received": session.ReceivedSize,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"id":       session.ID,
+			"received": session.ReceivedSize,
+			"size":     session.TotalSize,
+			"complete": session.ReceivedSize == session.TotalSize,
+		},
+	})
+}
+
+// openUploadSpool opens the spool positioned at the recorded offset. Bytes
+// past it (from a write that never made it into the DB) are dropped; a
+// spool shorter than the offset means the data is gone and the session is
+// discarded. Spools live on the instance that created the session, so a
+// missing spool usually means the request reached another instance: the
+// session is kept for its owner and the client is told to retry there.
+func (a *App) openUploadSpool(w http.ResponseWriter, r *http.Request, session uploadSession) (*os.File, bool) {
+	spool, err := os.OpenFile(a.UploadSessions.spoolPath(session.ID), os.O_RDWR, 0o600)
+	var info os.FileInfo
+	if err == nil {
+		info, err = spool.Stat()
+	}
+	if err == nil && info.Size() < session.ReceivedSize {
+		err = errUploadSpoolTruncated
+	}
+	if err == nil {
+		err = spool.Truncate(session.ReceivedSize)
+	}
+	if err == nil {
+		_, err = spool.Seek(session.ReceivedSize, io.SeekStart)
+	}
+	if err == nil {
+		return spool, true
+	}
+
+	if spool != nil {
+		spool.Close()
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		logFromContext(r.Context()).Warn("upload spool not on this instance", "id", session.ID)
+		writeJSON(w, http.StatusMisdirectedRequest, map[string]any{
+			"status":  "error",
+			"message": "upload data is held by another server instance; retry so the request reaches the instance that started the upload (sticky sessions)",
+		})
+		return nil, false
+	}
+	if errors.Is(err, errUploadSpoolTruncated) {
+		a.deleteUploadSession(r.Context(), session.ID)
+		writeJSON(w, http.StatusGone, map[string]any{
+			"status":  "error",
+			"message": "upload data is no longer available on this server; start a new upload",
+		})
+		return nil, false
+	}
+	logFromContext(r.Context()).Error("upload spool open failed", "id", session.ID, "error", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]any{
+		"status":  "error",
+		"message": "failed to open upload data",
+	})
+	return nil, false
+}
+
+// completeUploadSession verifies size and checksum, then stores the file
+// through the same policy and processing path as a direct upload. A
+// storage failure keeps the session so complete can be retried.
+func (a *App) completeUploadSession(w http.ResponseWriter, r *http.Request, id string) {
+	storage, ok := a.storageOrError(w)
+	if !ok {
+		return
+	}
+	session, release, ok := a.lockedUploadSession(w, r, id)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Hashing and storing a large file outlasts WriteTimeout; without this
+	// the client would lose the answer while the object is stored anyway.
+	extendRequestDeadlines(w, r, serverReadTimeout, uploadSessionCompleteTimeout+serverWriteTimeout)
+
+	setUploadOffsetHeaders(w, session)
+	if session.ReceivedSize != session.TotalSize {
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"status":   "error",
+			"message":  "upload is incomplete",
+			"received": session.ReceivedSize,
+			"size":     session.TotalSize,
+		})
+		return
+	}
+
+	spool, ok := a.openUploadSpool(w, r, session)
+	if !ok {
+		return
+	}
+	defer spool.Close()
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, spool); err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if session.Checksum != "" && checksum != session.Checksum {
+		a.deleteUploadSession(r.Context(), session.ID)
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"status":   "error",
+			"message":  "checksum mismatch; the upload was discarded",
+			"expected": session.Checksum,
+			"actual":   checksum,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), uploadSessionCompleteTimeout)
+	defer cancel()
+
+	data, err := a.storeUpload(ctx, storage, spool, session.TotalSize, storeUploadOptions{
+		Policy:     a.UploadPolicies.forResumable(session.Bucket),
+		Bucket:     session.Bucket,
+		Folder:     session.Folder,
+		Filename:   session.Filename,
+		Upsert:     session.Upsert,
+		Process:    session.Process,
+		AltText:    session.AltText,
+		Tags:       session.Tags,
+		UploadedBy: session.UploadedBy,
+	})
+	if err != nil {
+		var rejection *uploadRejection
+		if errors.As(err, &rejection) {
+			a.deleteUploadSession(r.Context(), session.ID)
+		}
+		writeUploadError(w, r, err)
+		return
+	}
+
+	a.deleteUploadSession(r.Context(), session.ID)
+	data["upload_session_id"] = session.ID
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"status": "success",
+		"data":   data,
+	})
+}
+
+func (a *App) abortUploadSession(w http.ResponseWriter, r *http.Request, id string) {
+	session, release, ok := a.lockedUploadSession(w, r, id)
+	if !ok {
+		return
+	}
+	defer release()
+
+	a.deleteUploadSession(r.Context(), session.ID)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  "success",
+		"message": "upload aborted",
+	})
+}
+
+func (a *App) deleteUploadSession(ctx context.Context, id string) {
+	if _, err := a.DB.ExecContext(
+		withQueryName(context.WithoutCancel(ctx), "upload_sessions.delete"),
+		`DELETE FROM public.upload_sessions WHERE id = $1`,
+		id,
+	); err != nil {
+		logFromContext(ctx).Error("upload session delete failed", "id", id, "error", err)
+	}
+	if err := os.Remove(a.UploadSessions.spoolPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logFromContext(ctx).Warn("upload spool delete failed", "id", id, "error", err)
+	}
+}
+
+// runUploadSessionCleanupLoop discards sessions that have been idle past
+// UPLOAD_SESSION_TTL, plus spool files no session refers to.
+func (a *App) runUploadSessionCleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(uploadSessionCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel := context.WithTimeout(ctx, time.Minute)
+			removed, err := a.cleanupUploadSessions(runCtx)
+			cancel()
+			if err != nil {
+				logFromContext(ctx).Error("upload session cleanup failed", "error", err)
+				continue
+			}
+			if removed > 0 {
+				logFromContext(ctx).Info("upload session cleanup finished", "removed", removed)
+			}
+		}
+	}
+}
+
+func (a *App) cleanupUploadSessions(ctx context.Context) (int, error) {
+	rows, err := a.DB.QueryContext(
+		withQueryName(ctx, "upload_sessions.expire"),
+		`DELETE FROM public.upload_sessions WHERE expires_at <= NOW() RETURNING id`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	removed := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return removed, err
+		}
+		_ = os.Remove(a.UploadSessions.spoolPath(id))
+		removed++
+	}
+	if err := rows.Err(); err != nil {
+		return removed, err
+	}
+
+	// Spools left behind by a crash between file and row writes.
+	entries, err := os.ReadDir(a.UploadSessions.Dir)
+	if err != nil {
+		return removed, err
+	}
+	cutoff := time.Now().Add(-2 * a.UploadSessions.TTL)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if os.Remove(filepath.Join(a.UploadSessions.Dir, entry.Name())) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}